@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	transcriptRunning   = "running"
+	transcriptCompleted = "completed"
+	transcriptStopped   = "stopped"
+)
+
+// transcriptSkippedFlags are loop flags a transcript doesn't keep: the input
+// is already in its messages and the state handle is recorded as StateID.
+var transcriptSkippedFlags = map[string]bool{
+	"input":         true,
+	"input-file":    true,
+	"editor":        true,
+	"system":        true,
+	"state-id":      true,
+	"state-ttl-sec": true,
+}
+
+// loopTranscript is the progress of an agent loop run. It is rewritten to
+// transcript.json in the run directory after every turn, so mrl agent resume
+// can continue a run that ran out of turns, time or was interrupted.
+type loopTranscript struct {
+	RunID   string `json:"run_id"`
+	WorkDir string `json:"work_dir"`
+	// Args are the loop flags the run was started with, as --name=value.
+	// Flags the tools file filled in aren't listed; resume reads it again.
+	Args      []string        `json:"args"`
+	StateID   string          `json:"state_id,omitempty"`
+	Status    string          `json:"status"`
+	Error     string          `json:"error,omitempty"`
+	Turns     int             `json:"turns"`
+	Model     string          `json:"model,omitempty"`
+	Usage     sdk.AgentUsage  `json:"usage"`
+	Steps     []agentLoopStep `json:"steps,omitempty"`
+	Tasks     []runTask       `json:"tasks,omitempty"`
+	Messages  []llm.InputItem `json:"messages"`
+	UpdatedAt time.Time       `json:"updated_at"`
+
+	run *agentRun
+	// warned is set after the first failed save.
+	warned bool
+}
+
+// newLoopTranscript starts the transcript of a run begun in the current
+// directory with the loop flags in flagset.
+func newLoopTranscript(flagset *pflag.FlagSet, messages []llm.InputItem) (*loopTranscript, error) {
+	args, err := loopTranscriptArgs(flagset)
+	if err != nil {
+		return nil, err
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return &loopTranscript{WorkDir: workDir, Args: args, Status: transcriptRunning, Messages: messages}, nil
+}
+
+// loopTranscriptArgs lists the loop flags set in flagset. Slice flags are
+// written as one CSV value, which is how pflag parses them back.
+func loopTranscriptArgs(flagset *pflag.FlagSet) ([]string, error) {
+	loop := &cobra.Command{}
+	bindAgentLoopFlags(loop, &agentLoopFlags{})
+	var (
+		args []string
+		err  error
+	)
+	loop.Flags().VisitAll(func(def *pflag.Flag) {
+		flag := flagset.Lookup(def.Name)
+		if err != nil || flag == nil || !flag.Changed || transcriptSkippedFlags[def.Name] {
+			return
+		}
+		value := flag.Value.String()
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			var line strings.Builder
+			w := csv.NewWriter(&line)
+			if err = w.Write(slice.GetSlice()); err != nil {
+				return
+			}
+			w.Flush()
+			if err = w.Error(); err != nil {
+				return
+			}
+			value = strings.TrimSuffix(line.String(), "\n")
+		}
+		args = append(args, "--"+def.Name+"="+value)
+	})
+	return args, err
+}
+
+// loopFlags parses the transcript's flags into fresh loop flags. The state
+// flags are always set, so a tools file can't swap the run's state handle.
+func (t *loopTranscript) loopFlags() (*pflag.FlagSet, *agentLoopFlags, error) {
+	loop := &cobra.Command{}
+	flags := &agentLoopFlags{}
+	bindAgentLoopFlags(loop, flags)
+	args := append(slices.Clone(t.Args), "--state-ttl-sec=0")
+	if t.StateID != "" {
+		args = append(args, "--state-id="+t.StateID)
+	}
+	if err := loop.Flags().Parse(args); err != nil {
+		return nil, nil, fmt.Errorf("run %s has invalid saved flags: %w", t.RunID, err)
+	}
+	if extra := loop.Flags().Args(); len(extra) > 0 {
+		return nil, nil, fmt.Errorf("run %s has unexpected saved arguments %q", t.RunID, extra)
+	}
+	return loop.Flags(), flags, nil
+}
+
+// attach records the transcript in run's directory.
+func (t *loopTranscript) attach(run *agentRun) {
+	t.run = run
+	t.RunID = run.ID
+	t.save()
+}
+
+// record saves the progress after turns turns.
+func (t *loopTranscript) record(turns int, messages []llm.InputItem, usage sdk.AgentUsage, steps []agentLoopStep, model string, tasks []runTask) {
+	t.Turns = turns
+	t.Messages = messages
+	t.Usage = usage
+	t.Steps = steps
+	t.Model = firstNonEmpty(model, t.Model)
+	t.Tasks = tasks
+	t.save()
+}
+
+// finish marks the run completed, or stopped with err and how to resume it.
+func (t *loopTranscript) finish(err error) {
+	if t.run == nil {
+		return
+	}
+	t.Status, t.Error = transcriptCompleted, ""
+	if err != nil {
+		t.Status, t.Error = transcriptStopped, err.Error()
+	}
+	t.save()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Run %s stopped after %d turns; continue it with: mrl agent resume %s\n", t.RunID, t.Turns, t.RunID)
+	}
+}
+
+// save writes the transcript. Only the first failure is reported, and it
+// doesn't stop the run.
+func (t *loopTranscript) save() {
+	if t.run == nil {
+		return
+	}
+	t.UpdatedAt = time.Now().UTC()
+	if err := writeJSONFile(filepath.Join(t.run.dir, "transcript.json"), t); err != nil && !t.warned {
+		t.warned = true
+		fmt.Fprintf(os.Stderr, "warning: saving the transcript failed, so this run may not be resumable: %v\n", err)
+	}
+}
+
+// loadLoopTranscript reads the transcript of run.
+func loadLoopTranscript(run *agentRun) (*loopTranscript, error) {
+	data, err := os.ReadFile(filepath.Join(run.dir, "transcript.json")) //nolint:gosec // path is derived from a validated run id under the mrl data dir
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run %s has no transcript; only mrl agent loop runs can be resumed", run.ID)
+		}
+		return nil, err
+	}
+	var transcript loopTranscript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript of run %s: %w", run.ID, err)
+	}
+	if len(transcript.Messages) == 0 {
+		return nil, fmt.Errorf("transcript of run %s has no messages", run.ID)
+	}
+	transcript.run = run
+	transcript.RunID = run.ID
+	return &transcript, nil
+}
+
+func addAgentUsage(a, b sdk.AgentUsage) sdk.AgentUsage {
+	a.LLMCalls += b.LLMCalls
+	a.ToolCalls += b.ToolCalls
+	a.InputTokens += b.InputTokens
+	a.OutputTokens += b.OutputTokens
+	a.TotalTokens += b.TotalTokens
+	a.ReasoningTokens += b.ReasoningTokens
+	a.CacheReadInputTokens += b.CacheReadInputTokens
+	a.CacheWriteInputTokens += b.CacheWriteInputTokens
+	return a
+}
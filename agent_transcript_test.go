@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+	"github.com/spf13/cobra"
+)
+
+func TestLoopTranscriptFlagsRoundTrip(t *testing.T) {
+	flags := &agentLoopFlags{}
+	cmd := &cobra.Command{}
+	bindAgentLoopFlags(cmd, flags)
+	for name, value := range map[string]string{
+		"input":         "refactor the parser",
+		"model":         "claude-sonnet-4-5",
+		"tool":          "bash,tasks.write",
+		"bash-allow":    `"git log --format=%h,%s",go test`,
+		"max-turns":     "80",
+		"bash-timeout":  "20s",
+		"state-ttl-sec": "600",
+	} {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			t.Fatalf("set %s: %v", name, err)
+		}
+	}
+
+	args, err := loopTranscriptArgs(cmd.Flags())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--input=") || strings.HasPrefix(arg, "--state-ttl-sec=") {
+			t.Fatalf("args keep %q: %q", arg, args)
+		}
+	}
+
+	transcript := &loopTranscript{RunID: "run", Args: args, StateID: "0b6a4a3e-5d51-4a8e-9a41-5a6b3d2f7c10"}
+	flagset, restored, err := transcript.loopFlags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.model != flags.model || restored.maxTurns != 80 || restored.bashTimeout != 20*time.Second || restored.inputText != "" {
+		t.Fatalf("restored = %+v", restored)
+	}
+	if !slices.Equal(restored.tools, []string{"bash", "tasks.write"}) || !slices.Equal(restored.bashAllow, []string{"git log --format=%h,%s", "go test"}) {
+		t.Fatalf("restored tools %q, allow %q", restored.tools, restored.bashAllow)
+	}
+	if restored.stateID != transcript.StateID || restored.stateTTLSeconds != 0 {
+		t.Fatalf("restored state %q, ttl %d", restored.stateID, restored.stateTTLSeconds)
+	}
+	// Flags the run didn't set stay open to the tools file.
+	if !flagset.Changed("tool") || flagset.Changed("tool-root") || !flagset.Changed("state-ttl-sec") {
+		t.Fatal("unexpected changed flags")
+	}
+}
+
+func TestLoopTranscriptSaveAndLoad(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	run, err := startAgentRun("agent loop", t.TempDir(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadLoopTranscript(run); err == nil {
+		t.Fatal("expected a run without a transcript to be rejected")
+	}
+
+	transcript := &loopTranscript{WorkDir: "/src", Args: []string{"--tool=bash"}, Status: transcriptRunning, Messages: []llm.InputItem{llm.NewUserText("fix the build")}}
+	transcript.attach(run)
+	messages := append(transcript.Messages, llm.NewAssistantText("done"))
+	steps := []agentLoopStep{{Turn: 0, ToolCalls: 1, Tools: []string{"bash"}}}
+	tasks := []runTask{{Content: "fix the build", Status: runTaskStatusInProgress}}
+	transcript.record(1, messages, sdk.AgentUsage{LLMCalls: 1, TotalTokens: 42}, steps, "model-a", tasks)
+	transcript.finish(errors.New("max turns reached"))
+
+	loaded, err := loadLoopTranscript(run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.RunID != run.ID || loaded.Turns != 1 || loaded.Status != transcriptStopped || loaded.Error != "max turns reached" {
+		t.Fatalf("loaded = %+v", loaded)
+	}
+	if len(loaded.Messages) != 2 || loaded.Usage.TotalTokens != 42 || loaded.Model != "model-a" || len(loaded.Steps) != 1 || len(loaded.Tasks) != 1 {
+		t.Fatalf("loaded = %+v", loaded)
+	}
+}
+
+func TestAgentResumeContinuesTranscript(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	var sent []llm.InputItem
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Input []llm.InputItem `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		sent = req.Input
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(llm.Response{
+			ID:    "resp_test",
+			Model: "demo",
+			Output: []llm.OutputItem{{
+				Type:    llm.OutputItemTypeMessage,
+				Role:    llm.RoleAssistant,
+				Content: []llm.ContentPart{llm.TextPart("the build passes")},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	workDir := t.TempDir()
+	run, err := startAgentRun("agent loop", workDir, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := &loopTranscript{
+		WorkDir:  workDir,
+		Args:     []string{"--model=demo", "--tool=tasks.write", "--tool-root=" + workDir, "--no-checkpoint"},
+		Status:   transcriptRunning,
+		Messages: []llm.InputItem{llm.NewUserText("fix the build"), llm.NewAssistantText("the test fails"), llm.NewUserText("keep going")},
+	}
+	saved.attach(run)
+	saved.record(3, saved.Messages, sdk.AgentUsage{LLMCalls: 3, TotalTokens: 30}, nil, "demo", nil)
+	saved.finish(errors.New("max turns reached"))
+
+	transcript, err := loadLoopTranscript(run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flagset, flags, err := transcript.loopFlags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := &cobra.Command{}
+	cmd.SetContext(withRuntimeConfig(context.Background(), runtimeConfig{BaseURL: server.URL, APIKey: "mr_sk_test", Output: outputFormatJSON}))
+	if err := runAgentLoop(cmd, flagset, nil, flags, transcript); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := json.Marshal(saved.Messages)
+	if got, _ := json.Marshal(sent); string(got) != string(want) {
+		t.Fatalf("resumed with input %s, want %s", got, want)
+	}
+	loaded, err := loadLoopTranscript(run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Status != transcriptCompleted || loaded.Turns != 4 || len(loaded.Messages) != 4 || loaded.Usage.LLMCalls != 4 {
+		t.Fatalf("loaded = %+v", loaded)
+	}
+}
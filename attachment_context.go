@@ -0,0 +1,482 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Directory and glob attachments (-a ./docs/, -a './src/**/*.go') are
+// expanded to the text files they contain, honoring .gitignore and the
+// profile's ignore_dirs, and packed into text parts with a path header per
+// file. Explicit file paths keep being sent as file parts.
+
+// defaultAttachmentIgnoreDirs are skipped in directory walks in addition to the
+// profile's ignore_dirs, matching the fs tool defaults in the manifest docs.
+var defaultAttachmentIgnoreDirs = []string{".git", "node_modules"}
+
+const (
+	// maxContextFiles bounds how many files one directory or glob may expand to.
+	maxContextFiles = 1000
+	// maxContextFileBytes skips single files too large to be useful as context.
+	maxContextFileBytes = 1 << 20
+	// minTruncatedTokens is the smallest remainder of the token budget worth
+	// filling with the head of a file that does not fit whole.
+	minTruncatedTokens = 256
+	// binarySniffBytes is how much of a file is checked for NUL bytes.
+	binarySniffBytes = 8000
+)
+
+// contextFile is a text file collected from a directory or glob attachment.
+// Note marks a file cut short by a token budget.
+type contextFile struct {
+	Path string
+	Data []byte
+	Note string
+}
+
+// expandedAttachments splits -a values into paths handled as before (files,
+// URLs, stdin) and text files collected from directories and globs.
+type expandedAttachments struct {
+	Paths   []string
+	Files   []contextFile
+	Skipped []string
+}
+
+func hasGlobMeta(value string) bool {
+	return strings.ContainsAny(value, "*?[")
+}
+
+// expandAttachmentPaths expands directory and glob attachments. Collected files
+// are ordered by priority: the order of the -a flags, then shallower paths
+// first, then by name. Binary and oversized files are skipped and reported.
+func expandAttachmentPaths(paths []string, ignoreDirs []string) (expandedAttachments, error) {
+	var out expandedAttachments
+	seen := map[string]bool{}
+	for _, raw := range paths {
+		value := strings.TrimSpace(raw)
+		if value == "" || value == "-" || isURLAttachment(value) {
+			out.Paths = append(out.Paths, raw)
+			continue
+		}
+		var (
+			matches []string
+			err     error
+		)
+		switch {
+		case hasGlobMeta(value):
+			matches, err = globAttachmentFiles(value, ignoreDirs)
+			if err == nil && len(matches) == 0 {
+				err = fmt.Errorf("no files match %s", value)
+			}
+		default:
+			info, statErr := os.Stat(value)
+			if statErr != nil || !info.IsDir() {
+				// Missing files fail later with the usual read error.
+				out.Paths = append(out.Paths, raw)
+				continue
+			}
+			matches, err = walkAttachmentDir(value, ignoreDirs)
+		}
+		if err != nil {
+			return expandedAttachments{}, err
+		}
+		if len(matches) > maxContextFiles {
+			return expandedAttachments{}, fmt.Errorf("%s matches %d files (limit %d); narrow the pattern or add ignore_dirs", value, len(matches), maxContextFiles)
+		}
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+			data, err := os.ReadFile(match) //nolint:gosec // attachment paths are explicitly selected by the CLI user
+			if err != nil {
+				return expandedAttachments{}, err
+			}
+			switch {
+			case len(data) == 0:
+				continue
+			case len(data) > maxContextFileBytes:
+				out.Skipped = append(out.Skipped, match+" (larger than 1 MB)")
+			case isBinaryData(data):
+				out.Skipped = append(out.Skipped, match+" (binary)")
+			default:
+				out.Files = append(out.Files, contextFile{Path: match, Data: data})
+			}
+		}
+	}
+	return out, nil
+}
+
+func isBinaryData(data []byte) bool {
+	sample := data[:min(len(data), binarySniffBytes)]
+	return bytes.IndexByte(sample, 0) >= 0 || !utf8.Valid(data)
+}
+
+// walkAttachmentDir lists the files under dir that are not ignored.
+func walkAttachmentDir(dir string, ignoreDirs []string) ([]string, error) {
+	return walkUnignored(dir, ignoreDirs, false)
+}
+
+// walkUnignored lists the regular files under dir that are not ignored, and
+// with withSymlinks the symlinks too. Symlinked directories aren't followed.
+func walkUnignored(dir string, ignoreDirs []string, withSymlinks bool) ([]string, error) {
+	ignore, err := newIgnoreMatcher(dir, ignoreDirs)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		if ignore.ignored(p, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return ignore.load(p)
+		}
+		if d.Type().IsRegular() || (withSymlinks && d.Type()&fs.ModeSymlink != 0) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortByDepth(files)
+	return files, nil
+}
+
+// globAttachmentFiles walks the non-glob prefix of pattern and returns the
+// files matching it; "**" matches any number of directories.
+func globAttachmentFiles(pattern string, ignoreDirs []string) ([]string, error) {
+	pattern = filepath.Clean(pattern)
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	base := 0
+	for base < len(segments) && !hasGlobMeta(segments[base]) {
+		base++
+	}
+	root := filepath.FromSlash(strings.Join(segments[:base], "/"))
+	switch {
+	case root == "" && strings.HasPrefix(pattern, string(filepath.Separator)):
+		root = string(filepath.Separator)
+	case root == "":
+		root = "."
+	}
+	rest := segments[base:]
+	if _, err := path.Match(strings.Join(rest, "/"), ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+	files, err := walkAttachmentDir(root, ignoreDirs)
+	if err != nil {
+		return nil, err
+	}
+	matched := files[:0]
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			continue
+		}
+		if matchSegments(rest, strings.Split(filepath.ToSlash(rel), "/")) {
+			matched = append(matched, file)
+		}
+	}
+	return matched, nil
+}
+
+func sortByDepth(files []string) {
+	sort.SliceStable(files, func(i, j int) bool {
+		di := strings.Count(files[i], string(filepath.Separator))
+		dj := strings.Count(files[j], string(filepath.Separator))
+		if di != dj {
+			return di < dj
+		}
+		return files[i] < files[j]
+	})
+}
+
+// matchSegments matches slash-separated path segments against a pattern in
+// which "**" stands for zero or more segments.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], name[0])
+	return err == nil && ok && matchSegments(pattern[1:], name[1:])
+}
+
+// ignoreRule is one .gitignore line. Rules are relative to base, the directory
+// (slash-separated, relative to the matcher root) holding the .gitignore.
+type ignoreRule struct {
+	base     string
+	pattern  []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreMatcher applies .gitignore files and ignore_dirs during a walk. The
+// root is the enclosing git checkout when there is one, so .gitignore files
+// above the attached directory apply too.
+type ignoreMatcher struct {
+	root  string
+	dirs  []string
+	rules []ignoreRule
+}
+
+func newIgnoreMatcher(dir string, ignoreDirs []string) (*ignoreMatcher, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	m := &ignoreMatcher{root: abs, dirs: append(slices.Clone(defaultAttachmentIgnoreDirs), ignoreDirs...)}
+	chain := []string{abs}
+	for current := abs; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			m.root = current
+			break
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			chain = chain[:1]
+			break
+		}
+		current = parent
+		chain = append(chain, current)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if err := m.load(chain[i]); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *ignoreMatcher) rel(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	rel, err := filepath.Rel(m.root, abs)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// load reads dir/.gitignore, if any, into the matcher.
+func (m *ignoreMatcher) load(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, ".gitignore")) //nolint:gosec // inside an attached directory
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	base := m.rel(dir)
+	if base == "." {
+		base = ""
+	}
+	m.rules = append(m.rules, parseGitignore(base, data)...)
+	return nil
+}
+
+func parseGitignore(base string, data []byte) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimRight(line, " ")
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		rule.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		rule.pattern = strings.Split(line, "/")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignored reports whether p is excluded; as in git, the last matching rule
+// wins and a "!" rule re-includes.
+func (m *ignoreMatcher) ignored(p string, isDir bool) bool {
+	name := filepath.Base(p)
+	if isDir && slices.Contains(m.dirs, name) {
+		return true
+	}
+	rel := m.rel(p)
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		var match bool
+		if rule.anchored {
+			match = matchSegments(rule.pattern, strings.Split(sub, "/"))
+		} else {
+			match = matchSegments(rule.pattern, []string{name})
+		}
+		if match {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// contextPackReport lists the files a --max-context-tokens budget left out.
+type contextPackReport struct {
+	Budget    int64
+	Tokens    int64
+	Packed    int
+	Truncated []string
+	Omitted   []string
+}
+
+// budgetContextFiles takes files whole in priority order while they fit in a
+// positive maxTokens. The first file that does not fit is truncated into what
+// is left, and it and everything after it are dropped when too little is left,
+// so lower-priority files never displace higher-priority ones.
+func budgetContextFiles(files []contextFile, maxTokens int64) ([]contextFile, contextPackReport) {
+	report := contextPackReport{Budget: maxTokens}
+	kept := make([]contextFile, 0, len(files))
+	for i, file := range files {
+		cost := estimateTextTokens(formatContextFile(file.Path, string(file.Data), ""))
+		if maxTokens > 0 && report.Tokens+cost > maxTokens {
+			if remaining := maxTokens - report.Tokens; remaining >= minTruncatedTokens {
+				file = truncateContextFile(file, remaining)
+				kept = append(kept, file)
+				report.Tokens += estimateTextTokens(formatContextFile(file.Path, string(file.Data), file.Note))
+				report.Truncated = append(report.Truncated, file.Path)
+				i++
+			}
+			for _, rest := range files[i:] {
+				report.Omitted = append(report.Omitted, rest.Path)
+			}
+			break
+		}
+		kept = append(kept, file)
+		report.Tokens += cost
+	}
+	report.Packed = len(kept)
+	return kept, report
+}
+
+// packContextFiles renders the files budgetContextFiles keeps, each with a
+// path header.
+func packContextFiles(files []contextFile, maxTokens int64) ([]string, contextPackReport) {
+	kept, report := budgetContextFiles(files, maxTokens)
+	packed := make([]string, 0, len(kept))
+	for _, file := range kept {
+		packed = append(packed, formatContextFile(file.Path, string(file.Data), file.Note))
+	}
+	return packed, report
+}
+
+func formatContextFile(name, content, note string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<file path=%q>\n%s", filepath.ToSlash(name), content)
+	if !strings.HasSuffix(content, "\n") {
+		b.WriteString("\n")
+	}
+	if note != "" {
+		b.WriteString(note + "\n")
+	}
+	b.WriteString("</file>")
+	return b.String()
+}
+
+// truncateContextFile keeps the head of file, cut at a line boundary, so that
+// the rendered part fits in tokens.
+func truncateContextFile(file contextFile, tokens int64) contextFile {
+	// Size the header and note for the longest possible note.
+	overhead := len(formatContextFile(file.Path, "", fmt.Sprintf("[truncated: %d of %d bytes omitted]", len(file.Data), len(file.Data))))
+	limit := max(int(tokens*4)-overhead-4, 0)
+	head := file.Data[:min(limit, len(file.Data))]
+	if cut := bytes.LastIndexByte(head, '\n'); cut > 0 {
+		head = head[:cut+1]
+	}
+	for len(head) > 0 && !utf8.Valid(head) {
+		head = head[:len(head)-1]
+	}
+	note := fmt.Sprintf("[truncated: %d of %d bytes omitted]", len(file.Data)-len(head), len(file.Data))
+	return contextFile{Path: file.Path, Data: head, Note: note}
+}
+
+// estimateTextTokens uses the same four-bytes-per-token heuristic as
+// estimateInputTokens.
+func estimateTextTokens(text string) int64 {
+	return int64(len(text)+3) / 4
+}
+
+// warnContextPack reports skipped files and anything a token budget dropped.
+func warnContextPack(w io.Writer, skipped []string, report contextPackReport) {
+	if len(skipped) > 0 {
+		_, _ = fmt.Fprintf(w, "warning: skipped %d file(s): %s\n", len(skipped), summarizeNames(skipped))
+	}
+	if len(report.Truncated) == 0 && len(report.Omitted) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "warning: --max-context-tokens %d: packed %d file(s), ~%d tokens", report.Budget, report.Packed, report.Tokens)
+	if len(report.Truncated) > 0 {
+		_, _ = fmt.Fprintf(w, "; truncated %s", strings.Join(report.Truncated, ", "))
+	}
+	if len(report.Omitted) > 0 {
+		_, _ = fmt.Fprintf(w, "; omitted %d file(s): %s", len(report.Omitted), summarizeNames(report.Omitted))
+	}
+	_, _ = fmt.Fprintln(w)
+}
+
+func summarizeNames(names []string) string {
+	const shown = 5
+	if len(names) <= shown {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:shown], ", "), len(names)-shown)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func relPaths(t *testing.T, root string, files []contextFile) []string {
+	t.Helper()
+	out := make([]string, 0, len(files))
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, filepath.ToSlash(rel))
+	}
+	return out
+}
+
+func TestExpandAttachmentDirRespectsIgnoreRules(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":              "*.log\n!keep.log\nbuild/\n/secret.txt\n",
+		"README.md":               "readme",
+		"keep.log":                "kept",
+		"debug.log":               "ignored",
+		"secret.txt":              "ignored",
+		"src/main.go":             "package main",
+		"src/secret.txt":          "not anchored here",
+		"src/.gitignore":          "gen_*.go\n",
+		"src/gen_api.go":          "generated",
+		"build/out.txt":           "ignored",
+		"node_modules/x/index.js": "ignored",
+		"vendor/lib.go":           "ignored by profile",
+		"image.png":               "\x89PNG\x00\x00",
+	})
+
+	expanded, err := expandAttachmentPaths([]string{root}, []string{"vendor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := relPaths(t, root, expanded.Files)
+	want := []string{".gitignore", "README.md", "keep.log", "src/.gitignore", "src/main.go", "src/secret.txt"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	if len(expanded.Skipped) != 1 || !strings.Contains(expanded.Skipped[0], "image.png (binary)") {
+		t.Fatalf("skipped = %v", expanded.Skipped)
+	}
+}
+
+func TestExpandAttachmentGlob(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a.go":          "a",
+		"pkg/b.go":      "b",
+		"pkg/deep/c.go": "c",
+		"pkg/notes.md":  "md",
+	})
+	explicit := filepath.Join(root, "pkg", "notes.md")
+	expanded, err := expandAttachmentPaths([]string{filepath.Join(root, "**", "*.go"), explicit, "-"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := relPaths(t, root, expanded.Files); !slices.Equal(got, []string{"a.go", "pkg/b.go", "pkg/deep/c.go"}) {
+		t.Fatalf("glob files = %v", got)
+	}
+	if !slices.Equal(expanded.Paths, []string{explicit, "-"}) {
+		t.Fatalf("explicit paths = %v", expanded.Paths)
+	}
+
+	if _, err := expandAttachmentPaths([]string{filepath.Join(root, "*.rs")}, nil); err == nil {
+		t.Fatal("expected error for a glob without matches")
+	}
+}
+
+func TestPackContextFilesBudget(t *testing.T) {
+	files := []contextFile{
+		{Path: "small.go", Data: []byte("package small\n")},
+		{Path: "big.go", Data: []byte(strings.Repeat("line of code\n", 400))},
+		{Path: "medium.go", Data: []byte(strings.Repeat("x", 400) + "\n")},
+		{Path: "last.go", Data: []byte(strings.Repeat("y", 4000) + "\n")},
+	}
+
+	parts, report := packContextFiles(files, 0)
+	if len(parts) != 4 || report.Packed != 4 || len(report.Omitted) != 0 {
+		t.Fatalf("unbudgeted pack = %d parts, %+v", len(parts), report)
+	}
+	if text := parts[0]; !strings.HasPrefix(text, `<file path="small.go">`) || !strings.HasSuffix(text, "</file>") {
+		t.Fatalf("unexpected part %q", text)
+	}
+
+	parts, report = packContextFiles(files, 700)
+	if report.Tokens > 700 {
+		t.Fatalf("packed %d tokens over a 700 token budget", report.Tokens)
+	}
+	if !slices.Equal(report.Truncated, []string{"big.go"}) || !slices.Equal(report.Omitted, []string{"medium.go", "last.go"}) {
+		t.Fatalf("report = %+v", report)
+	}
+	if len(parts) != 2 || !strings.Contains(parts[1], "[truncated: ") {
+		t.Fatalf("expected big.go truncated in place, got %d parts", len(parts))
+	}
+
+	// Too little is left to truncate big.go, and medium.go must not take
+	// its place even though it would fit.
+	parts, report = packContextFiles(files, 200)
+	if len(parts) != 1 || len(report.Truncated) != 0 || !slices.Equal(report.Omitted, []string{"big.go", "medium.go", "last.go"}) {
+		t.Fatalf("%d parts, report = %+v", len(parts), report)
+	}
+}
+
+func TestMatchSegments(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c.go", true},
+		{"src/**", "src/a/b", true},
+		{"src/*.go", "src/a/b.go", false},
+		{"a/**/b", "a/b", true},
+		{"*.md", "docs/x.md", false},
+	}
+	for _, tc := range cases {
+		if got := matchSegments(strings.Split(tc.pattern, "/"), strings.Split(tc.name, "/")); got != tc.want {
+			t.Fatalf("matchSegments(%q, %q) = %v", tc.pattern, tc.name, got)
+		}
+	}
+}
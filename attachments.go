@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"mime"
 	"net/http"
@@ -39,6 +42,120 @@ func resolveAttachmentInputs(paths []string, attachmentType string, attachStdin
 	return resolved, nil
 }
 
+// attachmentOptions control how collectAttachmentParts sends -a values.
+type attachmentOptions struct {
+	// mimeType overrides MIME detection (--attachment-type).
+	mimeType string
+	// extract sends locally extracted text instead of file bytes (--extract).
+	extract bool
+	// maxContextTokens budgets the text packed from directories, globs and
+	// extracted files (0 for no limit).
+	maxContextTokens int64
+}
+
+// collectAttachmentParts turns -a values into content parts: URLs are
+// downloaded, directories and globs (and, with extract, documents) are packed
+// as text, and everything else is attached as a file.
+func collectAttachmentParts(ctx context.Context, cfg runtimeConfig, paths []string, opts attachmentOptions, stdin io.Reader) ([]llm.ContentPart, error) {
+	if opts.maxContextTokens < 0 {
+		return nil, errors.New("max-context-tokens must be >= 0")
+	}
+	paths, err := fetchURLAttachments(ctx, cfg, paths)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := expandAttachmentPaths(paths, cfg.IgnoreDirs)
+	if err != nil {
+		return nil, err
+	}
+	filePaths := expanded.Paths
+	var extracted []contextFile
+	if opts.extract {
+		extracted, filePaths, stdin, err = extractAttachments(filePaths, opts.mimeType, stdin)
+		if err != nil {
+			return nil, err
+		}
+	}
+	parts, err := buildAttachmentParts(filePaths, opts.mimeType, stdin)
+	if err != nil {
+		return nil, err
+	}
+	// Explicitly attached documents rank ahead of directory contents.
+	packed, report := packContextFiles(append(extracted, expanded.Files...), opts.maxContextTokens)
+	warnContextPack(os.Stderr, expanded.Skipped, report)
+	for _, text := range packed {
+		parts = append(parts, llm.TextPart(text))
+	}
+	return parts, nil
+}
+
+// extractAttachments converts the attachments that have a text extractor.
+// The rest are returned, with a replacement stdin reader if stdin had to be
+// read, for buildAttachmentParts. A failed extraction falls back to sending
+// the file.
+func extractAttachments(paths []string, overrideMime string, stdin io.Reader) ([]contextFile, []string, io.Reader, error) {
+	var (
+		files []contextFile
+		rest  []string
+	)
+	for _, raw := range paths {
+		path := strings.TrimSpace(raw)
+		if path == "" || isURLAttachment(path) {
+			rest = append(rest, raw)
+			continue
+		}
+		var (
+			data []byte
+			err  error
+			name = path
+		)
+		if path == "-" {
+			if stdin == nil {
+				rest = append(rest, raw)
+				continue
+			}
+			name = "stdin"
+			data, err = io.ReadAll(stdin)
+			stdin = bytes.NewReader(data)
+		} else {
+			data, err = readExtractSource(path)
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		var mimeType llm.MimeType
+		if strings.TrimSpace(overrideMime) != "" {
+			mimeType = llm.NormalizeMimeType(overrideMime)
+		}
+		if mimeType == "" {
+			mimeType = llm.NormalizeMimeType(detectMimeType(path, data))
+		}
+		text, ok, err := extractText(name, mimeType, data)
+		switch {
+		case !ok:
+			rest = append(rest, raw)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "warning: %v; sending the file instead\n", err)
+			rest = append(rest, raw)
+		default:
+			files = append(files, contextFile{Path: name, Data: []byte(text)})
+		}
+	}
+	return files, rest, stdin, nil
+}
+
+// readExtractSource reads a file for extraction, refusing very large inputs.
+func readExtractSource(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxExtractSourceBytes {
+		return nil, fmt.Errorf("%s is too large to extract (limit %d MB)", path, maxExtractSourceBytes>>20)
+	}
+	return os.ReadFile(path) //nolint:gosec // attachment paths are explicitly selected by the CLI user
+}
+
 func buildAttachmentParts(paths []string, overrideMime string, stdin io.Reader) ([]llm.ContentPart, error) {
 	if len(paths) == 0 {
 		return nil, nil
@@ -66,8 +183,9 @@ func buildAttachmentParts(paths []string, overrideMime string, stdin io.Reader)
 		if path == "" {
 			return nil, errors.New("attachment path is empty")
 		}
-		if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
-			return nil, errors.New("URL attachments are not supported; download the file and pass the path instead")
+		// URLs are downloaded up front by fetchURLAttachments when allowed.
+		if isURLAttachment(path) {
+			return nil, errURLAttachmentsDisabled
 		}
 
 		var (
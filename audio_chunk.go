@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Long recordings are split before upload. WAV files are cut in Go on sample
+// boundaries; other formats are segmented with ffmpeg (stream copy, no
+// re-encoding) when it is installed.
+
+// audioChunk is one piece of a recording and where it starts in the original.
+type audioChunk struct {
+	Data     []byte
+	MimeType string
+	Offset   float64
+}
+
+// wavFormat is what splitting needs from a WAV "fmt " chunk.
+type wavFormat struct {
+	raw        []byte
+	byteRate   int
+	blockAlign int
+}
+
+// parseWAV returns the format and the sample data of a RIFF/WAVE file.
+func parseWAV(data []byte) (wavFormat, []byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return wavFormat{}, nil, errors.New("not a WAV file")
+	}
+	var (
+		format  wavFormat
+		samples []byte
+	)
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := data[pos+8:]
+		if size > len(body) {
+			// Streams written without a final size often leave it too
+			// large; take what is there.
+			size = len(body)
+		}
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return wavFormat{}, nil, errors.New("invalid WAV format chunk")
+			}
+			format.raw = body[:size]
+			format.byteRate = int(binary.LittleEndian.Uint32(body[8:12]))
+			format.blockAlign = int(binary.LittleEndian.Uint16(body[12:14]))
+		case "data":
+			samples = body[:size]
+		}
+		pos += 8 + size + size%2
+	}
+	if format.raw == nil || samples == nil || format.byteRate <= 0 || format.blockAlign <= 0 {
+		return wavFormat{}, nil, errors.New("WAV file has no audio data")
+	}
+	return format, samples, nil
+}
+
+// encodeWAV writes samples with the given format as a minimal WAV file.
+func encodeWAV(format wavFormat, samples []byte) []byte {
+	var buf bytes.Buffer
+	fmtSize := len(format.raw)
+	riffSize := 4 + 8 + fmtSize + fmtSize%2 + 8 + len(samples) + len(samples)%2
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(riffSize))
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(fmtSize))
+	buf.Write(format.raw)
+	if fmtSize%2 == 1 {
+		buf.WriteByte(0)
+	}
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(samples)))
+	buf.Write(samples)
+	if len(samples)%2 == 1 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// wavDuration returns the length of a WAV file in seconds.
+func wavDuration(data []byte) (float64, error) {
+	format, samples, err := parseWAV(data)
+	if err != nil {
+		return 0, err
+	}
+	return float64(len(samples)) / float64(format.byteRate), nil
+}
+
+// splitWAV cuts a WAV file into pieces of at most seconds each.
+func splitWAV(data []byte, seconds int) ([]audioChunk, error) {
+	format, samples, err := parseWAV(data)
+	if err != nil {
+		return nil, err
+	}
+	size := seconds * format.byteRate
+	size -= size % format.blockAlign
+	if size <= 0 {
+		return nil, errors.New("chunk duration is too short")
+	}
+	var chunks []audioChunk
+	for start := 0; start < len(samples); start += size {
+		end := min(start+size, len(samples))
+		chunks = append(chunks, audioChunk{
+			Data:     encodeWAV(format, samples[start:end]),
+			MimeType: "audio/wav",
+			Offset:   float64(start) / float64(format.byteRate),
+		})
+	}
+	return chunks, nil
+}
+
+// splitAudioFFmpeg segments path with ffmpeg into pieces of about seconds
+// each, keeping the codec and container.
+func splitAudioFFmpeg(ctx context.Context, path, mimeType string, seconds int) ([]audioChunk, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, errors.New("splitting this format needs ffmpeg on PATH (or convert the recording to WAV)")
+	}
+	dir, err := os.MkdirTemp("", "mrl-audio-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	ext := filepath.Ext(path)
+	list := filepath.Join(dir, "segments.csv")
+	cmd := exec.CommandContext(ctx, ffmpeg, //nolint:gosec // ffmpeg is resolved from PATH; the input path is chosen by the CLI user
+		"-hide_banner", "-loglevel", "error", "-i", path,
+		"-f", "segment", "-segment_time", strconv.Itoa(seconds), "-reset_timestamps", "1",
+		"-segment_list", list, "-segment_list_type", "csv",
+		"-map", "0:a", "-c", "copy", filepath.Join(dir, "part%04d"+ext))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %s", firstNonEmpty(strings.TrimSpace(stderr.String()), err.Error()))
+	}
+	listData, err := os.ReadFile(list) //nolint:gosec // written by ffmpeg in our temp dir
+	if err != nil {
+		return nil, err
+	}
+	segments, err := parseSegmentList(listData)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make([]audioChunk, 0, len(segments))
+	for _, segment := range segments {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.Base(segment.name))) //nolint:gosec // written by ffmpeg in our temp dir
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, audioChunk{Data: data, MimeType: mimeType, Offset: segment.start})
+	}
+	return chunks, nil
+}
+
+type segmentListEntry struct {
+	name  string
+	start float64
+}
+
+// parseSegmentList reads ffmpeg's CSV segment list (name,start,end).
+func parseSegmentList(data []byte) ([]segmentListEntry, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read segment list: %w", err)
+	}
+	entries := make([]segmentListEntry, 0, len(records))
+	for _, record := range records {
+		if len(record) < 2 {
+			return nil, errors.New("invalid segment list")
+		}
+		start, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment start %q", record[1])
+		}
+		entries = append(entries, segmentListEntry{name: record[0], start: start})
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("ffmpeg produced no segments")
+	}
+	return entries, nil
+}
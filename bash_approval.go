@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+// approveAsk is the profile value of approve that turns on --approve.
+const approveAsk = "ask"
+
+// errCommandDeclined is the tool error the model sees when a command is
+// refused at the prompt.
+var errCommandDeclined = errors.New("the user declined to run this command")
+
+// shellControl marks commands that chain, pipe, substitute or redirect. A
+// prefix rule never covers them, since "ls " would otherwise approve
+// "ls; rm -rf ~".
+var shellControl = []string{";", "&", "|", "`", "$(", ">", "<", "\n"}
+
+// twoWordTools get a two-word "always" prefix (git push, go test) because
+// their first word alone would allow every subcommand.
+var twoWordTools = map[string]bool{
+	"git": true, "go": true, "npm": true, "pnpm": true, "yarn": true, "cargo": true,
+	"docker": true, "kubectl": true, "gh": true, "pip": true, "uv": true, "make": true,
+}
+
+// bashApprover runs bash tool calls that an allow rule covers and asks on
+// the terminal before running anything else. Approved commands go to
+// runner, a bash tool pack that allows every command but keeps deny rules.
+type bashApprover struct {
+	allow  []sdk.BashCommandRule
+	deny   []sdk.BashCommandRule
+	runner *sdk.ToolRegistry
+
+	mu sync.Mutex
+	// in and out are the prompt's terminal, opened on the first command no
+	// rule covers.
+	in  *bufio.Reader
+	out io.Writer
+	// remember persists an "always" prefix to the profile's allow list.
+	remember func(prefix string) error
+}
+
+// bashApprovalResult is returned instead of the plain bash result when the
+// user edited the command, so the model knows what actually ran.
+type bashApprovalResult struct {
+	sdk.BashResult
+	EditedCommand string `json:"edited_command"`
+}
+
+func newBashApprover(allow, deny []sdk.BashCommandRule, runner *sdk.ToolRegistry, remember func(string) error) *bashApprover {
+	return &bashApprover{allow: allow, deny: deny, runner: runner, remember: remember}
+}
+
+// openTerminal wires the approval prompt to the controlling terminal. It is
+// deferred to the first prompt, so a run whose commands the allow rules all
+// cover needs no terminal.
+func (a *bashApprover) openTerminal() error {
+	if a.in != nil {
+		return nil
+	}
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		// The file stays open for the rest of the run.
+		a.in, a.out = bufio.NewReader(tty), tty
+		return nil
+	}
+	if stdinIsTTY, err := isTerminal(os.Stdin); err != nil || !stdinIsTTY {
+		return errors.New("--approve needs an interactive terminal")
+	}
+	a.in, a.out = bufio.NewReader(os.Stdin), os.Stderr
+	return nil
+}
+
+// approveRunner builds the registry approved commands run in.
+func approveRunner(root string, opts []sdk.LocalBashOption, deny []sdk.BashCommandRule) *sdk.ToolRegistry {
+	opts = append(slices.Clone(opts), sdk.WithLocalBashAllowAllCommands())
+	if len(deny) > 0 {
+		opts = append(opts, sdk.WithLocalBashDenyRules(deny...))
+	}
+	runner := sdk.NewToolRegistry()
+	sdk.NewLocalBashToolPack(root, opts...).RegisterInto(runner)
+	return runner
+}
+
+// handle is the bash tool handler under --approve.
+func (a *bashApprover) handle(args map[string]any, call llm.ToolCall) (any, error) {
+	command, _ := args["command"].(string)
+	command = strings.TrimSpace(command)
+	if command == "" || bashRulesMatch(a.deny, command) {
+		// The runner reports the usual error for these.
+		return a.run(call)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if bashCommandCovered(a.allow, command) {
+		return a.run(call)
+	}
+	if err := a.openTerminal(); err != nil {
+		return nil, fmt.Errorf("no allow rule covers %q and %w", command, err)
+	}
+	decision, edited := a.ask(command)
+	switch decision {
+	case 'n':
+		return nil, errCommandDeclined
+	case 'e':
+		if bashRulesMatch(a.deny, edited) {
+			return nil, fmt.Errorf("edited command %q matches a deny rule", edited)
+		}
+		result, err := a.run(withBashCommand(call, edited))
+		if bash, ok := result.(sdk.BashResult); ok {
+			return bashApprovalResult{BashResult: bash, EditedCommand: edited}, err
+		}
+		return result, err
+	}
+	return a.run(call)
+}
+
+// ask shows the command and reads a decision: y, n, a (always, after which
+// the prefix is allowed) or e (edited, with the new command). End of input
+// counts as no.
+func (a *bashApprover) ask(command string) (byte, string) {
+	_, _ = fmt.Fprintf(a.out, "\n\033[1;33mRun this command?\033[0m\n  %s\n", command)
+	for {
+		_, _ = fmt.Fprint(a.out, "[y]es / [n]o / [a]lways allow a prefix / [e]dit: ")
+		line, err := a.readLine()
+		if err != nil {
+			return 'n', ""
+		}
+		switch strings.ToLower(line) {
+		case "y", "yes":
+			return 'y', ""
+		case "n", "no", "":
+			return 'n', ""
+		case "a", "always":
+			suggested := suggestAllowPrefix(command)
+			_, _ = fmt.Fprintf(a.out, "Allow commands starting with [%s]: ", suggested)
+			prefix, err := a.readRawLine()
+			if err != nil {
+				return 'n', ""
+			}
+			if strings.TrimSpace(prefix) == "" {
+				prefix = suggested
+			}
+			if !bashRulesMatch([]sdk.BashCommandRule{sdk.BashCommandPrefix(prefix)}, command) {
+				_, _ = fmt.Fprintf(a.out, "%q does not start with %q\n", command, prefix)
+				continue
+			}
+			a.allow = append(a.allow, sdk.BashCommandPrefix(prefix))
+			if a.remember != nil {
+				if err := a.remember(prefix); err != nil {
+					_, _ = fmt.Fprintf(a.out, "warning: could not save %q to the profile: %v\n", prefix, err)
+				} else {
+					_, _ = fmt.Fprintf(a.out, "Saved %q to the profile's allow list.\n", prefix)
+				}
+			}
+			return 'a', ""
+		case "e", "edit":
+			_, _ = fmt.Fprint(a.out, "Command to run instead: ")
+			edited, err := a.readLine()
+			if err != nil || edited == "" {
+				continue
+			}
+			if edited == command {
+				return 'y', ""
+			}
+			return 'e', edited
+		}
+	}
+}
+
+func (a *bashApprover) readLine() (string, error) {
+	line, err := a.readRawLine()
+	return strings.TrimSpace(line), err
+}
+
+// readRawLine keeps spaces so a prefix like "git " can be typed.
+func (a *bashApprover) readRawLine() (string, error) {
+	line, err := a.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (a *bashApprover) run(call llm.ToolCall) (any, error) {
+	results := a.runner.ExecuteAll([]llm.ToolCall{call})
+	if len(results) == 0 {
+		return nil, errors.New("bash tool returned no result")
+	}
+	return results[0].Result, results[0].Error
+}
+
+// withBashCommand returns call with its command replaced.
+func withBashCommand(call llm.ToolCall, command string) llm.ToolCall {
+	if call.Function == nil {
+		return call
+	}
+	data, _ := json.Marshal(bashToolArgs{Command: command})
+	fn := *call.Function
+	fn.Arguments = string(data)
+	call.Function = &fn
+	return call
+}
+
+// bashCommandCovered reports whether an allow rule covers command. Prefix
+// rules do not cover commands with shell control operators; exact and
+// regexp rules are taken as written.
+func bashCommandCovered(rules []sdk.BashCommandRule, command string) bool {
+	for _, rule := range rules {
+		if _, isPrefix := rule.(sdk.BashCommandPrefix); isPrefix && hasShellControl(command) {
+			continue
+		}
+		if bashRulesMatch([]sdk.BashCommandRule{rule}, command) {
+			return true
+		}
+	}
+	return false
+}
+
+func bashRulesMatch(rules []sdk.BashCommandRule, command string) bool {
+	for _, rule := range rules {
+		switch r := rule.(type) {
+		case sdk.BashCommandPrefix:
+			// "ls " also covers a bare "ls".
+			if strings.HasPrefix(command, string(r)) || command == strings.TrimRight(string(r), " ") {
+				return true
+			}
+		case sdk.BashCommandExact:
+			if command == string(r) {
+				return true
+			}
+		case sdk.BashCommandRegexp:
+			if r.Re != nil && r.Re.MatchString(command) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasShellControl(command string) bool {
+	for _, op := range shellControl {
+		if strings.Contains(command, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestAllowPrefix proposes the "always" prefix for command: its first
+// word, or the first two for tools with subcommands.
+func suggestAllowPrefix(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return command
+	}
+	if len(fields) > 1 && twoWordTools[fields[0]] && !strings.HasPrefix(fields[1], "-") {
+		return fields[0] + " " + fields[1] + " "
+	}
+	return fields[0] + " "
+}
+
+// rememberAllowPrefix adds prefix to the allow list of the named profile.
+func rememberAllowPrefix(profileName, prefix string) error {
+	cfg, err := loadCLIConfig()
+	if err != nil {
+		return err
+	}
+	profile := profileFor(cfg, profileName)
+	if slices.Contains(profile.Allow, prefix) {
+		return nil
+	}
+	profile.Allow = append(profile.Allow, prefix)
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]cliProfile{}
+	}
+	cfg.Profiles[profileName] = profile
+	if cfg.CurrentProfile == "" {
+		cfg.CurrentProfile = profileName
+	}
+	return writeCLIConfig(cfg)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+	"testing"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+)
+
+func TestBashCommandCovered(t *testing.T) {
+	rules := []sdk.BashCommandRule{
+		sdk.BashCommandPrefix("git status"),
+		sdk.BashCommandExact("make test | tee out.log"),
+		sdk.BashCommandRegexp{Re: regexp.MustCompile(`^go (vet|test) \./\.\.\.$`)},
+	}
+	cases := map[string]bool{
+		"git status -s":           true,
+		"git status; rm -rf ~":    false,
+		"git status && git push":  false,
+		"git status > /tmp/x":     false,
+		"git push":                false,
+		"make test | tee out.log": true,
+		"go test ./...":           true,
+		"go test ./... -run X":    false,
+	}
+	for command, want := range cases {
+		if got := bashCommandCovered(rules, command); got != want {
+			t.Errorf("bashCommandCovered(%q) = %v, want %v", command, got, want)
+		}
+	}
+}
+
+func TestSuggestAllowPrefix(t *testing.T) {
+	cases := map[string]string{
+		"git push origin main": "git push ",
+		"go test ./...":        "go test ",
+		"git --version":        "git ",
+		"ls -la":               "ls ",
+		"pytest":               "pytest ",
+	}
+	for command, want := range cases {
+		if got := suggestAllowPrefix(command); got != want {
+			t.Errorf("suggestAllowPrefix(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+func TestBashApproverAsk(t *testing.T) {
+	var remembered []string
+	newApprover := func(input string) (*bashApprover, *strings.Builder) {
+		out := &strings.Builder{}
+		return &bashApprover{
+			in:  bufio.NewReader(strings.NewReader(input)),
+			out: out,
+			remember: func(prefix string) error {
+				remembered = append(remembered, prefix)
+				return nil
+			},
+		}, out
+	}
+
+	approver, out := newApprover("maybe\ny\n")
+	if decision, _ := approver.ask("rm build.log"); decision != 'y' {
+		t.Fatalf("decision = %c", decision)
+	}
+	if strings.Count(out.String(), "[y]es") != 2 {
+		t.Fatalf("expected a second prompt after an unknown answer:\n%s", out.String())
+	}
+
+	approver, _ = newApprover("a\n\n")
+	if decision, _ := approver.ask("go test ./..."); decision != 'a' {
+		t.Fatalf("decision = %c", decision)
+	}
+	if len(remembered) != 1 || remembered[0] != "go test " || !bashCommandCovered(approver.allow, "go test -run X ./pkg") {
+		t.Fatalf("remembered = %q, allow = %v", remembered, approver.allow)
+	}
+
+	approver, _ = newApprover("a\nnpm \ne\nls -la\n")
+	decision, edited := approver.ask("ls")
+	if decision != 'e' || edited != "ls -la" {
+		t.Fatalf("decision = %c, edited = %q", decision, edited)
+	}
+
+	approver, _ = newApprover("")
+	if decision, _ := approver.ask("curl example.com"); decision != 'n' {
+		t.Fatalf("decision at EOF = %c", decision)
+	}
+}
+
+func TestRememberAllowPrefix(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for range 2 {
+		if err := rememberAllowPrefix("work", "git status"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := loadCLIConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allow := cfg.Profiles["work"].Allow; len(allow) != 1 || allow[0] != "git status" || cfg.CurrentProfile != "work" {
+		t.Fatalf("config = %+v", cfg)
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+// A cassette is a directory holding the HTTP exchanges of one or more mrl
+// runs, so scripts built on mrl can be replayed offline (e.g. in CI). Every
+// HTTP client in mrl, and the SDK client, sends through http.DefaultTransport;
+// --record and --replay swap it for a cassetteTransport.
+const cassetteFileName = "exchanges.jsonl"
+
+// errCassetteMiss is returned in replay mode for a request with no recorded
+// exchange. Nothing is sent over the network.
+var errCassetteMiss = errors.New("no recorded exchange")
+
+// cassetteExchange is one line of exchanges.jsonl. Requests are kept only as
+// a SHA-256 of their body, which is all replay matches on, so headers and
+// bodies carrying credentials (API keys, login passwords) never reach disk.
+// Credential fields in JSON response bodies are redacted (see
+// redactCassetteBody). URLs keep only path and query, so a cassette replays
+// against any --base-url.
+type cassetteExchange struct {
+	Seq        int         `json:"seq"`
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	BodySHA256 string      `json:"body_sha256,omitempty"`
+	Status     int         `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"`
+	RecordedAt time.Time   `json:"recorded_at"`
+	DurationMS int64       `json:"duration_ms"`
+}
+
+func (e cassetteExchange) body() ([]byte, error) {
+	if e.BodyBase64 != "" {
+		return base64.StdEncoding.DecodeString(e.BodyBase64)
+	}
+	return []byte(e.Body), nil
+}
+
+// cassetteResponseHeaders are the response headers worth replaying; the rest
+// (dates, connection handling, tracing) only make cassettes noisy.
+var cassetteResponseHeaders = []string{"Content-Type", "Retry-After", "X-Request-Id"}
+
+type cassetteTransport struct {
+	dir  string
+	next http.RoundTripper
+	// bypass lets requests through unrecorded, e.g. calls to mrl's own
+	// loopback RLM servers.
+	bypass func(*http.Request) bool
+
+	mu        sync.Mutex
+	replaying bool
+	seq       int
+	recorded  []cassetteExchange
+	used      []bool
+}
+
+// cassetteReplayAPIKey stands in for a missing API key during --replay, since
+// replayed runs never reach the API.
+const cassetteReplayAPIKey = "mr_sk_replay"
+
+// setupCassette applies the --record and --replay persistent flags.
+func setupCassette(cmd *cobra.Command, cfg *runtimeConfig) error {
+	recordDir, _ := cmd.Flags().GetString("record")
+	replayDir, _ := cmd.Flags().GetString("replay")
+	switch {
+	case strings.TrimSpace(recordDir) != "" && strings.TrimSpace(replayDir) != "":
+		return errors.New("--record and --replay are mutually exclusive")
+	case strings.TrimSpace(recordDir) != "":
+		return installCassette(recordDir, false)
+	case strings.TrimSpace(replayDir) != "":
+		if err := installCassette(replayDir, true); err != nil {
+			return err
+		}
+		if cfg.APIKey == "" && cfg.Token == "" {
+			cfg.APIKey = cassetteReplayAPIKey
+		}
+	}
+	return nil
+}
+
+// installCassette routes http.DefaultTransport through a cassette in dir,
+// recording (replay=false) or replaying exchanges.
+func installCassette(dir string, replay bool) error {
+	transport, err := newCassetteTransport(dir, replay, http.DefaultTransport)
+	if err != nil {
+		return err
+	}
+	http.DefaultTransport = transport
+	return nil
+}
+
+func newCassetteTransport(dir string, replay bool, next http.RoundTripper) (*cassetteTransport, error) {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil, errors.New("cassette directory is required")
+	}
+	t := &cassetteTransport{dir: dir, next: next, replaying: replay, bypass: isLocalRLMRequest}
+	if !replay {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("create cassette directory: %w", err)
+		}
+		return t, nil
+	}
+	recorded, err := readCassette(filepath.Join(dir, cassetteFileName))
+	if err != nil {
+		return nil, err
+	}
+	t.recorded = recorded
+	t.used = make([]bool, len(recorded))
+	return t, nil
+}
+
+func readCassette(path string) ([]cassetteExchange, error) {
+	f, err := os.Open(path) //nolint:gosec // cassette directory is chosen by the CLI user
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no cassette at %s (record one with --record)", path)
+		}
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var exchanges []cassetteExchange
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var exchange cassetteExchange
+		if err := json.Unmarshal(scanner.Bytes(), &exchange); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	return exchanges, scanner.Err()
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.bypass != nil && t.bypass(req) {
+		return t.next.RoundTrip(req)
+	}
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if t.replaying {
+		return t.replay(req, body)
+	}
+	return t.record(req, body)
+}
+
+// readRequestBody drains the request body and puts an identical copy back.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func cassetteURL(req *http.Request) string {
+	return req.URL.RequestURI()
+}
+
+func bodyDigest(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// replay answers from the first unused exchange with the same method, URL and
+// request body; failing that, from the first unused one with the same method
+// and URL, which tolerates request fields that change between runs.
+func (t *cassetteTransport) replay(req *http.Request, body []byte) (*http.Response, error) {
+	url := cassetteURL(req)
+	digest := bodyDigest(body)
+	t.mu.Lock()
+	match := -1
+	for i, exchange := range t.recorded {
+		if t.used[i] || exchange.Method != req.Method || exchange.URL != url {
+			continue
+		}
+		if exchange.BodySHA256 == digest {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match >= 0 {
+		t.used[match] = true
+	}
+	t.mu.Unlock()
+	if match < 0 {
+		return nil, fmt.Errorf("replay %s %s: %w", req.Method, url, errCassetteMiss)
+	}
+
+	exchange := t.recorded[match]
+	data, err := exchange.body()
+	if err != nil {
+		return nil, fmt.Errorf("replay %s %s: %w", req.Method, url, err)
+	}
+	header := exchange.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.Status, http.StatusText(exchange.Status)),
+		StatusCode:    exchange.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+func (t *cassetteTransport) record(req *http.Request, body []byte) (*http.Response, error) {
+	t.mu.Lock()
+	t.seq++
+	exchange := cassetteExchange{
+		Seq:        t.seq,
+		Method:     req.Method,
+		URL:        cassetteURL(req),
+		BodySHA256: bodyDigest(body),
+		RecordedAt: time.Now().UTC(),
+	}
+	t.mu.Unlock()
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	exchange.Status = resp.StatusCode
+	for _, name := range cassetteResponseHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			if exchange.Header == nil {
+				exchange.Header = http.Header{}
+			}
+			exchange.Header[name] = values
+		}
+	}
+	// Streaming responses are passed through as they arrive; the exchange is
+	// written once the caller has consumed or closed the body.
+	resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(data []byte) {
+		exchange.DurationMS = time.Since(start).Milliseconds()
+		data = redactCassetteBody(data)
+		if utf8.Valid(data) {
+			exchange.Body = string(data)
+		} else {
+			exchange.BodyBase64 = base64.StdEncoding.EncodeToString(data)
+		}
+		if err := t.append(exchange); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to record %s %s: %v\n", exchange.Method, exchange.URL, err)
+		}
+	}}
+	return resp, nil
+}
+
+// cassetteSecretFields are JSON fields whose values are replaced before a
+// response body is written to a cassette, e.g. the tokens returned by
+// `mrl auth login`.
+var cassetteSecretFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"token":         true,
+	"api_key":       true,
+	"secret":        true,
+	"client_secret": true,
+	"password":      true,
+}
+
+const cassetteRedacted = "[redacted]"
+
+// redactCassetteBody replaces the string values of cassetteSecretFields
+// anywhere in a JSON body. Bodies that aren't a single JSON value (streams,
+// binary data) or hold no such field are returned unchanged.
+func redactCassetteBody(data []byte) []byte {
+	if !json.Valid(data) {
+		return data
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return data
+	}
+	if !redactSecretFields(value) {
+		return data
+	}
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+func redactSecretFields(value any) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if _, ok := field.(string); ok && cassetteSecretFields[strings.ToLower(key)] {
+				v[key] = cassetteRedacted
+				changed = true
+				continue
+			}
+			if redactSecretFields(field) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if redactSecretFields(item) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func (t *cassetteTransport) append(exchange cassetteExchange) error {
+	line, err := json.Marshal(exchange)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, err := os.OpenFile(filepath.Join(t.dir, cassetteFileName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600) //nolint:gosec // cassette directory is chosen by the CLI user
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// recordingBody copies everything read from a response body and hands the
+// copy to done exactly once, at EOF or Close.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		b.once.Do(func() { b.done(b.buf.Bytes()) })
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.buf.Bytes()) })
+	return err
+}
+
+// localRLMHosts are the loopback servers mrl starts for the RLM runner. Their
+// traffic is internal plumbing; the model calls they make are recorded.
+var localRLMHosts sync.Map
+
+func isLocalRLMRequest(req *http.Request) bool {
+	_, ok := localRLMHosts.Load(req.URL.Host)
+	return ok
+}
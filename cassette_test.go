@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/responses:stream" {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			flusher, _ := w.(http.Flusher)
+			for _, line := range []string{`{"delta":"hel"}`, `{"delta":"lo"}`} {
+				_, _ = io.WriteString(w, line+"\n")
+				flusher.Flush()
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder, err := newCassetteTransport(dir, false, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: recorder}
+	for _, payload := range []string{`"a"`, `"b"`} {
+		resp, err := client.Post(server.URL+"/customers?limit=1", "application/json", strings.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+	}
+	resp, err := client.Post(server.URL+"/responses:stream", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	replayer, err := newCassetteTransport(dir, true, failingTransport{t})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayer.recorded) != 3 {
+		t.Fatalf("expected 3 recorded exchanges, got %d", len(replayer.recorded))
+	}
+	if replayer.recorded[0].Header.Get("Set-Cookie") != "" {
+		t.Fatal("cassette must not keep unlisted response headers")
+	}
+	client = &http.Client{Transport: replayer}
+
+	// Requests are matched by body first, so order may differ from the recording.
+	for _, payload := range []string{`"b"`, `"a"`} {
+		resp, err := client.Post("http://replay.invalid/customers?limit=1", "application/json", strings.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(body) != `{"echo":`+payload+`}` || resp.Header.Get("Content-Type") != "application/json" {
+			t.Fatalf("unexpected replay for %s: %s %v", payload, body, resp.Header)
+		}
+	}
+	resp, err = client.Post("http://replay.invalid/responses:stream", "application/json", strings.NewReader(`{"changed":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(replayed) != string(streamed) {
+		t.Fatalf("stream replay = %q, want %q", replayed, streamed)
+	}
+
+	_, err = client.Get("http://replay.invalid/customers")
+	if !errors.Is(err, errCassetteMiss) {
+		t.Fatalf("expected cassette miss, got %v", err)
+	}
+}
+
+func TestCassetteReplayRequiresRecording(t *testing.T) {
+	if _, err := newCassetteTransport(t.TempDir(), true, http.DefaultTransport); err == nil {
+		t.Fatal("expected error for a directory without a cassette")
+	}
+}
+
+func TestCassetteKeepsNoCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"at-secret","refresh_token":"rt-secret","expires_in":3600}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	useCassette(t, dir, false)
+	cfg := runtimeConfig{BaseURL: server.URL, Timeout: 5 * time.Second}
+	var resp loginResponse
+	if err := doJSON(context.Background(), cfg, authModeNone, http.MethodPost, "/auth/login",
+		map[string]any{"email": "dev@example.com", "password": "pw-secret"}, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.AccessToken != "at-secret" {
+		t.Fatalf("recording must not change the live response, got %q", resp.AccessToken)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, cassetteFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"pw-secret", "at-secret", "rt-secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("cassette contains %q: %s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "expires_in") {
+		t.Fatalf("non-credential fields should be kept: %s", data)
+	}
+}
+
+func TestCassetteRecordsAndReplaysSDKCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(llm.Response{
+			ID:    "resp_test",
+			Model: "demo",
+			Output: []llm.OutputItem{{
+				Type:    llm.OutputItemTypeMessage,
+				Role:    llm.RoleAssistant,
+				Content: []llm.ContentPart{llm.TextPart("recorded answer")},
+			}},
+		})
+	}))
+
+	dir := t.TempDir()
+	cfg := runtimeConfig{BaseURL: server.URL, APIKey: "mr_sk_test", Timeout: 5 * time.Second}
+	input := []llm.InputItem{llm.NewUserText("ping")}
+
+	useCassette(t, dir, false)
+	client, err := newPromptClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := runCompletion(context.Background(), client, "demo", "", input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	useCassette(t, dir, true)
+	client, err = newPromptClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := runCompletion(context.Background(), client, "demo", "", input, nil)
+	if err != nil {
+		t.Fatalf("replay through the SDK client: %v", err)
+	}
+	if recorded.Text != "recorded answer" || replayed.Text != recorded.Text {
+		t.Fatalf("recorded %q, replayed %q", recorded.Text, replayed.Text)
+	}
+}
+
+// useCassette installs a cassette on http.DefaultTransport for the rest of
+// the test, the way --record and --replay do.
+func useCassette(t *testing.T, dir string, replay bool) {
+	t.Helper()
+	previous := http.DefaultTransport
+	base := previous
+	if replay {
+		base = failingTransport{t}
+	}
+	transport, err := newCassetteTransport(dir, replay, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	http.DefaultTransport = transport
+	t.Cleanup(func() { http.DefaultTransport = previous })
+}
+
+// failingTransport fails the test if replay ever reaches the network.
+type failingTransport struct{ t *testing.T }
+
+func (f failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.t.Fatalf("replay reached the network: %s %s", req.Method, req.URL)
+	return nil, errors.New("unreachable")
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxStoredRuns is how many runs keep their checkpoints; older ones are
+// removed when a new run starts.
+const maxStoredRuns = 20
+
+// agentRun is a do or agent loop run whose tool root is checkpointed. It is
+// stored as run.json in its run directory, next to objects/ (file contents
+// by SHA-256) and checkpoints/ (one file list per turn).
+type agentRun struct {
+	ID        string    `json:"id"`
+	Command   string    `json:"command"`
+	Root      string    `json:"root"`
+	StartedAt time.Time `json:"started_at"`
+
+	dir string
+}
+
+// runCheckpoint lists the files and symlinks under the tool root before the
+// tools of a turn ran. Files ignored by .gitignore, .git and node_modules are
+// left out, and undo leaves them alone.
+type runCheckpoint struct {
+	Turn      int              `json:"turn"`
+	CreatedAt time.Time        `json:"created_at"`
+	Files     []checkpointFile `json:"files"`
+}
+
+type checkpointFile struct {
+	// Path is slash-separated and relative to the tool root.
+	Path string `json:"path"`
+	// Link is the target of a symlink, which has no content of its own.
+	Link    string      `json:"link,omitempty"`
+	SHA256  string      `json:"sha256,omitempty"`
+	Size    int64       `json:"size"`
+	Mode    fs.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mod_time"`
+}
+
+// runCheckpointer snapshots the tool root of a run before each turn that
+// executes tools. The run is recorded at the first snapshot, so runs without
+// tool calls leave nothing behind. Files whose size and modification time
+// match the previous snapshot are not read again.
+type runCheckpointer struct {
+	command string
+	root    string
+	run     *agentRun
+	last    map[string]checkpointFile
+	// disabled is set when the run can't be recorded; warned after the
+	// first failed snapshot.
+	disabled bool
+	warned   bool
+}
+
+func runsDir() (string, error) {
+	dir, err := defaultDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "runs"), nil
+}
+
+// startAgentRun creates the directory of a new run over root.
+func startAgentRun(command, root string, now time.Time) (*agentRun, error) {
+	absRoot, err := filepath.Abs(firstNonEmpty(strings.TrimSpace(root), "."))
+	if err != nil {
+		return nil, err
+	}
+	base, err := runsDir()
+	if err != nil {
+		return nil, err
+	}
+	run := &agentRun{
+		ID:        now.UTC().Format("20060102T150405Z") + "-" + uuid.NewString()[:8],
+		Command:   command,
+		Root:      absRoot,
+		StartedAt: now.UTC(),
+	}
+	run.dir = filepath.Join(base, run.ID)
+	for _, sub := range []string{"objects", "checkpoints"} {
+		if err := os.MkdirAll(filepath.Join(run.dir, sub), 0o700); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeJSONFile(filepath.Join(run.dir, "run.json"), run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// beginAgentRun starts a run over root and removes the oldest runs beyond
+// maxStoredRuns.
+func beginAgentRun(command, root string) (*agentRun, error) {
+	run, err := startAgentRun(command, root, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if base, err := runsDir(); err == nil {
+		if err := pruneAgentRuns(base, maxStoredRuns); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: pruning old runs: %v\n", err)
+		}
+	}
+	return run, nil
+}
+
+func pruneAgentRuns(base string, keep int) error {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	// Run IDs start with their UTC start time, so they sort by age.
+	sort.Strings(ids)
+	for len(ids) > keep {
+		if err := os.RemoveAll(filepath.Join(base, ids[0])); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// loadAgentRun reads a run by ID.
+func loadAgentRun(id string) (*agentRun, error) {
+	if !storedNamePattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid run id %q", id)
+	}
+	base, err := runsDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(base, id)
+	data, err := os.ReadFile(filepath.Join(dir, "run.json")) //nolint:gosec // path is derived from a validated run id under the mrl data dir
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run %q not found", id)
+		}
+		return nil, err
+	}
+	var run agentRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse run %s: %w", id, err)
+	}
+	run.dir = dir
+	return &run, nil
+}
+
+// latestAgentRun returns the most recent run over root that has at least
+// one checkpoint.
+func latestAgentRun(root string) (*agentRun, error) {
+	absRoot, err := filepath.Abs(firstNonEmpty(strings.TrimSpace(root), "."))
+	if err != nil {
+		return nil, err
+	}
+	base, err := runsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(base)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		run, err := loadAgentRun(entries[i].Name())
+		if err != nil || run.Root != absRoot {
+			continue
+		}
+		if checkpoints, err := run.checkpoints(); err == nil && len(checkpoints) > 0 {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("no checkpointed runs for %s", absRoot)
+}
+
+func newRunCheckpointer(command, root string) *runCheckpointer {
+	return &runCheckpointer{command: command, root: root, last: map[string]checkpointFile{}}
+}
+
+// checkpointerFor checkpoints a run that was already started.
+func checkpointerFor(run *agentRun) *runCheckpointer {
+	return &runCheckpointer{command: run.Command, root: run.Root, run: run, last: map[string]checkpointFile{}}
+}
+
+// beforeTools snapshots the tool root before the tools of turn run. Only the
+// first failure is reported, and it doesn't stop the run.
+func (c *runCheckpointer) beforeTools(turn int) {
+	if c == nil || c.disabled {
+		return
+	}
+	if c.run == nil {
+		run, err := beginAgentRun(c.command, c.root)
+		if err != nil {
+			c.disabled = true
+			fmt.Fprintf(os.Stderr, "warning: checkpoints disabled, so undo won't cover this run: %v\n", err)
+			return
+		}
+		c.run = run
+	}
+	if err := c.snapshot(turn, time.Now()); err != nil && !c.warned {
+		c.warned = true
+		fmt.Fprintf(os.Stderr, "warning: checkpoint before turn %d failed, so undo may not cover this run: %v\n", turn, err)
+	}
+}
+
+// snapshot records the tool root as it is before the tools of turn run.
+func (c *runCheckpointer) snapshot(turn int, now time.Time) error {
+	paths, err := walkUnignored(c.run.Root, nil, true)
+	if err != nil {
+		return err
+	}
+	checkpoint := runCheckpoint{Turn: turn, CreatedAt: now.UTC(), Files: make([]checkpointFile, 0, len(paths))}
+	current := make(map[string]checkpointFile, len(paths))
+	for _, path := range paths {
+		rel, err := filepath.Rel(c.run.Root, path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		file := checkpointFile{Path: filepath.ToSlash(rel), Size: info.Size(), Mode: info.Mode().Perm(), ModTime: info.ModTime().UTC()}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if file.Link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		} else if prev, ok := c.last[file.Path]; ok && prev.Link == "" && prev.Size == file.Size && prev.ModTime.Equal(file.ModTime) {
+			file.SHA256 = prev.SHA256
+		} else if file.SHA256, err = c.run.storeObject(path); err != nil {
+			return err
+		}
+		checkpoint.Files = append(checkpoint.Files, file)
+		current[file.Path] = file
+	}
+	c.last = current
+	return writeJSONFile(c.run.checkpointPath(turn), checkpoint)
+}
+
+func (r *agentRun) checkpointPath(turn int) string {
+	return filepath.Join(r.dir, "checkpoints", "turn-"+strconv.Itoa(turn)+".json")
+}
+
+func (r *agentRun) objectPath(sum string) string {
+	return filepath.Join(r.dir, "objects", sum[:2], sum)
+}
+
+// storeObject copies the file at path into the object store and returns its
+// SHA-256.
+func (r *agentRun) storeObject(path string) (string, error) {
+	src, err := os.Open(path) //nolint:gosec // path comes from walking the run's tool root
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = src.Close() }()
+
+	tmp, err := os.CreateTemp(filepath.Join(r.dir, "objects"), "incoming-")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	hash := sha256.New()
+	_, copyErr := io.Copy(io.MultiWriter(tmp, hash), src)
+	if closeErr := tmp.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+	if copyErr != nil {
+		return "", copyErr
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	dest := r.objectPath(sum)
+	if _, err := os.Stat(dest); err == nil {
+		return sum, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return "", err
+	}
+	return sum, os.Rename(tmp.Name(), dest)
+}
+
+// checkpoints lists the run's checkpoints by turn.
+func (r *agentRun) checkpoints() ([]runCheckpoint, error) {
+	entries, err := os.ReadDir(filepath.Join(r.dir, "checkpoints"))
+	if err != nil {
+		return nil, err
+	}
+	var out []runCheckpoint
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "turn-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(r.dir, "checkpoints", name)) //nolint:gosec // file is listed from the run's checkpoint dir
+		if err != nil {
+			return nil, err
+		}
+		var checkpoint runCheckpoint
+		if err := json.Unmarshal(data, &checkpoint); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint %s: %w", name, err)
+		}
+		out = append(out, checkpoint)
+	}
+	slices.SortFunc(out, func(a, b runCheckpoint) int { return a.Turn - b.Turn })
+	return out, nil
+}
+
+// restoreSummary reports what restoring a checkpoint changed, with paths
+// relative to the tool root.
+type restoreSummary struct {
+	Written []string `json:"written"`
+	Deleted []string `json:"deleted"`
+}
+
+// restore puts the tool root back as it was at checkpoint: changed and
+// deleted files are written back and files created since are removed.
+func (r *agentRun) restore(checkpoint runCheckpoint) (restoreSummary, error) {
+	var summary restoreSummary
+	paths, err := walkUnignored(r.Root, nil, true)
+	if err != nil {
+		return summary, err
+	}
+	wanted := make(map[string]checkpointFile, len(checkpoint.Files))
+	for _, file := range checkpoint.Files {
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) || (file.Link == "" && len(file.SHA256) < 2) {
+			return summary, fmt.Errorf("checkpoint has an invalid entry %q", file.Path)
+		}
+		wanted[file.Path] = file
+	}
+
+	for _, path := range paths {
+		rel, err := filepath.Rel(r.Root, path)
+		if err != nil {
+			return summary, err
+		}
+		if _, keep := wanted[filepath.ToSlash(rel)]; keep {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return summary, err
+		}
+		removeEmptyParents(r.Root, filepath.Dir(path))
+		summary.Deleted = append(summary.Deleted, filepath.ToSlash(rel))
+	}
+
+	for _, file := range checkpoint.Files {
+		path := filepath.Join(r.Root, filepath.FromSlash(file.Path))
+		if file.Link != "" {
+			if target, err := os.Readlink(path); err == nil && target == file.Link {
+				continue
+			}
+			if err := restoreSymlink(file, path); err != nil {
+				return summary, fmt.Errorf("restore %s: %w", file.Path, err)
+			}
+			summary.Written = append(summary.Written, file.Path)
+			continue
+		}
+		if same, err := fileHasSHA256(path, file); err != nil {
+			return summary, err
+		} else if same {
+			continue
+		}
+		if err := r.writeObject(file, path); err != nil {
+			return summary, fmt.Errorf("restore %s: %w", file.Path, err)
+		}
+		summary.Written = append(summary.Written, file.Path)
+	}
+	return summary, nil
+}
+
+func (r *agentRun) writeObject(file checkpointFile, path string) error {
+	data, err := os.ReadFile(r.objectPath(file.SHA256))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// A symlink or directory may have taken the file's place.
+	if info, err := os.Lstat(path); err == nil && !info.Mode().IsRegular() {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, data, file.Mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, file.Mode)
+}
+
+// restoreSymlink recreates the symlink file at path, replacing whatever took
+// its place.
+func restoreSymlink(file checkpointFile, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	return os.Symlink(file.Link, path)
+}
+
+// fileHasSHA256 reports whether path is a regular file with file's content.
+func fileHasSHA256(path string, file checkpointFile) (bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !info.Mode().IsRegular() || info.Size() != file.Size || info.Mode().Perm() != file.Mode {
+		return false, nil
+	}
+	f, err := os.Open(path) //nolint:gosec // path is inside the run's tool root
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)) == file.SHA256, nil
+}
+
+// removeEmptyParents removes dir and its parents up to root while they are
+// empty.
+func removeEmptyParents(root, dir string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// writeJSONFile replaces path atomically, so a run interrupted mid-write
+// keeps the previous version.
+func writeJSONFile(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
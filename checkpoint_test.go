@@ -0,0 +1,183 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func readTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path) //nolint:gosec // test reads its own temp tree
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.go":       "package main\n",
+		"docs/a.md":     "# A\n",
+		".gitignore":    "build/\n",
+		"build/out.bin": "artifact",
+		".git/HEAD":     "ref: refs/heads/main\n",
+	})
+	before := readTree(t, root)
+
+	checkpoints := newRunCheckpointer("agent loop", root)
+	checkpoints.beforeTools(0)
+	if checkpoints.run == nil || checkpoints.warned {
+		t.Fatal("first snapshot failed")
+	}
+	// Turn 0 edits a file, deletes one and adds a directory.
+	writeTree(t, root, map[string]string{"main.go": "package main\n\nfunc main() {}\n", "pkg/new/x.go": "package x\n"})
+	if err := os.Remove(filepath.Join(root, "docs", "a.md")); err != nil {
+		t.Fatal(err)
+	}
+	checkpoints.beforeTools(1)
+	afterTurn0 := readTree(t, root)
+	// Turn 1 rewrites the new file and touches an ignored one.
+	writeTree(t, root, map[string]string{"pkg/new/x.go": "package y\n", "build/out.bin": "rebuilt"})
+
+	run, err := latestAgentRun(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := run.checkpoints()
+	if err != nil || len(saved) != 2 || saved[0].Turn != 0 || saved[1].Turn != 1 {
+		t.Fatalf("checkpoints = %+v, %v", saved, err)
+	}
+
+	summary, err := run.restore(saved[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := afterTurn0
+	want["build/out.bin"] = "rebuilt"
+	if got := readTree(t, root); !maps.Equal(got, want) || !slices.Equal(summary.Written, []string{"pkg/new/x.go"}) {
+		t.Fatalf("after restoring turn 1: %v\nsummary %+v", got, summary)
+	}
+
+	summary, err = run.restore(saved[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = before
+	want["build/out.bin"] = "rebuilt"
+	if got := readTree(t, root); !maps.Equal(got, want) {
+		t.Fatalf("after restoring turn 0: %v", got)
+	}
+	if !slices.Equal(summary.Deleted, []string{"pkg/new/x.go"}) {
+		t.Fatalf("summary = %+v", summary)
+	}
+	if _, err := os.Stat(filepath.Join(root, "pkg")); !os.IsNotExist(err) {
+		t.Fatalf("empty directories left behind: %v", err)
+	}
+}
+
+func TestCheckpointRestoresSymlinks(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"config/base.yaml": "a: 1\n", "config/prod.yaml": "a: 2\n"})
+	if err := os.Symlink("config/base.yaml", filepath.Join(root, "current.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoints := newRunCheckpointer("do", root)
+	checkpoints.beforeTools(0)
+	if checkpoints.run == nil || checkpoints.warned {
+		t.Fatal("snapshot failed")
+	}
+	// The turn repoints the link and adds another one.
+	if err := os.Remove(filepath.Join(root, "current.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("config/prod.yaml", filepath.Join(root, "current.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("config", filepath.Join(root, "cfg")); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := checkpoints.run.checkpoints()
+	if err != nil || len(saved) != 1 {
+		t.Fatalf("checkpoints = %+v, %v", saved, err)
+	}
+	summary, err := checkpoints.run.restore(saved[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target, err := os.Readlink(filepath.Join(root, "current.yaml")); err != nil || target != "config/base.yaml" {
+		t.Fatalf("current.yaml -> %q, %v", target, err)
+	}
+	if _, err := os.Lstat(filepath.Join(root, "cfg")); !os.IsNotExist(err) {
+		t.Fatalf("symlink created by the run was kept: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "config", "prod.yaml")); err != nil || string(data) != "a: 2\n" {
+		t.Fatalf("link target changed: %q, %v", data, err)
+	}
+	if !slices.Equal(summary.Written, []string{"current.yaml"}) || !slices.Equal(summary.Deleted, []string{"cfg"}) {
+		t.Fatalf("summary = %+v", summary)
+	}
+}
+
+func TestPruneAgentRuns(t *testing.T) {
+	base := t.TempDir()
+	for _, id := range []string{"20260103T000000Z-c", "20260101T000000Z-a", "20260102T000000Z-b"} {
+		if err := os.Mkdir(filepath.Join(base, id), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pruneAgentRuns(base, 2); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(base)
+	if len(entries) != 2 || entries[0].Name() != "20260102T000000Z-b" {
+		t.Fatalf("entries = %v", entries)
+	}
+}
+
+func TestLatestAgentRunSkipsOtherRoots(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	rootA, rootB := t.TempDir(), t.TempDir()
+	writeTree(t, rootA, map[string]string{"a.txt": "a"})
+	writeTree(t, rootB, map[string]string{"b.txt": "b"})
+	now := time.Now()
+	for i, root := range []string{rootA, rootB} {
+		run, err := startAgentRun("do", root, now.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := (&runCheckpointer{run: run}).snapshot(0, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run, err := latestAgentRun(rootA)
+	if err != nil || run.Root != rootA {
+		t.Fatalf("latestAgentRun = %+v, %v", run, err)
+	}
+	if _, err := latestAgentRun(t.TempDir()); err == nil {
+		t.Fatal("expected no run for an unrelated root")
+	}
+	if _, err := loadAgentRun("../escape"); err == nil {
+		t.Fatal("expected an invalid run id to be rejected")
+	}
+}
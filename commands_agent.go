@@ -17,6 +17,8 @@ func newAgentCmd() *cobra.Command {
 		Short: "Agent tools",
 	}
 	cmd.AddCommand(newAgentLoopCmd())
+	cmd.AddCommand(newAgentUndoCmd())
+	cmd.AddCommand(newAgentResumeCmd())
 	return cmd
 }
 
@@ -5,23 +5,28 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
 	sdk "github.com/modelrelay/modelrelay/sdk/go"
 	"github.com/modelrelay/modelrelay/sdk/go/llm"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 const toolNameTasksWrite sdk.ToolName = "tasks_write"
 
 type agentLoopFlags struct {
 	inputText       string
+	editor          bool
 	inputFile       string
 	systemPrompt    string
 	model           string
@@ -34,14 +39,21 @@ type agentLoopFlags struct {
 	bashAllow       []string
 	bashDeny        []string
 	bashAllowAll    bool
+	bashApprove     bool
 	bashTimeout     time.Duration
 	bashMaxOutBytes uint64
+	sandbox         sandboxOptions
+	noCheckpoint    bool
 	stateID         string
 	stateTTLSeconds int64
 	outputPath      string
 	trace           bool
 	tasksOutputPath string
 	printTasks      bool
+	schemaFile      string
+	schemaRetries   int
+	maxCost         float64
+	fallbacks       []string
 }
 
 type agentLoopStep struct {
@@ -52,12 +64,16 @@ type agentLoopStep struct {
 }
 
 type agentLoopResult struct {
-	Output   string          `json:"output,omitempty"`
-	Usage    sdk.AgentUsage  `json:"usage"`
-	StateID  string          `json:"state_id,omitempty"`
-	Steps    []agentLoopStep `json:"steps,omitempty"`
-	Tasks    []runTask       `json:"tasks,omitempty"`
-	Response *sdk.Response   `json:"response,omitempty"`
+	Output     string          `json:"output,omitempty"`
+	Structured json.RawMessage `json:"structured,omitempty"`
+	Model      string          `json:"model,omitempty"`
+	Usage      sdk.AgentUsage  `json:"usage"`
+	CostCents  *float64        `json:"estimated_cost_cents,omitempty"`
+	StateID    string          `json:"state_id,omitempty"`
+	RunID      string          `json:"run_id,omitempty"`
+	Steps      []agentLoopStep `json:"steps,omitempty"`
+	Tasks      []runTask       `json:"tasks,omitempty"`
+	Response   *sdk.Response   `json:"response,omitempty"`
 }
 
 func newAgentLoopCmd() *cobra.Command {
@@ -67,7 +83,7 @@ func newAgentLoopCmd() *cobra.Command {
 		Short: "Run an agentic tool loop with local tools",
 		Args:  cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runAgentLoop(cmd, args, flags)
+			return runAgentLoop(cmd, cmd.Flags(), args, flags, nil)
 		},
 	}
 	bindAgentLoopFlags(cmd, flags)
@@ -77,8 +93,10 @@ func newAgentLoopCmd() *cobra.Command {
 func bindAgentLoopFlags(cmd *cobra.Command, flags *agentLoopFlags) {
 	cmd.Flags().StringVar(&flags.inputText, "input", "", "Inline user input")
 	cmd.Flags().StringVar(&flags.inputFile, "input-file", "", "Path to JSON array of input items")
+	cmd.Flags().BoolVar(&flags.editor, "editor", false, "Compose the input in $VISUAL/$EDITOR (--input or arguments seed the buffer)")
 	cmd.Flags().StringVar(&flags.systemPrompt, "system", "", "System prompt")
 	cmd.Flags().StringVar(&flags.model, "model", "", "Model ID")
+	cmd.Flags().StringSliceVar(&flags.fallbacks, "fallback-model", nil, "Models to try in order when the model fails (comma-separated)")
 	cmd.Flags().IntVar(&flags.maxTurns, "max-turns", sdk.DefaultMaxTurns, "Max tool loop turns (0 uses default)")
 	cmd.Flags().BoolVar(&flags.noTurnLimit, "no-turn-limit", false, "Disable turn limit")
 	cmd.Flags().StringVar(&flags.customerID, "customer", "", "Customer ID (allows omitting model)")
@@ -88,17 +106,26 @@ func bindAgentLoopFlags(cmd *cobra.Command, flags *agentLoopFlags) {
 	cmd.Flags().StringSliceVar(&flags.bashAllow, "bash-allow", nil, "Allow bash command prefix (repeatable)")
 	cmd.Flags().StringSliceVar(&flags.bashDeny, "bash-deny", nil, "Deny bash command prefix (repeatable)")
 	cmd.Flags().BoolVar(&flags.bashAllowAll, "bash-allow-all", false, "Allow all bash commands (use with care)")
+	cmd.Flags().BoolVar(&flags.bashApprove, "approve", false, "Ask before running bash commands no allow rule covers")
 	cmd.Flags().DurationVar(&flags.bashTimeout, "bash-timeout", 10*time.Second, "Bash tool timeout")
 	cmd.Flags().Uint64Var(&flags.bashMaxOutBytes, "bash-max-output-bytes", 32_000, "Bash tool max output bytes")
+	addSandboxFlags(cmd, &flags.sandbox)
+	cmd.Flags().BoolVar(&flags.noCheckpoint, "no-checkpoint", false, "Don't snapshot the tool root before each turn (disables mrl agent undo)")
 	cmd.Flags().StringVar(&flags.stateID, "state-id", "", "State handle UUID for stateful tools")
 	cmd.Flags().Int64Var(&flags.stateTTLSeconds, "state-ttl-sec", 0, "Create state handle with TTL seconds")
 	cmd.Flags().StringVar(&flags.outputPath, "output", "", "Write JSON output to file")
 	cmd.Flags().BoolVar(&flags.trace, "trace", false, "Print per-turn tool activity")
 	cmd.Flags().StringVar(&flags.tasksOutputPath, "tasks-output", "", "Write tasks list to file (JSON)")
 	cmd.Flags().BoolVar(&flags.printTasks, "print-tasks", false, "Print tasks at end")
+	cmd.Flags().StringVar(&flags.schemaFile, "schema", "", "JSON Schema file the final output must match")
+	cmd.Flags().IntVar(&flags.schemaRetries, "schema-retries", 0, "Re-prompt up to N times when the final output fails schema validation")
+	cmd.Flags().Float64Var(&flags.maxCost, "max-cost", 0, "Stop before the estimated cost exceeds this many cents")
 }
 
-func runAgentLoop(cmd *cobra.Command, args []string, flags *agentLoopFlags) error {
+// runAgentLoop runs the tool loop with the loop flags parsed into flagset.
+// With resume set it continues that run's transcript instead of reading new
+// input.
+func runAgentLoop(cmd *cobra.Command, flagset *pflag.FlagSet, args []string, flags *agentLoopFlags, resume *loopTranscript) (err error) {
 	cfg, err := runtimeConfigFrom(cmd)
 	if err != nil {
 		return err
@@ -109,7 +136,7 @@ func runAgentLoop(cmd *cobra.Command, args []string, flags *agentLoopFlags) erro
 		if loadErr != nil {
 			return loadErr
 		}
-		if applyErr := applyToolManifest(flags, loaded, cmd.Flags()); applyErr != nil {
+		if applyErr := applyToolManifest(flags, loaded, flagset); applyErr != nil {
 			return applyErr
 		}
 		manifest = &loaded
@@ -117,33 +144,103 @@ func runAgentLoop(cmd *cobra.Command, args []string, flags *agentLoopFlags) erro
 	if strings.TrimSpace(flags.model) == "" && strings.TrimSpace(flags.customerID) == "" {
 		return errors.New("model is required unless --customer is set")
 	}
+	// A customer's tier picks the model, so there is nothing to fall back to.
+	chain := []string{""}
+	if strings.TrimSpace(flags.customerID) != "" {
+		if len(flags.fallbacks) > 0 {
+			return errors.New("--fallback-model cannot be combined with --customer")
+		}
+	} else {
+		chain = modelChain(flags.model, flags.fallbacks, cfg)
+	}
 
 	client, err := newAgentClient(cfg)
 	if err != nil {
 		return err
 	}
 
-	input, err := resolveInput(flags.inputText, flags.inputFile, args)
+	var input []llm.InputItem
+	if resume != nil {
+		input = resume.Messages
+	} else {
+		if flags.editor {
+			if strings.TrimSpace(flags.inputFile) != "" {
+				return errors.New("--editor cannot be combined with --input-file")
+			}
+			seed := firstNonEmpty(strings.TrimSpace(flags.inputText), strings.Join(args, " "))
+			if flags.inputText, err = composeInEditor(cmd.Context(), seed); err != nil {
+				return err
+			}
+		}
+		if input, err = resolveInput(flags.inputText, flags.inputFile, args); err != nil {
+			return err
+		}
+		if sys := strings.TrimSpace(flags.systemPrompt); sys != "" {
+			input = append([]llm.InputItem{llm.NewSystemText(sys)}, input...)
+		}
+	}
+
+	var box *toolSandbox
+	if flags.sandbox.enabled {
+		if box, err = newToolSandbox(cmd.Context(), flags.toolRoot, flags.sandbox); err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := box.close(os.Stderr); closeErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: sandbox cleanup: %v\n", closeErr)
+			}
+		}()
+	}
+	tools, registry, taskState, err := buildAgentLoopTools(cfg, flags, manifest, box)
 	if err != nil {
 		return err
 	}
-	if sys := strings.TrimSpace(flags.systemPrompt); sys != "" {
-		input = append([]llm.InputItem{llm.NewSystemText(sys)}, input...)
+
+	structured, err := loadStructuredOutput(flags.schemaFile, flags.schemaRetries)
+	if err != nil {
+		return err
 	}
 
-	tools, registry, taskState, err := buildAgentLoopTools(flags, manifest)
+	costs, err := newCostTracker(cfg, flags.maxCost)
 	if err != nil {
 		return err
 	}
 
-	ctx, cancel := contextWithTimeout(cfg.Timeout)
+	ctx, cancel := contextWithTimeout(fallbackTimeout(cfg.Timeout, chain))
 	defer cancel()
+	// An interrupt cancels the model call in flight and ends the run. The
+	// transcript keeps every completed turn, so the run stays resumable.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	stateID, stateCreated, err := resolveLoopStateID(ctx, client, flags)
 	if err != nil {
 		return err
 	}
 
+	transcript := resume
+	if transcript == nil {
+		if transcript, err = newLoopTranscript(flagset, input); err != nil {
+			return err
+		}
+		if stateID != nil {
+			transcript.StateID = stateID.String()
+		}
+		run, runErr := beginAgentRun("agent loop", flags.toolRoot)
+		if runErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: this run can't be resumed or undone: %v\n", runErr)
+		} else {
+			transcript.attach(run)
+		}
+	} else {
+		taskState.restore(transcript.Tasks)
+	}
+	defer func() { transcript.finish(err) }()
+	var checkpoints *runCheckpointer
+	if transcript.run != nil && !flags.noCheckpoint && box == nil {
+		checkpoints = checkpointerFor(transcript.run)
+	}
+
 	maxTurns := flags.maxTurns
 	if flags.noTurnLimit {
 		maxTurns = sdk.NoTurnLimit
@@ -156,35 +253,64 @@ func runAgentLoop(cmd *cobra.Command, args []string, flags *agentLoopFlags) erro
 	}
 
 	var (
-		usage    sdk.AgentUsage
-		steps    []agentLoopStep
-		lastResp *sdk.Response
-		messages = input
-		toolDefs = tools
+		// usage covers this invocation; resumed is what earlier ones used.
+		usage         sdk.AgentUsage
+		resumed       = transcript.Usage
+		steps         = transcript.Steps
+		lastResp      *sdk.Response
+		servedModel   string
+		messages      = input
+		toolDefs      = tools
+		schemaRetries int
 	)
+	start := time.Now()
+	defer func() {
+		model := firstNonEmpty(servedModel, flags.model, "customer:"+flags.customerID)
+		recordUsage(ledgerCommandName(cmd), cfg, model, usage.LLMCalls, agentUsageTotals(usage), time.Since(start), costs.jsonCents())
+	}()
+
+	startTurn := transcript.Turns
+	for i := 0; i < maxTurns; i++ {
+		turn := startTurn + i
+		if i == 0 && strings.TrimSpace(flags.model) != "" {
+			if err := costs.checkInput(ctx, flags.model, estimateInputTokens(messages)); err != nil {
+				return err
+			}
+		} else if i > 0 {
+			if err := costs.checkNext(); err != nil {
+				return err
+			}
+		}
 
-	for turn := 0; turn < maxTurns; turn++ {
-		builder := client.Responses.New().
-			Input(messages).
-			Tools(toolDefs)
+		resp, served, err := callWithFallback(ctx, chain, cfg.Timeout, func(ctx context.Context, model string) (*sdk.Response, error) {
+			builder := client.Responses.New().
+				Input(messages).
+				Tools(toolDefs)
 
-		if strings.TrimSpace(flags.customerID) != "" {
-			builder = builder.CustomerID(flags.customerID)
-		} else {
-			builder = builder.Model(sdk.NewModelID(flags.model))
-		}
-		if stateID != nil {
-			builder = builder.StateID(*stateID)
-		}
+			if model == "" {
+				builder = builder.CustomerID(flags.customerID)
+			} else {
+				builder = builder.Model(sdk.NewModelID(model))
+			}
+			if stateID != nil {
+				builder = builder.StateID(*stateID)
+			}
+			if structured != nil {
+				builder = builder.OutputFormat(structured.format())
+			}
 
-		req, callOpts, err := builder.Build()
-		if err != nil {
-			return err
-		}
-		resp, err := client.Responses.Create(ctx, req, callOpts...)
+			req, callOpts, buildErr := builder.Build()
+			if buildErr != nil {
+				return nil, noFallback(buildErr)
+			}
+			return client.Responses.Create(ctx, req, callOpts...)
+		})
 		if err != nil {
 			return err
 		}
+		// Once a fallback has served a turn it serves the rest of the run.
+		chain = chain[served:]
+		servedModel = firstNonEmpty(resp.Model.String(), chain[0])
 
 		lastResp = resp
 		usage.LLMCalls++
@@ -194,10 +320,26 @@ func runAgentLoop(cmd *cobra.Command, args []string, flags *agentLoopFlags) erro
 		usage.ReasoningTokens += resp.Usage.ReasoningTokens
 		usage.CacheReadInputTokens += resp.Usage.CacheReadInputTokens
 		usage.CacheWriteInputTokens += resp.Usage.CacheWriteInputTokens
+		costs.record(ctx, servedModel, resp.Usage)
 
 		toolCalls := resp.ToolCalls()
 		if len(toolCalls) == 0 {
-			return handleAgentLoopOutput(cfg, resp, usage, steps, taskState, stateID, stateCreated, flags)
+			answer := llm.NewAssistantText(resp.AssistantText())
+			var validated json.RawMessage
+			if structured != nil {
+				var validationErr error
+				if validated, validationErr = structured.validate(resp.AssistantText()); validationErr != nil {
+					if schemaRetries >= structured.retries {
+						return validationErr
+					}
+					schemaRetries++
+					messages = append(messages, answer, structured.retryMessage(validationErr))
+					transcript.record(turn+1, messages, addAgentUsage(resumed, usage), steps, servedModel, taskState.Snapshot())
+					continue
+				}
+			}
+			transcript.record(turn+1, append(messages, answer), addAgentUsage(resumed, usage), steps, servedModel, taskState.Snapshot())
+			return handleAgentLoopOutput(cfg, resp, servedModel, validated, addAgentUsage(resumed, usage), costs, steps, taskState, stateID, stateCreated, transcript.RunID, flags)
 		}
 
 		usage.ToolCalls += len(toolCalls)
@@ -213,6 +355,7 @@ func runAgentLoop(cmd *cobra.Command, args []string, flags *agentLoopFlags) erro
 		}
 
 		messages = append(messages, sdk.AssistantMessageWithToolCalls(resp.AssistantText(), toolCalls))
+		checkpoints.beforeTools(turn)
 		results := registry.ExecuteAll(toolCalls)
 		for _, res := range results {
 			if res.Error != nil {
@@ -224,33 +367,45 @@ func runAgentLoop(cmd *cobra.Command, args []string, flags *agentLoopFlags) erro
 		if cfg.Output == outputFormatTable && flags.trace {
 			printAgentLoopTrace(step, results)
 		}
-		if cfg.Output == outputFormatJSON || flags.trace {
-			steps = append(steps, step)
-		}
+		steps = append(steps, step)
+		transcript.record(turn+1, messages, addAgentUsage(resumed, usage), steps, servedModel, taskState.Snapshot())
 	}
 
 	return sdk.AgentMaxTurnsError{
 		MaxTurns:     maxTurns,
 		LastResponse: lastResp,
-		Usage:        usage,
+		Usage:        addAgentUsage(resumed, usage),
 	}
 }
 
 func handleAgentLoopOutput(
 	cfg runtimeConfig,
 	resp *sdk.Response,
+	model string,
+	structured json.RawMessage,
 	usage sdk.AgentUsage,
+	costs *costTracker,
 	steps []agentLoopStep,
 	taskState *tasksState,
 	stateID *uuid.UUID,
 	stateCreated bool,
+	runID string,
 	flags *agentLoopFlags,
 ) error {
 	result := agentLoopResult{
-		Output:   resp.AssistantText(),
-		Usage:    usage,
-		Steps:    steps,
-		Response: resp,
+		Output:     resp.AssistantText(),
+		Structured: structured,
+		Model:      model,
+		Usage:      usage,
+		CostCents:  costs.jsonCents(),
+		RunID:      runID,
+		Response:   resp,
+	}
+	if cfg.Output == outputFormatJSON || flags.trace {
+		result.Steps = steps
+	}
+	if len(structured) > 0 {
+		result.Output = string(structured)
 	}
 	if stateID != nil {
 		result.StateID = stateID.String()
@@ -280,11 +435,14 @@ func handleAgentLoopOutput(
 	if outputText := strings.TrimSpace(result.Output); outputText != "" {
 		fmt.Println("Output:\n" + outputText)
 	}
-	fmt.Printf("Usage: %d LLM calls | %d tool calls | %d tokens\n",
+	fmt.Printf("Usage: %d LLM calls | %d tool calls | %d tokens | Cost: %s | Model: %s\n",
 		usage.LLMCalls,
 		usage.ToolCalls,
 		usage.TotalTokens,
+		costs.summary(),
+		model,
 	)
+	costs.warnIfOverBudget()
 
 	if flags.printTasks && taskState != nil {
 		printTasks(taskState.Snapshot())
@@ -366,14 +524,16 @@ func splitCSVValues(values []string) []string {
 	return out
 }
 
-func buildAgentLoopTools(flags *agentLoopFlags, manifest *toolManifest) ([]llm.Tool, *sdk.ToolRegistry, *tasksState, error) {
+// buildAgentLoopTools registers the selected tools. With a sandbox, bash and
+// custom tools run inside it.
+func buildAgentLoopTools(cfg runtimeConfig, flags *agentLoopFlags, manifest *toolManifest, box *toolSandbox) ([]llm.Tool, *sdk.ToolRegistry, *tasksState, error) {
 	allowEmpty := manifest != nil && len(manifest.Custom) > 0
 	selection, err := parseLoopTools(flags.tools, allowEmpty)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 	if !selection.enableBash {
-		if flags.bashAllowAll || len(flags.bashAllow) > 0 || len(flags.bashDeny) > 0 {
+		if flags.bashAllowAll || flags.bashApprove || len(flags.bashAllow) > 0 || len(flags.bashDeny) > 0 {
 			return nil, nil, nil, errors.New("bash flags set but bash tool not enabled (add --tool bash)")
 		}
 	}
@@ -385,6 +545,10 @@ func buildAgentLoopTools(flags *agentLoopFlags, manifest *toolManifest) ([]llm.T
 	if !selection.enableFS && manifest != nil && manifest.FS != nil {
 		return nil, nil, nil, errors.New("fs tool config provided but fs tool not enabled (add --tool fs)")
 	}
+	if selection.enableFS && box != nil {
+		// The fs tools write the host tool root directly, past the overlay.
+		return nil, nil, nil, errors.New("--sandbox cannot be combined with the fs tool (use bash inside the sandbox instead)")
+	}
 
 	registry := sdk.NewToolRegistry()
 	var defs []llm.Tool
@@ -400,25 +564,45 @@ func buildAgentLoopTools(flags *agentLoopFlags, manifest *toolManifest) ([]llm.T
 		if parseErr != nil {
 			return nil, nil, nil, parseErr
 		}
-		if !flags.bashAllowAll && len(allowRules) == 0 {
-			return nil, nil, nil, errors.New("bash tool requires --bash-allow or --bash-allow-all")
+		approve := (flags.bashApprove || cfg.Approve == approveAsk) && !flags.bashAllowAll
+		if !flags.bashAllowAll && len(allowRules) == 0 && !approve {
+			return nil, nil, nil, errors.New("bash tool requires --bash-allow, --bash-allow-all or --approve")
 		}
 
 		opts := []sdk.LocalBashOption{
 			sdk.WithLocalBashTimeout(flags.bashTimeout),
 			sdk.WithLocalBashMaxOutputBytes(flags.bashMaxOutBytes),
 		}
-		if flags.bashAllowAll {
-			opts = append(opts, sdk.WithLocalBashAllowAllCommands())
+		jailed := func(allowAll bool, allow []sdk.BashCommandRule) *sandboxBash {
+			return &sandboxBash{box: box, allowAll: allowAll, allow: allow, deny: denyRules, timeout: flags.bashTimeout, maxOutput: int(min(flags.bashMaxOutBytes, math.MaxInt32))}
 		}
-		if len(allowRules) > 0 {
-			opts = append(opts, sdk.WithLocalBashAllowRules(allowRules...))
-		}
-		if len(denyRules) > 0 {
-			opts = append(opts, sdk.WithLocalBashDenyRules(denyRules...))
+		if approve {
+			// Prefixes saved from earlier "always" answers count as rules.
+			for _, prefix := range cfg.Allow {
+				allowRules = append(allowRules, sdk.BashCommandPrefix(prefix))
+			}
+			runner := approveRunner(flags.toolRoot, opts, denyRules)
+			if box != nil {
+				runner = jailed(true, nil).registry()
+			}
+			approver := newBashApprover(allowRules, denyRules, runner, func(prefix string) error {
+				return rememberAllowPrefix(cfg.Profile, prefix)
+			})
+			registry.Register(sdk.ToolNameBash, approver.handle)
+		} else if box != nil {
+			registry.Register(sdk.ToolNameBash, jailed(flags.bashAllowAll, allowRules).handle)
+		} else {
+			if flags.bashAllowAll {
+				opts = append(opts, sdk.WithLocalBashAllowAllCommands())
+			}
+			if len(allowRules) > 0 {
+				opts = append(opts, sdk.WithLocalBashAllowRules(allowRules...))
+			}
+			if len(denyRules) > 0 {
+				opts = append(opts, sdk.WithLocalBashDenyRules(denyRules...))
+			}
+			sdk.NewLocalBashToolPack(flags.toolRoot, opts...).RegisterInto(registry)
 		}
-
-		sdk.NewLocalBashToolPack(flags.toolRoot, opts...).RegisterInto(registry)
 		defs, err = appendToolDefs(defs, seen, bashToolDefinition())
 		if err != nil {
 			return nil, nil, nil, err
@@ -446,7 +630,7 @@ func buildAgentLoopTools(flags *agentLoopFlags, manifest *toolManifest) ([]llm.T
 		}
 	}
 
-	customDefs, err := registerCustomTools(registry, flags.toolRoot, manifest, seen)
+	customDefs, err := registerCustomTools(registry, flags.toolRoot, manifest, seen, box)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -573,6 +757,16 @@ func (s *tasksState) Snapshot() []runTask {
 	return append([]runTask(nil), s.tasks...)
 }
 
+// restore puts back the tasks of a resumed run without writing them out.
+func (s *tasksState) restore(tasks []runTask) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.tasks = append([]runTask(nil), tasks...)
+	s.mu.Unlock()
+}
+
 func (s *tasksState) handleToolCall(args map[string]any, _ llm.ToolCall) (any, error) {
 	if s == nil {
 		return nil, errors.New("tasks state unavailable")
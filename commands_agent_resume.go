@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/spf13/cobra"
+)
+
+func newAgentResumeCmd() *cobra.Command {
+	var maxTurns int
+	cmd := &cobra.Command{
+		Use:   "resume <run-id>",
+		Short: "Continue an agent loop run from its last turn",
+		Long: `Continue an mrl agent loop run that stopped at --max-turns, timed out, failed
+or was interrupted. The loop picks up after the last completed turn with the
+saved messages, usage and tasks.
+
+The run restarts in the directory it was started from, with the same flags.
+A --tools-file manifest is read again, so its tools and settings apply as
+they do to a new run. --max-turns limits the turns of this invocation and
+defaults to the run's own limit.
+
+Run IDs are printed when a run stops and included in --output json as
+run_id. The 20 most recent runs are kept.
+
+Examples:
+  mrl agent resume 20260301T120000Z-1a2b3c4d
+  mrl agent resume 20260301T120000Z-1a2b3c4d --max-turns 100`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run, err := loadAgentRun(strings.TrimSpace(args[0]))
+			if err != nil {
+				return err
+			}
+			transcript, err := loadLoopTranscript(run)
+			if err != nil {
+				return err
+			}
+			if transcript.Status == transcriptCompleted {
+				return fmt.Errorf("run %s already completed", run.ID)
+			}
+			flagset, flags, err := transcript.loopFlags()
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("max-turns") {
+				flags.maxTurns = maxTurns
+				flags.noTurnLimit = false
+			}
+			if err := os.Chdir(transcript.WorkDir); err != nil {
+				return fmt.Errorf("run %s started in %s: %w", run.ID, transcript.WorkDir, err)
+			}
+			return runAgentLoop(cmd, flagset, nil, flags, transcript)
+		},
+	}
+	cmd.Flags().IntVar(&maxTurns, "max-turns", sdk.DefaultMaxTurns, "Max further tool loop turns (default: the run's own limit)")
+	return cmd
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newAgentUndoCmd() *cobra.Command {
+	var (
+		toTurn   int
+		runID    string
+		toolRoot string
+		list     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Restore files changed by an agent loop or do run",
+		Long: `Restore the tool root from the checkpoints taken before each turn that ran
+tools in mrl agent loop or mrl do.
+
+By default the most recent run over --tool-root is undone completely. With
+--to-turn N the files are put back as they were before the tools of turn N
+ran. Files ignored by .gitignore, .git and node_modules are not checkpointed
+and are left alone.
+
+The current state is saved as a new run first, so running undo again goes
+back to it.
+
+Examples:
+  mrl agent undo
+  mrl agent undo --list
+  mrl agent undo --to-turn 3
+  mrl agent undo --run 20260301T120000Z-1a2b3c4d --tool-root ./service`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := runtimeConfigFrom(cmd)
+			if err != nil {
+				return err
+			}
+			var run *agentRun
+			if strings.TrimSpace(runID) != "" {
+				run, err = loadAgentRun(strings.TrimSpace(runID))
+			} else {
+				run, err = latestAgentRun(toolRoot)
+			}
+			if err != nil {
+				return err
+			}
+			checkpoints, err := run.checkpoints()
+			if err != nil {
+				return err
+			}
+			if len(checkpoints) == 0 {
+				return fmt.Errorf("run %s has no checkpoints", run.ID)
+			}
+			if list {
+				return printRunCheckpoints(cfg, run, checkpoints)
+			}
+
+			target := checkpoints[0]
+			if cmd.Flags().Changed("to-turn") {
+				found := false
+				for _, checkpoint := range checkpoints {
+					if checkpoint.Turn == toTurn {
+						target, found = checkpoint, true
+						break
+					}
+				}
+				if !found {
+					return fmt.Errorf("run %s has no checkpoint for turn %d (turns: %s)", run.ID, toTurn, checkpointTurns(checkpoints))
+				}
+			}
+			return undoRun(cfg, run, target)
+		},
+	}
+	cmd.Flags().IntVar(&toTurn, "to-turn", 0, "Restore the files as they were before this turn's tools ran (default: before the run)")
+	cmd.Flags().StringVar(&runID, "run", "", "Run ID to undo (default: the latest run over --tool-root)")
+	cmd.Flags().StringVar(&toolRoot, "tool-root", ".", "Tool root whose latest run to undo")
+	cmd.Flags().BoolVar(&list, "list", false, "List the run's checkpoints instead of restoring")
+	return cmd
+}
+
+// undoRun saves the current state as a new run, then restores target.
+func undoRun(cfg runtimeConfig, run *agentRun, target runCheckpoint) error {
+	if info, err := os.Stat(run.Root); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("tool root %s is not a directory", run.Root)
+	}
+	backup, err := startAgentRun("agent undo", run.Root, time.Now())
+	if err != nil {
+		return fmt.Errorf("save current state: %w", err)
+	}
+	if err := (&runCheckpointer{run: backup}).snapshot(0, time.Now()); err != nil {
+		return fmt.Errorf("save current state: %w", err)
+	}
+
+	summary, err := run.restore(target)
+	if err != nil {
+		return errors.Join(err, fmt.Errorf("the state before undo is saved as run %s", backup.ID))
+	}
+	if cfg.Output == outputFormatJSON {
+		printJSON(map[string]any{
+			"run_id":    run.ID,
+			"root":      run.Root,
+			"turn":      target.Turn,
+			"written":   summary.Written,
+			"deleted":   summary.Deleted,
+			"backup_id": backup.ID,
+		})
+		return nil
+	}
+	fmt.Printf("Restored %s to before turn %d of run %s: %d files written, %d deleted.\n",
+		run.Root, target.Turn, run.ID, len(summary.Written), len(summary.Deleted))
+	fmt.Printf("Run mrl agent undo again to go back (run %s).\n", backup.ID)
+	return nil
+}
+
+func printRunCheckpoints(cfg runtimeConfig, run *agentRun, checkpoints []runCheckpoint) error {
+	if cfg.Output == outputFormatJSON {
+		summaries := make([]map[string]any, 0, len(checkpoints))
+		for _, checkpoint := range checkpoints {
+			summaries = append(summaries, map[string]any{
+				"turn":       checkpoint.Turn,
+				"files":      len(checkpoint.Files),
+				"created_at": checkpoint.CreatedAt,
+			})
+		}
+		printJSON(map[string]any{"run_id": run.ID, "command": run.Command, "root": run.Root, "started_at": run.StartedAt, "checkpoints": summaries})
+		return nil
+	}
+	fmt.Printf("Run %s (%s) over %s, started %s\n\n", run.ID, run.Command, run.Root, run.StartedAt.Format(time.RFC3339))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TURN\tFILES\tCREATED_AT")
+	for _, checkpoint := range checkpoints {
+		_, _ = fmt.Fprintf(w, "%d\t%d\t%s\n", checkpoint.Turn, len(checkpoint.Files), checkpoint.CreatedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+func checkpointTurns(checkpoints []runCheckpoint) string {
+	turns := make([]string, len(checkpoints))
+	for i, checkpoint := range checkpoints {
+		turns[i] = strconv.Itoa(checkpoint.Turn)
+	}
+	return strings.Join(turns, ", ")
+}
@@ -0,0 +1,558 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// transcribeCapability and speechCapability select models from the
+	// catalog when --model is not set, as in `mrl model list --capability`.
+	transcribeCapability = "speech_to_text"
+	speechCapability     = "text_to_speech"
+	// maxAudioUploadBytes is the largest recording sent in one request;
+	// longer files are split into --chunk-seconds pieces.
+	maxAudioUploadBytes = 25 << 20
+	// maxAudioSourceBytes bounds the recordings mrl reads at all.
+	maxAudioSourceBytes = 1 << 30
+	defaultChunkSeconds = 600
+	// maxSpeechChars is the longest text sent to mrl speak.
+	maxSpeechChars = 4096
+	// audioTimeout is the minimum per-request timeout for audio calls unless
+	// --timeout is given.
+	audioTimeout = 5 * time.Minute
+)
+
+// audioMimeTypes covers audio extensions that detectMimeType may only
+// recognize from a system MIME table.
+var audioMimeTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".mp4":  "audio/mp4",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/opus",
+	".flac": "audio/flac",
+	".aac":  "audio/aac",
+	".webm": "audio/webm",
+	".pcm":  "audio/pcm",
+}
+
+// audioMimeAliases maps the legacy names some MIME tables use to the
+// canonical types.
+var audioMimeAliases = map[string]string{
+	"audio/x-wav":    "audio/wav",
+	"audio/wave":     "audio/wav",
+	"audio/vnd.wave": "audio/wav",
+	"audio/x-flac":   "audio/flac",
+	"audio/x-m4a":    "audio/mp4",
+	"audio/mp3":      "audio/mpeg",
+}
+
+type audioPayload struct {
+	DataBase64 string `json:"data_base64"`
+	MimeType   string `json:"mime_type"`
+}
+
+type audioUsage struct {
+	InputTokens  int64 `json:"input_tokens,omitempty"`
+	OutputTokens int64 `json:"output_tokens,omitempty"`
+	TotalTokens  int64 `json:"total_tokens,omitempty"`
+}
+
+type transcriptSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// transcriptSchema is the answer asked of a transcription model. Segment
+// times are seconds from the start of the chunk it was given.
+const transcriptSchema = `{
+  "type": "object",
+  "properties": {
+    "text": {"type": "string"},
+    "language": {"type": "string"},
+    "duration": {"type": "number"},
+    "segments": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "start": {"type": "number"},
+          "end": {"type": "number"},
+          "text": {"type": "string"}
+        },
+        "required": ["start", "end", "text"],
+        "additionalProperties": false
+      }
+    }
+  },
+  "required": ["text", "language", "duration", "segments"],
+  "additionalProperties": false
+}`
+
+// transcriptSchemaRetries is how often a transcript that doesn't match
+// transcriptSchema is asked for again.
+const transcriptSchemaRetries = 1
+
+type transcriptionResponse struct {
+	Model    string              `json:"model,omitempty"`
+	Text     string              `json:"text"`
+	Language string              `json:"language,omitempty"`
+	Duration float64             `json:"duration,omitempty"`
+	Segments []transcriptSegment `json:"segments,omitempty"`
+	Usage    audioUsage          `json:"usage,omitempty"`
+}
+
+type speechRequest struct {
+	Model  string  `json:"model"`
+	Input  string  `json:"input"`
+	Voice  string  `json:"voice,omitempty"`
+	Format string  `json:"format"`
+	Speed  float64 `json:"speed,omitempty"`
+}
+
+type speechResponse struct {
+	Model string       `json:"model,omitempty"`
+	Audio audioPayload `json:"audio"`
+	Usage audioUsage   `json:"usage,omitempty"`
+}
+
+func (u audioUsage) sdkUsage() sdk.Usage {
+	return sdk.Usage{InputTokens: u.InputTokens, OutputTokens: u.OutputTokens, TotalTokens: u.TotalTokens}
+}
+
+func newTranscribeCmd() *cobra.Command {
+	var (
+		model        string
+		language     string
+		prompt       string
+		output       string
+		chunkSeconds int
+		timestamps   bool
+		force        bool
+	)
+	cmd := &cobra.Command{
+		Use:   "transcribe <audio-file>",
+		Short: "Transcribe a recording to text",
+		Long: `Transcribe a recording with a speech-to-text model.
+
+Recordings over 25 MB, or any recording when --chunk-seconds is set, are split
+into chunks and transcribed in order; segment timestamps are shifted to the
+position in the original file. WAV files are split directly; other formats
+need ffmpeg on PATH.
+
+--json prints the text with timestamped segments.
+
+Examples:
+  mrl transcribe call.wav
+  mrl transcribe call.mp3 --language en --timestamps
+  mrl transcribe long-call.m4a --chunk-seconds 300 --json -o call.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := runtimeConfigFrom(cmd)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(cfg.APIKey) == "" {
+				return errors.New("api key required")
+			}
+			if chunkSeconds <= 0 {
+				return errors.New("--chunk-seconds must be positive")
+			}
+			output = strings.TrimSpace(output)
+			if output != "" && output != "-" && !force {
+				if _, err := os.Stat(output); err == nil {
+					return fmt.Errorf("%s already exists (use --force to overwrite)", output)
+				}
+			}
+			timeout := cfg.Timeout
+			if !cmd.Flags().Changed("timeout") {
+				timeout = max(timeout, audioTimeout)
+			}
+
+			path := args[0]
+			chunks, err := loadAudioChunks(cmd.Context(), path, chunkSeconds, cmd.Flags().Changed("chunk-seconds"))
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(model) == "" {
+				ctx, cancel := contextWithTimeout(cfg.Timeout)
+				model, err = defaultCapabilityModel(ctx, cfg, transcribeCapability)
+				cancel()
+				if err != nil {
+					return err
+				}
+			}
+
+			structured, err := newStructuredOutput("transcript", "transcript", json.RawMessage(transcriptSchema), transcriptSchemaRetries)
+			if err != nil {
+				return err
+			}
+			clientCfg := cfg
+			clientCfg.Timeout = timeout
+			client, err := newPromptClient(clientCfg)
+			if err != nil {
+				return err
+			}
+			instruction := transcriptionInstruction(language, prompt)
+
+			start := time.Now()
+			var calls int
+			var usage audioUsage
+			transcript, err := transcribeChunks(chunks, func(chunk audioChunk) (transcriptionResponse, error) {
+				ctx, cancel := contextWithTimeout(timeout)
+				defer cancel()
+				resp, err := transcribeChunk(ctx, client, model, structured, instruction, chunk)
+				if err == nil {
+					calls++
+					usage.InputTokens += resp.Usage.InputTokens
+					usage.OutputTokens += resp.Usage.OutputTokens
+					usage.TotalTokens += resp.Usage.TotalTokens
+				}
+				return resp, err
+			})
+			if calls > 0 {
+				recordUsage(ledgerCommandName(cmd), cfg, firstNonEmpty(transcript.Model, model), calls, usage.sdkUsage(), time.Since(start), nil)
+			}
+			if err != nil {
+				return err
+			}
+			transcript.Model = firstNonEmpty(transcript.Model, model)
+			transcript.Usage = usage
+
+			out := io.Writer(os.Stdout)
+			if output != "" && output != "-" {
+				file, err := os.Create(output) //nolint:gosec // output path is chosen by the CLI user
+				if err != nil {
+					return err
+				}
+				defer func() { _ = file.Close() }()
+				out = file
+			}
+			if cfg.Output == outputFormatJSON {
+				data, err := json.MarshalIndent(transcript, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(out, string(data))
+				return err
+			}
+			_, err = io.WriteString(out, formatTranscript(transcript, timestamps))
+			if len(chunks) > 1 {
+				fmt.Fprintf(os.Stderr, "Model: %s | Chunks: %d | Duration: %s\n", transcript.Model, len(chunks), formatTimestamp(transcript.Duration))
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&model, "model", "", "Speech-to-text model (default: first speech_to_text model in the catalog)")
+	cmd.Flags().StringVar(&language, "language", "", "Spoken language hint, e.g. en or de")
+	cmd.Flags().StringVar(&prompt, "prompt", "", "Context hint for names and terms in the recording")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the transcript to a file")
+	cmd.Flags().IntVar(&chunkSeconds, "chunk-seconds", defaultChunkSeconds, "Split recordings into chunks of this many seconds")
+	cmd.Flags().BoolVar(&timestamps, "timestamps", false, "Prefix each segment with its start time")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing output file")
+	return cmd
+}
+
+func newSpeakCmd() *cobra.Command {
+	var (
+		model  string
+		voice  string
+		output string
+		format string
+		speed  float64
+		force  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "speak [text]",
+		Short: "Synthesize speech from text",
+		Long: `Synthesize speech with a text-to-speech model and save it to a file.
+
+The text comes from the arguments or stdin. The audio format follows the -o
+extension (mp3, wav, opus, flac, aac or pcm) unless --format is set; -o -
+writes the audio to stdout.
+
+Examples:
+  mrl speak "Your order has shipped." -o shipped.mp3
+  cat notice.txt | mrl speak --voice alloy -o notice.wav
+  mrl speak "Hello" -o - | ffplay -nodisp -autoexit -`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := runtimeConfigFrom(cmd)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(cfg.APIKey) == "" {
+				return errors.New("api key required")
+			}
+			text := strings.TrimSpace(strings.Join(args, " "))
+			if text == "" {
+				stdinIsTTY, err := isTerminal(os.Stdin)
+				if err != nil {
+					return err
+				}
+				if !stdinIsTTY {
+					data, err := io.ReadAll(io.LimitReader(os.Stdin, maxSpeechChars*4+1))
+					if err != nil {
+						return err
+					}
+					text = strings.TrimSpace(string(data))
+				}
+			}
+			if text == "" {
+				return errors.New("text is required (argument or stdin)")
+			}
+			if n := len([]rune(text)); n > maxSpeechChars {
+				return fmt.Errorf("text is %d characters; mrl speak accepts up to %d", n, maxSpeechChars)
+			}
+			format, output, err := speechOutput(format, output, time.Now())
+			if err != nil {
+				return err
+			}
+			if output != "-" && !force {
+				if _, err := os.Stat(output); err == nil {
+					return fmt.Errorf("%s already exists (use --force to overwrite)", output)
+				}
+			}
+
+			timeout := cfg.Timeout
+			if !cmd.Flags().Changed("timeout") {
+				timeout = max(timeout, audioTimeout)
+			}
+			ctx, cancel := contextWithTimeout(timeout)
+			defer cancel()
+			if strings.TrimSpace(model) == "" {
+				if model, err = defaultCapabilityModel(ctx, cfg, speechCapability); err != nil {
+					return err
+				}
+			}
+
+			start := time.Now()
+			var resp speechResponse
+			err = postModelRoute(ctx, cfg, "/audio/speech", speechRequest{
+				Model:  model,
+				Input:  text,
+				Voice:  strings.TrimSpace(voice),
+				Format: format,
+				Speed:  speed,
+			}, &resp)
+			if err != nil {
+				return err
+			}
+			servedModel := firstNonEmpty(resp.Model, model)
+			recordUsage(ledgerCommandName(cmd), cfg, servedModel, 1, resp.Usage.sdkUsage(), time.Since(start), nil)
+			audio, err := base64.StdEncoding.DecodeString(resp.Audio.DataBase64)
+			if err != nil || len(audio) == 0 {
+				return errors.New("response has no audio")
+			}
+			if output == "-" {
+				_, err = os.Stdout.Write(audio)
+				return err
+			}
+			if err := os.WriteFile(output, audio, 0o644); err != nil { //nolint:gosec // generated audio is meant to be shared
+				return err
+			}
+			mimeType := firstNonEmpty(resp.Audio.MimeType, audioMimeTypes["."+format])
+			if cfg.Output == outputFormatJSON {
+				printJSON(map[string]any{"model": servedModel, "path": output, "mime_type": mimeType, "bytes": len(audio), "usage": resp.Usage})
+				return nil
+			}
+			fmt.Printf("Wrote %s (%s, %d bytes)\n", output, mimeType, len(audio))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&model, "model", "", "Text-to-speech model (default: first text_to_speech model in the catalog)")
+	cmd.Flags().StringVar(&voice, "voice", "", "Voice name (model specific)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output file, or - for stdout (default: speech-<timestamp>.<format>)")
+	cmd.Flags().StringVar(&format, "format", "", "Audio format: mp3, wav, opus, flac, aac or pcm (default: from -o, else mp3)")
+	cmd.Flags().Float64Var(&speed, "speed", 0, "Speaking speed multiplier, e.g. 1.25 (default: model default)")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite an existing output file")
+	return cmd
+}
+
+// audioMimeType detects the type of a recording, falling back to the
+// extension for formats the system MIME table does not know.
+func audioMimeType(path string, data []byte) string {
+	mimeType := detectMimeType(path, data)
+	if base, _, _ := strings.Cut(mimeType, ";"); strings.HasPrefix(base, "audio/") || strings.HasPrefix(base, "video/") {
+		base = strings.ToLower(strings.TrimSpace(base))
+		if canonical, ok := audioMimeAliases[base]; ok {
+			return canonical
+		}
+		return base
+	}
+	if known, ok := audioMimeTypes[strings.ToLower(filepath.Ext(path))]; ok {
+		return known
+	}
+	return mimeType
+}
+
+// loadAudioChunks reads a recording and splits it when it is too large for
+// one request or when force is set.
+func loadAudioChunks(ctx context.Context, path string, seconds int, force bool) ([]audioChunk, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxAudioSourceBytes {
+		return nil, fmt.Errorf("%s is larger than %d MB", path, maxAudioSourceBytes>>20)
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // recordings are explicitly selected by the CLI user
+	if err != nil {
+		return nil, err
+	}
+	mimeType := audioMimeType(path, data)
+	if !strings.HasPrefix(mimeType, "audio/") && !strings.HasPrefix(mimeType, "video/") {
+		return nil, fmt.Errorf("%s is not an audio file (%s)", path, mimeType)
+	}
+	if len(data) <= maxAudioUploadBytes && !force {
+		return []audioChunk{{Data: data, MimeType: mimeType}}, nil
+	}
+	var chunks []audioChunk
+	if _, _, wavErr := parseWAV(data); wavErr == nil {
+		chunks, err = splitWAV(data, seconds)
+	} else {
+		chunks, err = splitAudioFFmpeg(ctx, path, mimeType, seconds)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("split %s: %w", path, err)
+	}
+	for _, chunk := range chunks {
+		if len(chunk.Data) > maxAudioUploadBytes {
+			return nil, fmt.Errorf("split %s: chunks are still over %d MB; lower --chunk-seconds", path, maxAudioUploadBytes>>20)
+		}
+	}
+	return chunks, nil
+}
+
+// transcriptionInstruction is the text sent alongside each audio chunk.
+func transcriptionInstruction(language, prompt string) string {
+	var b strings.Builder
+	b.WriteString("Transcribe the attached recording verbatim. Split it into segments of about one sentence, " +
+		"with start and end times in seconds from the start of the recording, and give the recording's duration " +
+		"in seconds and its spoken language as an ISO 639-1 code.")
+	if language = strings.TrimSpace(language); language != "" {
+		fmt.Fprintf(&b, " The recording is in %s.", language)
+	}
+	if prompt = strings.TrimSpace(prompt); prompt != "" {
+		fmt.Fprintf(&b, " Context, including names and terms that may occur: %s", prompt)
+	}
+	return b.String()
+}
+
+// transcribeChunk sends one chunk to the Responses API as an audio file part
+// and parses the structured transcript the model returns.
+func transcribeChunk(ctx context.Context, client *sdk.Client, model string, structured *structuredOutput, instruction string, chunk audioChunk) (transcriptionResponse, error) {
+	input := []llm.InputItem{{
+		Type: llm.InputItemTypeMessage,
+		Role: llm.RoleUser,
+		Content: []llm.ContentPart{
+			llm.TextPart(instruction),
+			llm.FilePartFromBytes(chunk.Data, llm.NormalizeMimeType(chunk.MimeType), ""),
+		},
+	}}
+	result, err := runStructuredCompletion(ctx, client, model, "", input, structured)
+	if err != nil {
+		return transcriptionResponse{}, err
+	}
+	var resp transcriptionResponse
+	if err := json.Unmarshal([]byte(result.Text), &resp); err != nil {
+		return transcriptionResponse{}, fmt.Errorf("parse transcript: %w", err)
+	}
+	resp.Model = result.Model
+	resp.Usage = audioUsage{InputTokens: result.Usage.InputTokens, OutputTokens: result.Usage.OutputTokens, TotalTokens: result.Usage.TotalTokens}
+	return resp, nil
+}
+
+// transcribeChunks transcribes chunks in order and merges the results,
+// shifting segment times by each chunk's offset.
+func transcribeChunks(chunks []audioChunk, transcribe func(audioChunk) (transcriptionResponse, error)) (transcriptionResponse, error) {
+	var (
+		merged transcriptionResponse
+		texts  []string
+	)
+	for i, chunk := range chunks {
+		resp, err := transcribe(chunk)
+		if err != nil {
+			if len(chunks) > 1 {
+				return merged, fmt.Errorf("chunk %d of %d (at %s): %w", i+1, len(chunks), formatTimestamp(chunk.Offset), err)
+			}
+			return merged, err
+		}
+		merged.Model = firstNonEmpty(merged.Model, resp.Model)
+		merged.Language = firstNonEmpty(merged.Language, resp.Language)
+		if text := strings.TrimSpace(resp.Text); text != "" {
+			texts = append(texts, text)
+		}
+		end := chunk.Offset + resp.Duration
+		for _, segment := range resp.Segments {
+			segment.Start += chunk.Offset
+			segment.End += chunk.Offset
+			segment.Text = strings.TrimSpace(segment.Text)
+			merged.Segments = append(merged.Segments, segment)
+			end = max(end, segment.End)
+		}
+		if resp.Duration == 0 && len(chunk.Data) > 0 {
+			if seconds, err := wavDuration(chunk.Data); err == nil {
+				end = max(end, chunk.Offset+seconds)
+			}
+		}
+		merged.Duration = max(merged.Duration, end)
+	}
+	merged.Text = strings.Join(texts, " ")
+	return merged, nil
+}
+
+// formatTranscript renders a transcript as plain text, optionally one
+// timestamped segment per line.
+func formatTranscript(transcript transcriptionResponse, timestamps bool) string {
+	if !timestamps || len(transcript.Segments) == 0 {
+		return transcript.Text + "\n"
+	}
+	var out strings.Builder
+	for _, segment := range transcript.Segments {
+		fmt.Fprintf(&out, "[%s] %s\n", formatTimestamp(segment.Start), segment.Text)
+	}
+	return out.String()
+}
+
+// formatTimestamp formats seconds as HH:MM:SS.
+func formatTimestamp(seconds float64) string {
+	total := int(seconds)
+	return fmt.Sprintf("%02d:%02d:%02d", total/3600, total/60%60, total%60)
+}
+
+// speechOutput resolves the audio format and output path of mrl speak.
+func speechOutput(format, output string, now time.Time) (string, string, error) {
+	format = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), "."))
+	output = strings.TrimSpace(output)
+	if format == "" && output != "" && output != "-" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(output), "."))
+	}
+	if format == "" {
+		format = "mp3"
+	}
+	switch format {
+	case "mp3", "wav", "opus", "flac", "aac", "pcm":
+	default:
+		return "", "", fmt.Errorf("unsupported audio format %q (want mp3, wav, opus, flac, aac or pcm)", format)
+	}
+	if output == "" {
+		output = "speech-" + now.Format("20060102-150405") + "." + format
+	}
+	return format, output, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+// testWAV builds a mono 16-bit WAV of the given length at 8 kHz.
+func testWAV(seconds float64) []byte {
+	raw := make([]byte, 16)
+	binary.LittleEndian.PutUint16(raw[0:2], 1)      // PCM
+	binary.LittleEndian.PutUint16(raw[2:4], 1)      // channels
+	binary.LittleEndian.PutUint32(raw[4:8], 8000)   // sample rate
+	binary.LittleEndian.PutUint32(raw[8:12], 16000) // byte rate
+	binary.LittleEndian.PutUint16(raw[12:14], 2)    // block align
+	binary.LittleEndian.PutUint16(raw[14:16], 16)   // bits per sample
+	samples := make([]byte, int(seconds*16000))
+	return encodeWAV(wavFormat{raw: raw, byteRate: 16000, blockAlign: 2}, samples)
+}
+
+func TestSplitWAV(t *testing.T) {
+	data := testWAV(25)
+	if seconds, err := wavDuration(data); err != nil || seconds != 25 {
+		t.Fatalf("duration = %v, %v", seconds, err)
+	}
+	chunks, err := splitWAV(data, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks", len(chunks))
+	}
+	var total float64
+	for i, chunk := range chunks {
+		if chunk.Offset != float64(i*10) || chunk.MimeType != "audio/wav" {
+			t.Fatalf("chunk %d = offset %v, %s", i, chunk.Offset, chunk.MimeType)
+		}
+		seconds, err := wavDuration(chunk.Data)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		total += seconds
+	}
+	if total != 25 {
+		t.Fatalf("chunks cover %v seconds", total)
+	}
+	if _, err := splitWAV([]byte("ID3 not a wav"), 10); err == nil {
+		t.Fatal("expected an error for non-WAV data")
+	}
+}
+
+func TestParseSegmentList(t *testing.T) {
+	entries, err := parseSegmentList([]byte("part0000.mp3,0.000000,600.012000\npart0001.mp3,600.012000,731.5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].name != "part0001.mp3" || entries[1].start != 600.012 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if _, err := parseSegmentList(nil); err == nil {
+		t.Fatal("expected an error for an empty list")
+	}
+}
+
+func TestTranscribeChunksShiftsSegments(t *testing.T) {
+	chunks := []audioChunk{{Offset: 0}, {Offset: 600}}
+	transcript, err := transcribeChunks(chunks, func(chunk audioChunk) (transcriptionResponse, error) {
+		return transcriptionResponse{
+			Model:    "stt-small",
+			Text:     " part " + formatTimestamp(chunk.Offset) + " ",
+			Duration: 600,
+			Segments: []transcriptSegment{{Start: 1.5, End: 4, Text: " hello "}},
+		}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transcript.Text != "part 00:00:00 part 00:10:00" || transcript.Duration != 1200 || transcript.Model != "stt-small" {
+		t.Fatalf("transcript = %+v", transcript)
+	}
+	if len(transcript.Segments) != 2 || transcript.Segments[1].Start != 601.5 || transcript.Segments[1].End != 604 {
+		t.Fatalf("segments = %+v", transcript.Segments)
+	}
+	if got := formatTranscript(transcript, true); got != "[00:00:01] hello\n[00:10:01] hello\n" {
+		t.Fatalf("formatted = %q", got)
+	}
+
+	_, err = transcribeChunks(chunks, func(chunk audioChunk) (transcriptionResponse, error) {
+		if chunk.Offset > 0 {
+			return transcriptionResponse{}, errors.New("boom")
+		}
+		return transcriptionResponse{Text: "ok"}, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "chunk 2 of 2 (at 00:10:00)") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestTranscribeChunkUsesResponsesWithAudioPart(t *testing.T) {
+	audio := testWAV(0.01)
+	var sawAudio bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sawAudio = r.URL.Path == "/responses" && strings.Contains(string(body), base64.StdEncoding.EncodeToString(audio))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(llm.Response{
+			ID:    "resp_test",
+			Model: "stt-small",
+			Output: []llm.OutputItem{{
+				Type:    llm.OutputItemTypeMessage,
+				Role:    llm.RoleAssistant,
+				Content: []llm.ContentPart{llm.TextPart(`{"text":"hello","language":"en","duration":1.5,"segments":[{"start":0,"end":1.5,"text":"hello"}]}`)},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := newPromptClient(runtimeConfig{BaseURL: server.URL, APIKey: "mr_sk_test", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	structured, err := newStructuredOutput("transcript", "transcript", json.RawMessage(transcriptSchema), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transcribeChunk(context.Background(), client, "stt-small", structured, transcriptionInstruction("en", ""), audioChunk{Data: audio, MimeType: "audio/wav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sawAudio {
+		t.Fatal("expected the recording as a file part of a /responses request")
+	}
+	if resp.Text != "hello" || resp.Language != "en" || len(resp.Segments) != 1 || resp.Model != "stt-small" {
+		t.Fatalf("transcript = %+v", resp)
+	}
+}
+
+func TestLoadAudioChunks(t *testing.T) {
+	dir := t.TempDir()
+	wav := filepath.Join(dir, "call.wav")
+	if err := os.WriteFile(wav, testWAV(3), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := loadAudioChunks(t.Context(), wav, defaultChunkSeconds, false)
+	if err != nil || len(chunks) != 1 || chunks[0].MimeType != "audio/wav" {
+		t.Fatalf("chunks = %d, %v", len(chunks), err)
+	}
+	chunks, err = loadAudioChunks(t.Context(), wav, 1, true)
+	if err != nil || len(chunks) != 3 || math.Abs(chunks[2].Offset-2) > 1e-9 {
+		t.Fatalf("forced chunks = %d, %v", len(chunks), err)
+	}
+
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadAudioChunks(t.Context(), notes, defaultChunkSeconds, false); err == nil {
+		t.Fatal("expected an error for a text file")
+	}
+}
+
+func TestSpeechOutput(t *testing.T) {
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	cases := []struct {
+		format, output, wantFormat, wantOutput string
+	}{
+		{"", "", "mp3", "speech-20260304-050607.mp3"},
+		{"", "hello.WAV", "wav", "hello.WAV"},
+		{"opus", "-", "opus", "-"},
+		{".flac", "", "flac", "speech-20260304-050607.flac"},
+	}
+	for _, tc := range cases {
+		format, output, err := speechOutput(tc.format, tc.output, now)
+		if err != nil || format != tc.wantFormat || output != tc.wantOutput {
+			t.Fatalf("speechOutput(%q, %q) = %q, %q, %v", tc.format, tc.output, format, output, err)
+		}
+	}
+	if _, _, err := speechOutput("", "out.txt", now); err == nil {
+		t.Fatal("expected an error for an unknown extension")
+	}
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+	"github.com/spf13/cobra"
+)
+
+const (
+	batchStatusOK    = "ok"
+	batchStatusError = "error"
+)
+
+type batchFlags struct {
+	model       string
+	system      string
+	outputPath  string
+	concurrency int
+	retries     int
+}
+
+// batchItem is one line of a batch input file.
+type batchItem struct {
+	ID          string   `json:"id,omitempty"`
+	Prompt      string   `json:"prompt"`
+	System      string   `json:"system,omitempty"`
+	Model       string   `json:"model,omitempty"`
+	Attachments []string `json:"attachments,omitempty"`
+
+	line int
+}
+
+// batchResult is one line of a batch output file.
+type batchResult struct {
+	ID        string       `json:"id"`
+	Line      int          `json:"line"`
+	Status    string       `json:"status"`
+	Model     string       `json:"model,omitempty"`
+	Output    string       `json:"output,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Usage     sessionUsage `json:"usage"`
+	LatencyMS int64        `json:"latency_ms"`
+	Attempts  int          `json:"attempts"`
+}
+
+type batchSummary struct {
+	Output    string       `json:"output"`
+	Total     int          `json:"total"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Skipped   int          `json:"skipped"`
+	Usage     sessionUsage `json:"usage"`
+}
+
+func newBatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Run prompts in bulk",
+	}
+	cmd.AddCommand(newBatchRunCmd())
+	return cmd
+}
+
+func newBatchRunCmd() *cobra.Command {
+	flags := &batchFlags{}
+	cmd := &cobra.Command{
+		Use:   "run <input.jsonl>",
+		Short: "Run a JSONL file of prompts with bounded concurrency",
+		Long: `Run a JSONL file of prompts with bounded concurrency.
+
+Each input line is an object:
+  {"id": "q1", "prompt": "...", "system": "...", "model": "...", "attachments": ["doc.pdf"]}
+
+Only "prompt" is required. "id" defaults to the line number, and relative
+attachment paths are resolved against the input file's directory.
+
+Results are appended to the output file as JSONL (default: <input>.out.jsonl)
+with usage and latency per row. Re-running the same command resumes: rows
+that already succeeded in the output file are skipped, failed rows are retried.
+On resume the output file is first compacted to one line per succeeded row.
+
+A row is retried up to --retries times after a rate limit, server error,
+network error or timeout. Other errors, such as an invalid request or a
+rejected API key, fail the row at once.
+
+Examples:
+  mrl batch run questions.jsonl
+  mrl batch run questions.jsonl --concurrency 8 --retries 3 -o answers.jsonl`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBatch(cmd, args[0], flags)
+		},
+	}
+	cmd.Flags().StringVar(&flags.model, "model", "", "Default model ID for rows without one (overrides profile default)")
+	cmd.Flags().StringVar(&flags.system, "system", "", "Default system prompt for rows without one")
+	cmd.Flags().StringVarP(&flags.outputPath, "output", "o", "", "Output JSONL file (default: <input>.out.jsonl)")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", 4, "Number of prompts to run in parallel")
+	cmd.Flags().IntVar(&flags.retries, "retries", 2, "Retries per row after a failed request")
+	return cmd
+}
+
+func runBatch(cmd *cobra.Command, inputPath string, flags *batchFlags) error {
+	cfg, err := runtimeConfigFrom(cmd)
+	if err != nil {
+		return err
+	}
+	if flags.concurrency < 1 {
+		return errors.New("concurrency must be >= 1")
+	}
+	if flags.retries < 0 {
+		return errors.New("retries must be >= 0")
+	}
+
+	items, err := readBatchItems(inputPath)
+	if err != nil {
+		return err
+	}
+	defaultModel := resolveModel(flags.model, cfg)
+	for _, item := range items {
+		if strings.TrimSpace(item.Model) == "" && defaultModel == "" {
+			return fmt.Errorf("line %d: model is required (set it on the row, via --model, MODELRELAY_MODEL, or mrl config set --model)", item.line)
+		}
+	}
+
+	outputPath := strings.TrimSpace(flags.outputPath)
+	if outputPath == "" {
+		outputPath = defaultBatchOutputPath(inputPath)
+	}
+	completed, err := compactBatchOutput(outputPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := newPromptClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if mkdirErr := os.MkdirAll(filepath.Dir(outputPath), 0o700); mkdirErr != nil {
+		return fmt.Errorf("failed to create output directory: %w", mkdirErr)
+	}
+	out, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600) //nolint:gosec // output path is explicitly selected by the CLI user
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	summary := batchSummary{Output: outputPath, Total: len(items)}
+	pending := make([]batchItem, 0, len(items))
+	for _, item := range items {
+		if _, done := completed[item.ID]; done {
+			summary.Skipped++
+			continue
+		}
+		pending = append(pending, item)
+	}
+
+	costs, err := newCostTracker(cfg, 0)
+	if err != nil {
+		return err
+	}
+	runner := &batchRunner{
+		client:       client,
+		cfg:          cfg,
+		costs:        costs,
+		command:      ledgerCommandName(cmd),
+		defaultModel: defaultModel,
+		defaultSys:   flags.system,
+		baseDir:      filepath.Dir(inputPath),
+		retries:      flags.retries,
+		out:          out,
+		summary:      &summary,
+	}
+	if err := runner.run(cmd.Context(), pending, flags.concurrency); err != nil {
+		return err
+	}
+
+	if cfg.Output == outputFormatJSON {
+		printJSON(summary)
+	} else {
+		fmt.Fprintf(os.Stderr, "batch: %d succeeded, %d failed, %d skipped | %d tokens | results in %s\n",
+			summary.Succeeded, summary.Failed, summary.Skipped, summary.Usage.TotalTokens, outputPath)
+	}
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d batch rows failed (re-run to retry them)", summary.Failed)
+	}
+	return nil
+}
+
+type batchRunner struct {
+	client       *sdk.Client
+	cfg          runtimeConfig
+	costs        *costTracker
+	command      string
+	defaultModel string
+	defaultSys   string
+	baseDir      string
+	retries      int
+
+	mu      sync.Mutex
+	out     io.Writer
+	summary *batchSummary
+}
+
+func (r *batchRunner) run(ctx context.Context, items []batchItem, concurrency int) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	queue := make(chan batchItem)
+	var wg sync.WaitGroup
+	var writeErr error
+	var writeErrOnce sync.Once
+
+	for range min(concurrency, max(len(items), 1)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				result := r.runItem(ctx, item)
+				if err := r.record(result); err != nil {
+					writeErrOnce.Do(func() { writeErr = err })
+				}
+			}
+		}()
+	}
+	for _, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- item
+	}
+	close(queue)
+	wg.Wait()
+	if writeErr != nil {
+		return fmt.Errorf("failed to write batch output: %w", writeErr)
+	}
+	return ctx.Err()
+}
+
+func (r *batchRunner) runItem(ctx context.Context, item batchItem) batchResult {
+	result := batchResult{ID: item.ID, Line: item.line, Status: batchStatusError}
+	model := firstNonEmpty(strings.TrimSpace(item.Model), r.defaultModel)
+	system := firstNonEmpty(item.System, r.defaultSys)
+	result.Model = model
+
+	parts := []llm.ContentPart{llm.TextPart(item.Prompt)}
+	if len(item.Attachments) > 0 {
+		attachmentParts, err := collectAttachmentParts(ctx, r.cfg, resolveBatchAttachmentPaths(r.baseDir, item.Attachments), attachmentOptions{}, nil)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		parts = append(parts, attachmentParts...)
+	}
+	input := []llm.InputItem{{
+		Type:    llm.InputItemTypeMessage,
+		Role:    llm.RoleUser,
+		Content: parts,
+	}}
+
+	for attempt := 0; attempt <= r.retries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, batchRetryDelay(attempt)); err != nil {
+				result.Error = err.Error()
+				return result
+			}
+		}
+		result.Attempts = attempt + 1
+		itemCtx, cancel := batchItemContext(ctx, r.cfg.Timeout)
+		itemCtx, outcome := withCallOutcome(itemCtx)
+		completion, err := runCompletion(itemCtx, r.client, model, system, input, nil)
+		cancel()
+		if err != nil {
+			result.Error = err.Error()
+			if !transientFailure(err, outcome) {
+				return result
+			}
+			continue
+		}
+		result.Status = batchStatusOK
+		result.Error = ""
+		result.Output = completion.Text
+		if !completion.Model.IsEmpty() {
+			result.Model = completion.Model.String()
+		}
+		result.Usage = sessionUsage{
+			InputTokens:  completion.Usage.InputTokens,
+			OutputTokens: completion.Usage.OutputTokens,
+			TotalTokens:  completion.Usage.TotalTokens,
+		}
+		result.LatencyMS = completion.Latency.Milliseconds()
+		recordUsage(r.command, r.cfg, result.Model, 1, completion.Usage, completion.Latency, pricedCents(r.costs.record(ctx, result.Model, completion.Usage)))
+		return result
+	}
+	return result
+}
+
+func (r *batchRunner) record(result batchResult) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if result.Status == batchStatusOK {
+		r.summary.Succeeded++
+	} else {
+		r.summary.Failed++
+	}
+	r.summary.Usage.InputTokens += result.Usage.InputTokens
+	r.summary.Usage.OutputTokens += result.Usage.OutputTokens
+	r.summary.Usage.TotalTokens += result.Usage.TotalTokens
+	_, err = r.out.Write(append(line, '\n'))
+	return err
+}
+
+func readBatchItems(path string) ([]batchItem, error) {
+	f, err := os.Open(path) //nolint:gosec // batch input is explicitly selected by the CLI user
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return parseBatchItems(f)
+}
+
+func parseBatchItems(r io.Reader) ([]batchItem, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var items []batchItem
+	seen := make(map[string]int)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		var item batchItem
+		if err := json.Unmarshal([]byte(raw), &item); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", lineNo, err)
+		}
+		if strings.TrimSpace(item.Prompt) == "" {
+			return nil, fmt.Errorf("line %d: prompt is required", lineNo)
+		}
+		item.line = lineNo
+		item.ID = strings.TrimSpace(item.ID)
+		if item.ID == "" {
+			item.ID = strconv.Itoa(lineNo)
+		}
+		if prev, dup := seen[item.ID]; dup {
+			return nil, fmt.Errorf("line %d: duplicate id %q (first seen on line %d)", lineNo, item.ID, prev)
+		}
+		seen[item.ID] = lineNo
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("batch input is empty")
+	}
+	return items, nil
+}
+
+// compactBatchOutput returns the IDs of rows that already succeeded in an
+// existing output file, and rewrites the file to hold only the first
+// succeeded line of each, so failed attempts don't pile up across resumes.
+// A missing file means nothing has run yet; a trailing partial line from an
+// interrupted run is dropped.
+func compactBatchOutput(path string) (map[string]struct{}, error) {
+	completed := make(map[string]struct{})
+	data, err := os.ReadFile(path) //nolint:gosec // output path is explicitly selected by the CLI user
+	if err != nil {
+		if os.IsNotExist(err) {
+			return completed, nil
+		}
+		return nil, err
+	}
+	var kept bytes.Buffer
+	for line := range bytes.Lines(data) {
+		var result batchResult
+		if json.Unmarshal(line, &result) != nil || result.Status != batchStatusOK || result.ID == "" {
+			continue
+		}
+		if _, dup := completed[result.ID]; dup {
+			continue
+		}
+		completed[result.ID] = struct{}{}
+		kept.Write(bytes.TrimRight(line, "\r\n"))
+		kept.WriteByte('\n')
+	}
+	if bytes.Equal(kept.Bytes(), data) {
+		return completed, nil
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, kept.Bytes(), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+	return completed, nil
+}
+
+func defaultBatchOutputPath(inputPath string) string {
+	ext := filepath.Ext(inputPath)
+	return strings.TrimSuffix(inputPath, ext) + ".out.jsonl"
+}
+
+func resolveBatchAttachmentPaths(baseDir string, paths []string) []string {
+	out := make([]string, 0, len(paths))
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path != "" && path != "-" && !isURLAttachment(path) && !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		out = append(out, path)
+	}
+	return out
+}
+
+func batchRetryDelay(attempt int) time.Duration {
+	return time.Second << min(attempt-1, 5)
+}
+
+func batchItemContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBatchItems(t *testing.T) {
+	input := `{"id":"a","prompt":"hello","model":"m1","attachments":["doc.txt"]}
+
+{"prompt":"second","system":"be brief"}
+`
+	items, err := parseBatchItems(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if items[0].ID != "a" || items[0].Model != "m1" || items[0].line != 1 {
+		t.Fatalf("unexpected first item: %+v", items[0])
+	}
+	if items[1].ID != "3" || items[1].System != "be brief" || items[1].line != 3 {
+		t.Fatalf("unexpected second item: %+v", items[1])
+	}
+}
+
+func TestParseBatchItemsErrors(t *testing.T) {
+	cases := map[string]string{
+		"missing prompt": `{"id":"a"}`,
+		"invalid json":   `{"prompt":`,
+		"duplicate id":   "{\"id\":\"a\",\"prompt\":\"x\"}\n{\"id\":\"a\",\"prompt\":\"y\"}",
+		"empty":          "\n\n",
+	}
+	for name, input := range cases {
+		if _, err := parseBatchItems(strings.NewReader(input)); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCompactBatchOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+	completed, err := compactBatchOutput(path)
+	if err != nil || len(completed) != 0 {
+		t.Fatalf("missing file: got %v, %v", completed, err)
+	}
+
+	data := `{"id":"a","status":"ok"}
+{"id":"b","status":"error","error":"boom"}
+{"id":"b","status":"error","error":"boom again"}
+{"id":"c","status":"ok"}
+{"id":"a","status":"ok","output":"second"}
+{"id":"d","sta`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	completed, err = compactBatchOutput(path)
+	if err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if len(completed) != 2 {
+		t.Fatalf("expected 2 completed ids, got %v", completed)
+	}
+	for _, id := range []string{"a", "c"} {
+		if _, ok := completed[id]; !ok {
+			t.Fatalf("expected %s to be completed", id)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"id\":\"a\",\"status\":\"ok\"}\n{\"id\":\"c\",\"status\":\"ok\"}\n"; string(got) != want {
+		t.Fatalf("compacted output = %q, want %q", got, want)
+	}
+}
+
+func TestBatchPaths(t *testing.T) {
+	if got := defaultBatchOutputPath("runs/input.jsonl"); got != "runs/input.out.jsonl" {
+		t.Fatalf("unexpected output path %q", got)
+	}
+	got := resolveBatchAttachmentPaths("runs", []string{"doc.txt", "/abs/a.png", "-"})
+	want := []string{filepath.Join("runs", "doc.txt"), "/abs/a.png", "-"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("path %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
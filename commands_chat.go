@@ -14,24 +14,92 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// promptOptions carries the flags of a single prompt invocation. It is filled
+// from the root command flags or from a stored template.
+type promptOptions struct {
+	model          string
+	system         string
+	attachments    []string
+	attachmentType string
+	attachStdin    bool
+	stream         bool
+	showUsage      bool
+	session        string
+	schemaPath     string
+	schemaRetries  int
+	maxCost        float64
+	fallbacks      []string
+	// extract sends text extracted from document attachments instead of
+	// the files themselves.
+	extract bool
+	// maxContextTokens budgets text packed from directory and glob
+	// attachments (0 for no limit).
+	maxContextTokens int64
+	// toClipboard copies the response text to the clipboard.
+	toClipboard bool
+	// raw prints the response as is instead of rendering markdown on a
+	// terminal; extractCode prints only its fenced code blocks.
+	raw         bool
+	extractCode bool
+	// stdinSlot marks a prompt rendered from a template that contains the
+	// {{stdin}} placeholder: piped stdin is substituted there instead of
+	// being prepended to the prompt.
+	stdinSlot bool
+}
+
 // runPrompt is the default action when mrl is invoked with a prompt.
-func runPrompt(cmd *cobra.Command, args []string, modelFlag, system string, attachments []string, attachmentType string, attachStdin bool, stream, showUsage bool) error {
+func runPrompt(cmd *cobra.Command, prompt string, opts promptOptions) error {
 	cfg, err := runtimeConfigFrom(cmd)
 	if err != nil {
 		return err
 	}
 
+	// A named session supplies the history plus the model and system prompt
+	// it was started with; explicit flags still take precedence.
+	modelFlag := opts.model
+	system := opts.system
+	var session *promptSession
+	if strings.TrimSpace(opts.session) != "" {
+		session, err = openPromptSession(opts.session)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(modelFlag) == "" {
+			modelFlag = session.Model
+		}
+		if system == "" {
+			system = session.System
+		}
+	}
+
 	model := resolveModel(modelFlag, cfg)
 	if model == "" {
 		return errors.New("model is required (set via --model, MODELRELAY_MODEL, or mrl config set --model)")
 	}
 
+	structured, err := loadStructuredOutput(opts.schemaPath, opts.schemaRetries)
+	if err != nil {
+		return err
+	}
+	if structured != nil && opts.stream {
+		return errors.New("--schema cannot be combined with --stream")
+	}
+	if structured != nil && opts.extractCode {
+		return errors.New("--schema cannot be combined with --extract-code")
+	}
+	// Structured answers are JSON for other programs; never restyle them.
+	mode := responseMarkdownMode(opts.raw || structured != nil, opts.extractCode)
+
+	costs, err := newCostTracker(cfg, opts.maxCost)
+	if err != nil {
+		return err
+	}
+
 	client, err := newPromptClient(cfg)
 	if err != nil {
 		return err
 	}
 
-	prompt := strings.Join(args, " ")
 	stdinIsTTY, err := isTerminal(os.Stdin)
 	if err != nil {
 		return err
@@ -39,8 +107,15 @@ func runPrompt(cmd *cobra.Command, args []string, modelFlag, system string, atta
 
 	// Auto-read stdin as text when piped and no explicit attachment flags are set.
 	// This enables: cat file.md | mrl "question about the content"
+	// A template {{stdin}} slot claims stdin unless it is attached explicitly.
 	var stdinText string
-	useStdinAsText := !stdinIsTTY && len(attachments) == 0 && attachmentType == "" && !attachStdin
+	useStdinAsText := !stdinIsTTY && len(opts.attachments) == 0 && opts.attachmentType == "" && !opts.attachStdin
+	if opts.stdinSlot {
+		useStdinAsText = !stdinIsTTY && !opts.attachStdin && !hasStdinAttachment(opts.attachments)
+		if !useStdinAsText {
+			return errors.New("this template reads {{stdin}}; pipe input into mrl run")
+		}
+	}
 	if useStdinAsText {
 		data, readErr := io.ReadAll(os.Stdin)
 		if readErr != nil {
@@ -49,21 +124,24 @@ func runPrompt(cmd *cobra.Command, args []string, modelFlag, system string, atta
 		stdinText = string(data)
 	}
 
-	var attachmentParts []llm.ContentPart
-	if !useStdinAsText {
-		resolvedAttachments, resolveErr := resolveAttachmentInputs(attachments, attachmentType, attachStdin, stdinIsTTY)
-		if resolveErr != nil {
-			return resolveErr
-		}
-		attachmentParts, err = buildAttachmentParts(resolvedAttachments, attachmentType, os.Stdin)
-		if err != nil {
-			return err
-		}
+	resolvedAttachments, err := resolveAttachmentInputs(opts.attachments, opts.attachmentType, opts.attachStdin, stdinIsTTY || useStdinAsText)
+	if err != nil {
+		return err
+	}
+	attachmentParts, err := collectAttachmentParts(cmd.Context(), cfg, resolvedAttachments, attachmentOptions{
+		mimeType:         opts.attachmentType,
+		extract:          opts.extract,
+		maxContextTokens: opts.maxContextTokens,
+	}, os.Stdin)
+	if err != nil {
+		return err
 	}
 
 	// Build the final prompt: stdin text (if any) + args prompt
 	var finalPrompt string
 	switch {
+	case opts.stdinSlot:
+		finalPrompt = strings.ReplaceAll(prompt, templateStdinPlaceholder, stdinText)
 	case stdinText != "" && strings.TrimSpace(prompt) != "":
 		finalPrompt = stdinText + "\n\n" + prompt
 	case stdinText != "":
@@ -81,44 +159,167 @@ func runPrompt(cmd *cobra.Command, args []string, modelFlag, system string, atta
 		return errors.New("prompt or attachment required")
 	}
 
-	ctx, cancel := contextWithTimeout(cfg.Timeout)
+	chain := modelChain(model, opts.fallbacks, cfg)
+	ctx, cancel := contextWithTimeout(fallbackTimeout(cfg.Timeout, chain))
 	defer cancel()
 
-	if stream {
-		return runStreamWithUsage(ctx, client, model, system, userParts, showUsage)
-	}
-
-	builder := client.Responses.New().Model(sdk.NewModelID(model))
-	if system != "" {
-		builder = builder.System(system)
-	}
-	builder = builder.Item(llm.InputItem{
+	userItem := llm.InputItem{
 		Type:    llm.InputItemTypeMessage,
 		Role:    llm.RoleUser,
 		Content: userParts,
+	}
+	var input []llm.InputItem
+	if session != nil {
+		input = append(input, session.Items...)
+	}
+	input = append(input, userItem)
+
+	if err := costs.checkInput(ctx, model, estimateInputTokens(promptInput(system, input))); err != nil {
+		return err
+	}
+
+	out := newMarkdownWriter(os.Stdout, mode)
+	result, served, err := callWithFallback(ctx, chain, cfg.Timeout, func(ctx context.Context, model string) (promptResult, error) {
+		switch {
+		case opts.stream:
+			return runStreamWithUsage(ctx, client, model, system, input, false, out)
+		case structured != nil:
+			return runStructuredCompletion(ctx, client, model, system, input, structured)
+		default:
+			return runCompletion(ctx, client, model, system, input, nil)
+		}
 	})
+	if err != nil {
+		return err
+	}
+	if !opts.stream {
+		_, _ = out.WriteString(result.Text)
+		_ = out.Flush()
+	}
+	if opts.toClipboard {
+		if err := writeClipboard(ctx, result.Text); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not copy the response to the clipboard: %v\n", err)
+		}
+	}
+	servedModel := firstNonEmpty(result.Model.String(), chain[served])
+	recordUsage(ledgerCommandName(cmd), cfg, servedModel, 1, result.Usage, result.Latency, pricedCents(costs.record(ctx, servedModel, result.Usage)))
+	if opts.showUsage && (result.Usage.InputTokens > 0 || result.Usage.OutputTokens > 0) {
+		printPromptUsage(result, opts.stream, costs.summary())
+	}
+	costs.warnIfOverBudget()
+
+	if session != nil {
+		session.Model = model
+		session.System = system
+		session.recordTurn(userItem, finalPrompt, result)
+		if err := savePromptSession(session); err != nil {
+			return fmt.Errorf("failed to save session %s: %w", session.Name, err)
+		}
+	}
+	if opts.extractCode && !out.foundCode() {
+		return errors.New("response has no fenced code blocks")
+	}
+	return nil
+}
+
+// promptResult captures one completed prompt turn so callers that keep a
+// conversation history can append the assistant reply and account for usage.
+type promptResult struct {
+	Text    string
+	Model   sdk.ModelID
+	Usage   sdk.Usage
+	Latency time.Duration
+	TTFT    time.Duration
+}
+
+// promptInput prepends the system prompt (if any) to the conversation items.
+func promptInput(system string, items []llm.InputItem) []llm.InputItem {
+	if strings.TrimSpace(system) == "" {
+		return items
+	}
+	out := make([]llm.InputItem, 0, len(items)+1)
+	out = append(out, llm.NewSystemText(system))
+	return append(out, items...)
+}
 
+func runCompletion(ctx context.Context, client *sdk.Client, model, system string, items []llm.InputItem, structured *structuredOutput) (promptResult, error) {
 	start := time.Now()
+	builder := client.Responses.New().
+		Model(sdk.NewModelID(model)).
+		Input(promptInput(system, items))
+	if structured != nil {
+		builder = builder.OutputFormat(structured.format())
+	}
 	req, callOpts, err := builder.Build()
 	if err != nil {
-		return err
+		return promptResult{}, err
 	}
 	resp, err := client.Responses.Create(ctx, req, callOpts...)
 	if err != nil {
-		return err
+		return promptResult{}, err
+	}
+	return promptResult{
+		Text:    resp.AssistantText(),
+		Model:   resp.Model,
+		Usage:   resp.Usage,
+		Latency: time.Since(start),
+	}, nil
+}
+
+// runStructuredCompletion runs a completion whose answer must match the
+// schema, re-prompting with the validation error up to structured.retries
+// times. Usage and latency are summed across attempts.
+func runStructuredCompletion(ctx context.Context, client *sdk.Client, model, system string, items []llm.InputItem, structured *structuredOutput) (promptResult, error) {
+	var total promptResult
+	attempt := append([]llm.InputItem(nil), items...)
+	for try := 0; ; try++ {
+		result, err := runCompletion(ctx, client, model, system, attempt, structured)
+		if err != nil {
+			return promptResult{}, err
+		}
+		total.Model = result.Model
+		total.Latency += result.Latency
+		total.Usage.InputTokens += result.Usage.InputTokens
+		total.Usage.OutputTokens += result.Usage.OutputTokens
+		total.Usage.TotalTokens += result.Usage.TotalTokens
+
+		validated, validationErr := structured.validate(result.Text)
+		if validationErr == nil {
+			total.Text = string(validated)
+			return total, nil
+		}
+		if try >= structured.retries {
+			return total, noFallback(validationErr)
+		}
+		attempt = append(attempt, llm.NewAssistantText(result.Text), structured.retryMessage(validationErr))
 	}
-	latency := time.Since(start)
+}
 
-	fmt.Println(resp.AssistantText())
-	if showUsage {
-		fmt.Printf("\nModel: %s | Tokens: %d in / %d out | Latency: %s\n",
-			resp.Model,
-			resp.Usage.InputTokens,
-			resp.Usage.OutputTokens,
-			latency.Round(time.Millisecond),
+// printPromptUsage prints the usage line after a response. cost is the
+// formatted estimated cost, or empty to leave it out.
+func printPromptUsage(result promptResult, streamed bool, cost string) {
+	costSuffix := ""
+	if cost != "" {
+		costSuffix = " | Cost: " + cost
+	}
+	if streamed {
+		fmt.Printf("\nModel: %s | Tokens: %d in / %d out | TTFT: %s | Total: %s%s\n",
+			result.Model,
+			result.Usage.InputTokens,
+			result.Usage.OutputTokens,
+			result.TTFT.Round(time.Millisecond),
+			result.Latency.Round(time.Millisecond),
+			costSuffix,
 		)
+		return
 	}
-	return nil
+	fmt.Printf("\nModel: %s | Tokens: %d in / %d out | Latency: %s%s\n",
+		result.Model,
+		result.Usage.InputTokens,
+		result.Usage.OutputTokens,
+		result.Latency.Round(time.Millisecond),
+		costSuffix,
+	)
 }
 
 func isTerminal(file *os.File) (bool, error) {
@@ -129,69 +330,77 @@ func isTerminal(file *os.File) (bool, error) {
 	return (info.Mode() & os.ModeCharDevice) != 0, nil
 }
 
-func runStreamWithUsage(ctx context.Context, client *sdk.Client, model, system string, userParts []llm.ContentPart, showUsage bool) error {
-	builder := client.Responses.New().Model(sdk.NewModelID(model))
-	if system != "" {
-		builder = builder.System(system)
-	}
-	builder = builder.Item(llm.InputItem{
-		Type:    llm.InputItemTypeMessage,
-		Role:    llm.RoleUser,
-		Content: userParts,
+// runStreamWithUsage streams a completion through out, which renders the
+// deltas as they arrive.
+func runStreamWithUsage(ctx context.Context, client *sdk.Client, model, system string, items []llm.InputItem, showUsage bool, out *markdownWriter) (promptResult, error) {
+	result, sawUsage, err := streamCompletion(ctx, client, model, system, items, func(delta string) {
+		_, _ = out.WriteString(delta)
 	})
+	if err != nil {
+		return promptResult{}, err
+	}
+	_ = out.Flush()
 
-	req, opts, err := builder.Build()
+	if showUsage && sawUsage {
+		printPromptUsage(result, true, "")
+	}
+	return result, nil
+}
+
+// streamCompletion streams one completion, passing each text delta to
+// onDelta, and measures time to first token. sawUsage reports whether the
+// stream carried usage.
+func streamCompletion(ctx context.Context, client *sdk.Client, model, system string, items []llm.InputItem, onDelta func(string)) (result promptResult, sawUsage bool, err error) {
+	req, opts, err := client.Responses.New().
+		Model(sdk.NewModelID(model)).
+		Input(promptInput(system, items)).
+		Build()
 	if err != nil {
-		return err
+		return promptResult{}, false, err
 	}
 
 	start := time.Now()
 	stream, err := client.Responses.Stream(ctx, req, opts...)
 	if err != nil {
-		return err
+		return promptResult{}, false, err
 	}
 	defer func() { _ = stream.Close() }()
 
-	var finalModel sdk.ModelID
-	var finalUsage *sdk.Usage
-	var ttft time.Duration
-	var sawFirstToken bool
+	var (
+		text          strings.Builder
+		sawFirstToken bool
+	)
 
 	for {
 		ev, ok, err := stream.Next()
 		if err != nil {
-			return err
+			if sawFirstToken {
+				return promptResult{}, false, noFallback(err)
+			}
+			return promptResult{}, false, err
 		}
 		if !ok {
 			break
 		}
 		if ev.TextDelta != "" {
 			if !sawFirstToken {
-				ttft = time.Since(start)
+				result.TTFT = time.Since(start)
 				sawFirstToken = true
 			}
-			fmt.Print(ev.TextDelta)
+			onDelta(ev.TextDelta)
+			text.WriteString(ev.TextDelta)
 		}
 		if !ev.Model.IsEmpty() {
-			finalModel = ev.Model
+			result.Model = ev.Model
 		}
 		if ev.Usage != nil {
-			finalUsage = ev.Usage
+			result.Usage = *ev.Usage
+			sawUsage = true
 		}
 	}
-	totalDuration := time.Since(start)
-	fmt.Println()
-
-	if showUsage && finalUsage != nil {
-		fmt.Printf("\nModel: %s | Tokens: %d in / %d out | TTFT: %s | Total: %s\n",
-			finalModel,
-			finalUsage.InputTokens,
-			finalUsage.OutputTokens,
-			ttft.Round(time.Millisecond),
-			totalDuration.Round(time.Millisecond),
-		)
-	}
-	return nil
+	result.Latency = time.Since(start)
+	result.Text = text.String()
+	return result, sawUsage, nil
 }
 
 func newPromptClient(cfg runtimeConfig) (*sdk.Client, error) {
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+	"github.com/spf13/cobra"
+)
+
+const chatReplHelp = `Commands:
+  /model [id]      Show or switch the model
+  /system [text]   Set the system prompt (no text clears it)
+  /attach <path>   Attach a file to the next message
+  /usage           Show token usage for this conversation
+  /reset           Clear the conversation history
+  /help            Show this help
+  /exit            Leave the chat (Ctrl-D also works)`
+
+func newChatCmd() *cobra.Command {
+	var model string
+	var system string
+	var stream bool
+	var usage bool
+	var attachments []string
+	var attachmentType string
+	var extract bool
+	var sessionName string
+
+	cmd := &cobra.Command{
+		Use:   "chat [prompt]",
+		Short: "Start an interactive multi-turn chat",
+		Long: `Start an interactive chat that keeps the conversation history across turns.
+
+Lines starting with '/' are commands:
+
+` + chatReplHelp + `
+
+Examples:
+  mrl chat
+  mrl chat --model gpt-5.2 --stream
+  mrl chat --session refactor
+  mrl chat "Review this diff" -a change.patch`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runChat(cmd, args, model, system, attachments, attachmentType, extract, stream, usage, sessionName)
+		},
+	}
+
+	cmd.Flags().StringVar(&model, "model", "", "Model ID (overrides profile default)")
+	cmd.Flags().StringVar(&system, "system", "", "System prompt")
+	cmd.Flags().BoolVar(&stream, "stream", false, "Stream output as it's generated")
+	cmd.Flags().BoolVar(&usage, "usage", false, "Show token usage after each response")
+	cmd.Flags().StringArrayVarP(&attachments, "attachment", "a", nil, "Attach a local file to the first message (repeatable)")
+	cmd.Flags().StringVar(&attachmentType, "attachment-type", "", "Override attachment MIME type")
+	cmd.Flags().BoolVar(&extract, "extract", false, "Send text extracted from PDF, DOCX, XLSX, CSV and HTML attachments instead of the files")
+	cmd.Flags().StringVar(&sessionName, "session", "", "Continue (or start) a named conversation saved on disk")
+	return cmd
+}
+
+// chatSession is the mutable state of one interactive chat: the settings the
+// slash commands can change plus the accumulated conversation history.
+type chatSession struct {
+	model          string
+	system         string
+	attachmentType string
+	extract        bool
+	stream         bool
+	showUsage      bool
+	history        []llm.InputItem
+	pending        []string
+	turns          int
+	usage          sdk.Usage
+	saved          *promptSession
+	costs          *costTracker
+	command        string
+}
+
+func runChat(cmd *cobra.Command, args []string, modelFlag, system string, attachments []string, attachmentType string, extract, stream, showUsage bool, sessionName string) error {
+	cfg, err := runtimeConfigFrom(cmd)
+	if err != nil {
+		return err
+	}
+
+	var saved *promptSession
+	if strings.TrimSpace(sessionName) != "" {
+		saved, err = openPromptSession(sessionName)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(modelFlag) == "" {
+			modelFlag = saved.Model
+		}
+		if system == "" {
+			system = saved.System
+		}
+	}
+
+	model := resolveModel(modelFlag, cfg)
+	if model == "" {
+		return errors.New("model is required (set via --model, MODELRELAY_MODEL, or mrl config set --model)")
+	}
+	for _, path := range attachments {
+		if strings.TrimSpace(path) == "-" {
+			return errors.New("stdin attachments are not supported in chat mode")
+		}
+	}
+
+	costs, err := newCostTracker(cfg, 0)
+	if err != nil {
+		return err
+	}
+
+	client, err := newPromptClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	stdinIsTTY, err := isTerminal(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	session := &chatSession{
+		model:          model,
+		system:         system,
+		attachmentType: attachmentType,
+		extract:        extract,
+		stream:         stream,
+		showUsage:      showUsage,
+		pending:        append([]string(nil), attachments...),
+		saved:          saved,
+		costs:          costs,
+		command:        ledgerCommandName(cmd),
+	}
+	if saved != nil {
+		session.history = append([]llm.InputItem(nil), saved.Items...)
+		session.turns = len(saved.Turns)
+	}
+
+	if prompt := strings.TrimSpace(strings.Join(args, " ")); prompt != "" {
+		if err := session.send(client, cfg, prompt); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+	}
+	return session.loop(client, cfg, os.Stdin, stdinIsTTY)
+}
+
+func (s *chatSession) loop(client *sdk.Client, cfg runtimeConfig, in io.Reader, interactive bool) error {
+	if interactive {
+		fmt.Fprintf(os.Stderr, "Chatting with %s. Type /help for commands, /exit to quit.\n", s.model)
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		if interactive {
+			fmt.Fprint(os.Stderr, "> ")
+		}
+		if !scanner.Scan() {
+			if interactive {
+				fmt.Fprintln(os.Stderr)
+			}
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			done, err := s.handleCommand(line)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			}
+			if done {
+				return nil
+			}
+			continue
+		}
+		if err := s.send(client, cfg, line); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+	}
+}
+
+// handleCommand applies one slash command. It reports done=true when the
+// user asked to leave the chat.
+func (s *chatSession) handleCommand(line string) (bool, error) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(line, "/"), " ")
+	arg = strings.TrimSpace(arg)
+	switch strings.ToLower(name) {
+	case "exit", "quit", "q":
+		return true, nil
+	case "help", "?":
+		fmt.Println(chatReplHelp)
+	case "model":
+		if arg != "" {
+			s.model = arg
+		}
+		fmt.Printf("model: %s\n", s.model)
+	case "system":
+		s.system = arg
+		if arg == "" {
+			fmt.Println("system prompt cleared")
+		} else {
+			fmt.Println("system prompt set")
+		}
+	case "attach":
+		if arg == "" {
+			return false, errors.New("usage: /attach <path>")
+		}
+		if arg == "-" {
+			return false, errors.New("stdin attachments are not supported in chat mode")
+		}
+		if !isURLAttachment(arg) && !hasGlobMeta(arg) {
+			if _, err := os.Stat(arg); err != nil {
+				return false, err
+			}
+		}
+		s.pending = append(s.pending, arg)
+		fmt.Printf("attached %s to the next message\n", arg)
+	case "usage":
+		fmt.Printf("Model: %s | Turns: %d | Tokens: %d in / %d out\n",
+			s.model, s.turns, s.usage.InputTokens, s.usage.OutputTokens)
+	case "reset":
+		s.history = nil
+		s.pending = nil
+		s.turns = 0
+		s.usage = sdk.Usage{}
+		if s.saved != nil {
+			s.saved.Items = nil
+			s.saved.Turns = nil
+			if err := savePromptSession(s.saved); err != nil {
+				return false, err
+			}
+		}
+		fmt.Println("conversation reset")
+	default:
+		return false, fmt.Errorf("unknown command /%s (try /help)", name)
+	}
+	return false, nil
+}
+
+// send runs one user turn. The user message is only committed to the history
+// once the model has answered, so a failed turn can simply be retried.
+func (s *chatSession) send(client *sdk.Client, cfg runtimeConfig, prompt string) error {
+	parts := []llm.ContentPart{llm.TextPart(prompt)}
+	if len(s.pending) > 0 {
+		attachmentParts, err := collectAttachmentParts(context.Background(), cfg, s.pending, attachmentOptions{mimeType: s.attachmentType, extract: s.extract}, nil)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, attachmentParts...)
+	}
+	userItem := llm.InputItem{
+		Type:    llm.InputItemTypeMessage,
+		Role:    llm.RoleUser,
+		Content: parts,
+	}
+	items := append(append([]llm.InputItem(nil), s.history...), userItem)
+
+	ctx, cancel := contextWithTimeout(cfg.Timeout)
+	defer cancel()
+
+	result, err := s.complete(ctx, client, items)
+	if err != nil {
+		return err
+	}
+
+	s.history = append(items, llm.NewAssistantText(result.Text))
+	s.pending = nil
+	s.turns++
+	s.usage.InputTokens += result.Usage.InputTokens
+	s.usage.OutputTokens += result.Usage.OutputTokens
+	s.usage.TotalTokens += result.Usage.TotalTokens
+	servedModel := firstNonEmpty(result.Model.String(), s.model)
+	recordUsage(s.command, cfg, servedModel, 1, result.Usage, result.Latency, pricedCents(s.costs.record(ctx, servedModel, result.Usage)))
+
+	if s.saved != nil {
+		s.saved.Model = s.model
+		s.saved.System = s.system
+		s.saved.recordTurn(userItem, prompt, result)
+		if err := savePromptSession(s.saved); err != nil {
+			return fmt.Errorf("failed to save session %s: %w", s.saved.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *chatSession) complete(ctx context.Context, client *sdk.Client, items []llm.InputItem) (promptResult, error) {
+	if s.stream {
+		return runStreamWithUsage(ctx, client, s.model, s.system, items, s.showUsage, newMarkdownWriter(os.Stdout, markdownRaw))
+	}
+	result, err := runCompletion(ctx, client, s.model, s.system, items, nil)
+	if err != nil {
+		return promptResult{}, err
+	}
+	fmt.Println(result.Text)
+	if s.showUsage {
+		printPromptUsage(result, false, "")
+	}
+	return result, nil
+}
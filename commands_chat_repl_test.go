@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+func TestChatSessionHandleCommand_ModelAndSystem(t *testing.T) {
+	session := &chatSession{model: "a", system: "be brief"}
+
+	if done, err := session.handleCommand("/model b"); err != nil || done {
+		t.Fatalf("unexpected result: done=%v err=%v", done, err)
+	}
+	if session.model != "b" {
+		t.Fatalf("expected model b, got %s", session.model)
+	}
+	if _, err := session.handleCommand("/system"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if session.system != "" {
+		t.Fatalf("expected system prompt cleared, got %q", session.system)
+	}
+}
+
+func TestChatSessionHandleCommand_AttachAndReset(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	session := &chatSession{
+		history: []llm.InputItem{llm.NewUserText("hi"), llm.NewAssistantText("hello")},
+		turns:   1,
+		usage:   sdk.Usage{InputTokens: 3, OutputTokens: 2},
+	}
+
+	if _, err := session.handleCommand("/attach " + path); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if len(session.pending) != 1 || session.pending[0] != path {
+		t.Fatalf("expected pending attachment, got %v", session.pending)
+	}
+	if _, err := session.handleCommand("/attach " + filepath.Join(dir, "missing.txt")); err == nil {
+		t.Fatal("expected error for missing attachment")
+	}
+
+	if _, err := session.handleCommand("/reset"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if len(session.history) != 0 || len(session.pending) != 0 || session.turns != 0 || session.usage.InputTokens != 0 {
+		t.Fatalf("expected cleared session, got %+v", session)
+	}
+}
+
+func TestChatSessionHandleCommand_ExitAndUnknown(t *testing.T) {
+	session := &chatSession{}
+	done, err := session.handleCommand("/exit")
+	if err != nil || !done {
+		t.Fatalf("expected exit, got done=%v err=%v", done, err)
+	}
+	if _, err := session.handleCommand("/nope"); err == nil {
+		t.Fatal("expected error for unknown command")
+	}
+}
+
+func TestPromptInput_PrependsSystem(t *testing.T) {
+	items := []llm.InputItem{llm.NewUserText("hi")}
+	if got := promptInput("", items); len(got) != 1 {
+		t.Fatalf("expected items unchanged without system prompt, got %d", len(got))
+	}
+	got := promptInput("be brief", items)
+	if len(got) != 2 || got[0].Role != llm.RoleSystem {
+		t.Fatalf("expected system item first, got %+v", got)
+	}
+}
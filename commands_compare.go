@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+	"github.com/spf13/cobra"
+)
+
+type compareFlags struct {
+	models           []string
+	system           string
+	attachments      []string
+	attachmentType   string
+	extract          bool
+	maxContextTokens int64
+	width            int
+}
+
+// compareResult is one model's answer to the compared prompt.
+type compareResult struct {
+	Model       string        `json:"model"`
+	ServedModel string        `json:"served_model,omitempty"`
+	Provider    string        `json:"provider,omitempty"`
+	Output      string        `json:"output,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	LatencyMS   int64         `json:"latency_ms"`
+	TTFTMS      int64         `json:"ttft_ms"`
+	Usage       sessionUsage  `json:"usage"`
+	Pricing     *modelPricing `json:"pricing,omitempty"`
+	CostCents   *float64      `json:"estimated_cost_cents,omitempty"`
+}
+
+func newCompareCmd() *cobra.Command {
+	flags := &compareFlags{}
+	cmd := &cobra.Command{
+		Use:   "compare <prompt>",
+		Short: "Run one prompt against several models side by side",
+		Long: `Run the same prompt (with system prompt and attachments) concurrently
+against several models and print the answers side by side, with latency,
+time to first token, tokens and the pricing resolved for each model.
+
+Examples:
+  mrl compare "Explain CRDTs in two sentences" --model gpt-5.2 --model claude-sonnet-5
+  mrl compare "Summarize this" -a report.pdf --model a --model b --model c --json
+  git diff | mrl compare "Review this change" --model gpt-5.2,claude-sonnet-5`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCompare(cmd, strings.Join(args, " "), flags)
+		},
+	}
+	cmd.Flags().StringSliceVar(&flags.models, "model", nil, "Model to compare (repeatable or comma-separated; at least two)")
+	cmd.Flags().StringVar(&flags.system, "system", "", "System prompt")
+	cmd.Flags().StringArrayVarP(&flags.attachments, "attachment", "a", nil, "Attach a file, directory or glob (repeatable; use '-' for stdin)")
+	cmd.Flags().StringVar(&flags.attachmentType, "attachment-type", "", "Override attachment MIME type (useful for stdin)")
+	cmd.Flags().BoolVar(&flags.extract, "extract", false, "Send text extracted from PDF, DOCX, XLSX, CSV and HTML attachments instead of the files")
+	cmd.Flags().Int64Var(&flags.maxContextTokens, "max-context-tokens", 0, "Token budget for text packed from directory and glob attachments (0 = no limit)")
+	cmd.Flags().IntVar(&flags.width, "width", 0, "Output width for side-by-side answers (default: $COLUMNS or 120)")
+	return cmd
+}
+
+func runCompare(cmd *cobra.Command, prompt string, flags *compareFlags) error {
+	cfg, err := runtimeConfigFrom(cmd)
+	if err != nil {
+		return err
+	}
+	var models []string
+	for _, model := range splitCSVValues(flags.models) {
+		if !containsString(models, model) {
+			models = append(models, model)
+		}
+	}
+	if len(models) < 2 {
+		return errors.New("at least two distinct --model values are required")
+	}
+
+	client, err := newPromptClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	stdinIsTTY, err := isTerminal(os.Stdin)
+	if err != nil {
+		return err
+	}
+	// As for a plain prompt, piped stdin is read as text unless attached.
+	useStdinAsText := !stdinIsTTY && len(flags.attachments) == 0 && flags.attachmentType == ""
+	if useStdinAsText {
+		data, readErr := io.ReadAll(os.Stdin)
+		if readErr != nil {
+			return fmt.Errorf("failed to read stdin: %w", readErr)
+		}
+		if stdinText := string(data); strings.TrimSpace(stdinText) != "" {
+			prompt = strings.TrimSpace(stdinText + "\n\n" + prompt)
+		}
+	}
+	resolvedAttachments, err := resolveAttachmentInputs(flags.attachments, flags.attachmentType, false, stdinIsTTY || useStdinAsText)
+	if err != nil {
+		return err
+	}
+	attachmentParts, err := collectAttachmentParts(cmd.Context(), cfg, resolvedAttachments, attachmentOptions{
+		mimeType:         flags.attachmentType,
+		extract:          flags.extract,
+		maxContextTokens: flags.maxContextTokens,
+	}, os.Stdin)
+	if err != nil {
+		return err
+	}
+	userParts := make([]llm.ContentPart, 0, 1+len(attachmentParts))
+	if strings.TrimSpace(prompt) != "" {
+		userParts = append(userParts, llm.TextPart(prompt))
+	}
+	userParts = append(userParts, attachmentParts...)
+	if len(userParts) == 0 {
+		return errors.New("prompt or attachment required")
+	}
+	input := []llm.InputItem{{Type: llm.InputItemTypeMessage, Role: llm.RoleUser, Content: userParts}}
+
+	ctx, cancel := contextWithTimeout(cfg.Timeout)
+	defer cancel()
+
+	results := make([]compareResult, len(models))
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = compareModel(ctx, cfg, client, model, flags.system, input)
+		}()
+	}
+	wg.Wait()
+
+	command := ledgerCommandName(cmd)
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+			continue
+		}
+		usage := sdk.Usage{InputTokens: result.Usage.InputTokens, OutputTokens: result.Usage.OutputTokens, TotalTokens: result.Usage.TotalTokens}
+		recordUsage(command, cfg, firstNonEmpty(result.ServedModel, result.Model), 1, usage, time.Duration(result.LatencyMS)*time.Millisecond, result.CostCents)
+	}
+
+	if cfg.Output == outputFormatJSON {
+		printJSON(map[string]any{"prompt": prompt, "results": results})
+	} else {
+		printCompareResults(os.Stdout, results, compareWidth(flags.width))
+	}
+	if failed == len(results) {
+		return errors.New("all models failed")
+	}
+	return nil
+}
+
+// compareModel streams the prompt on one model while resolving its pricing,
+// so the answer carries both timing and cost.
+func compareModel(ctx context.Context, cfg runtimeConfig, client *sdk.Client, model, system string, input []llm.InputItem) compareResult {
+	result := compareResult{Model: model}
+
+	var pricing modelPricing
+	var provider string
+	pricingDone := make(chan struct{})
+	go func() {
+		defer close(pricingDone)
+		if resolved, err := requestResponseResolution(ctx, cfg, model, ""); err == nil {
+			pricing = pricingFromResolution(resolved)
+			provider = resolved.Provider
+		}
+	}()
+
+	streamed, _, err := streamCompletion(ctx, client, model, system, input, func(string) {})
+	<-pricingDone
+	result.Provider = provider
+	if pricing.Known {
+		result.Pricing = &pricing
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ServedModel = streamed.Model.String()
+	result.Output = streamed.Text
+	result.LatencyMS = streamed.Latency.Milliseconds()
+	result.TTFTMS = streamed.TTFT.Milliseconds()
+	result.Usage = sessionUsage{
+		InputTokens:  streamed.Usage.InputTokens,
+		OutputTokens: streamed.Usage.OutputTokens,
+		TotalTokens:  streamed.Usage.TotalTokens,
+	}
+	if pricing.Known {
+		cost := pricing.costCents(streamed.Usage.InputTokens, streamed.Usage.OutputTokens)
+		result.CostCents = &cost
+	}
+	return result
+}
+
+// compareWidth picks the total output width: --width, then $COLUMNS, then 120.
+func compareWidth(flagWidth int) int {
+	if flagWidth > 0 {
+		return flagWidth
+	}
+	if columns, err := strconv.Atoi(strings.TrimSpace(os.Getenv("COLUMNS"))); err == nil && columns > 0 {
+		return columns
+	}
+	return 120
+}
+
+// minCompareColumn is the narrowest answer column worth printing side by
+// side; narrower layouts print the answers one after another.
+const minCompareColumn = 24
+
+func printCompareResults(w io.Writer, results []compareResult, width int) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "MODEL\tPROVIDER\tLATENCY\tTTFT\tIN\tOUT\tCOST")
+	for _, r := range results {
+		cost := "-"
+		if r.CostCents != nil {
+			cost = formatCents(*r.CostCents)
+		}
+		if r.Error != "" {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\terror\t-\t-\t-\t-\n", r.Model, firstNonEmpty(r.Provider, "-"))
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			firstNonEmpty(r.ServedModel, r.Model),
+			firstNonEmpty(r.Provider, "-"),
+			(time.Duration(r.LatencyMS) * time.Millisecond).String(),
+			(time.Duration(r.TTFTMS) * time.Millisecond).String(),
+			r.Usage.InputTokens,
+			r.Usage.OutputTokens,
+			cost,
+		)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(w)
+
+	headers := make([]string, len(results))
+	bodies := make([]string, len(results))
+	for i, r := range results {
+		headers[i] = r.Model
+		bodies[i] = r.Output
+		if r.Error != "" {
+			bodies[i] = "error: " + r.Error
+		}
+	}
+	printColumns(w, headers, bodies, width)
+}
+
+// printColumns lays the bodies out in word-wrapped columns of equal width, or
+// one after another when the width cannot fit them.
+func printColumns(w io.Writer, headers, bodies []string, width int) {
+	const gutter = " │ "
+	n := len(bodies)
+	column := (width - (n-1)*utf8.RuneCountInString(gutter)) / n
+	if column < minCompareColumn {
+		for i, body := range bodies {
+			_, _ = fmt.Fprintf(w, "=== %s ===\n%s\n\n", headers[i], strings.TrimSpace(body))
+		}
+		return
+	}
+
+	wrapped := make([][]string, n)
+	rows := 0
+	for i, body := range bodies {
+		wrapped[i] = append([]string{truncateRunes(headers[i], column), strings.Repeat("─", column)}, wrapText(body, column)...)
+		rows = max(rows, len(wrapped[i]))
+	}
+	for row := range rows {
+		cells := make([]string, n)
+		for i := range wrapped {
+			cell := ""
+			if row < len(wrapped[i]) {
+				cell = wrapped[i][row]
+			}
+			cells[i] = cell + strings.Repeat(" ", column-utf8.RuneCountInString(cell))
+		}
+		_, _ = fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, gutter), " "))
+	}
+}
+
+// wrapText word-wraps text to lines of at most width runes, keeping blank
+// lines and hard-splitting words longer than a line.
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.TrimSpace(text), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			for utf8.RuneCountInString(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				runes := []rune(word)
+				lines = append(lines, string(runes[:width]))
+				word = string(runes[width:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func truncateRunes(value string, width int) string {
+	runes := []rune(value)
+	if len(runes) <= width {
+		return value
+	}
+	return string(runes[:width-1]) + "…"
+}
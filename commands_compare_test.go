@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	got := wrapText("the quick brown fox\n\njumps over supercalifragilistic", 10)
+	want := []string{"the quick", "brown fox", "", "jumps over", "supercalif", "ragilistic"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrapText = %q, want %q", got, want)
+	}
+}
+
+func TestPrintColumnsSideBySide(t *testing.T) {
+	var out bytes.Buffer
+	printColumns(&out, []string{"model-a", "model-b"}, []string{"short answer", "a somewhat longer answer here"}, 63)
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d:\n%s", len(lines), out.String())
+	}
+	if !strings.HasPrefix(lines[0], "model-a") || !strings.Contains(lines[0], "│ model-b") {
+		t.Fatalf("unexpected header line %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "short answer") || !strings.HasSuffix(lines[2], "a somewhat longer answer here") {
+		t.Fatalf("unexpected body line %q", lines[2])
+	}
+}
+
+func TestPrintColumnsFallsBackToStacked(t *testing.T) {
+	var out bytes.Buffer
+	printColumns(&out, []string{"a", "b", "c"}, []string{"one", "two", "three"}, 40)
+	if got := out.String(); got != "=== a ===\none\n\n=== b ===\ntwo\n\n=== c ===\nthree\n\n" {
+		t.Fatalf("unexpected stacked output %q", got)
+	}
+}
+
+func TestCompareWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "200")
+	if got := compareWidth(0); got != 200 {
+		t.Fatalf("expected $COLUMNS, got %d", got)
+	}
+	if got := compareWidth(80); got != 80 {
+		t.Fatalf("expected flag width, got %d", got)
+	}
+	t.Setenv("COLUMNS", "")
+	if got := compareWidth(0); got != 120 {
+		t.Fatalf("expected default width, got %d", got)
+	}
+}
@@ -45,10 +45,14 @@ func newConfigShowCmd() *cobra.Command {
 				{Key: "base_url", Value: profileCfg.BaseURL},
 				{Key: "project_id", Value: profileCfg.ProjectID},
 				{Key: "model", Value: profileCfg.Model},
+				{Key: "fallbacks", Value: strings.Join(profileCfg.Fallbacks, ", ")},
 				{Key: "output", Value: profileCfg.Output},
 				{Key: "allow_all", Value: fmt.Sprintf("%v", profileCfg.AllowAll)},
 				{Key: "allow", Value: strings.Join(profileCfg.Allow, ", ")},
 				{Key: "trace", Value: fmt.Sprintf("%v", profileCfg.Trace)},
+				{Key: "approve", Value: profileCfg.Approve},
+				{Key: "allow_url_attachments", Value: fmt.Sprintf("%v", profileCfg.AllowURLAttachments)},
+				{Key: "ignore_dirs", Value: strings.Join(profileCfg.IgnoreDirs, ", ")},
 			}
 			printKeyValueTable(pairs)
 			return nil
@@ -64,10 +68,14 @@ func newConfigSetCmd() *cobra.Command {
 	var baseURL string
 	var projectID string
 	var model string
+	var fallbacks []string
 	var output string
 	var allowAll bool
 	var allow []string
 	var trace bool
+	var approve string
+	var allowURLAttachments bool
+	var ignoreDirs []string
 
 	cmd := &cobra.Command{
 		Use:   "set",
@@ -92,6 +100,9 @@ func newConfigSetCmd() *cobra.Command {
 			if cmd.Flags().Changed("model") {
 				profileCfg.Model = strings.TrimSpace(model)
 			}
+			if cmd.Flags().Changed("fallback-model") {
+				profileCfg.Fallbacks = splitCSVValues(fallbacks)
+			}
 			if cmd.Flags().Changed("output") {
 				clean := strings.ToLower(strings.TrimSpace(output))
 				switch clean {
@@ -110,6 +121,21 @@ func newConfigSetCmd() *cobra.Command {
 			if cmd.Flags().Changed("trace") {
 				profileCfg.Trace = trace
 			}
+			if cmd.Flags().Changed("approve") {
+				clean := strings.ToLower(strings.TrimSpace(approve))
+				switch clean {
+				case "", approveAsk:
+					profileCfg.Approve = clean
+				default:
+					return errors.New("approve must be ask or empty")
+				}
+			}
+			if cmd.Flags().Changed("allow-url-attachments") {
+				profileCfg.AllowURLAttachments = allowURLAttachments
+			}
+			if cmd.Flags().Changed("ignore-dir") {
+				profileCfg.IgnoreDirs = splitCSVValues(ignoreDirs)
+			}
 
 			if cfg.Profiles == nil {
 				cfg.Profiles = map[string]cliProfile{}
@@ -131,10 +157,14 @@ func newConfigSetCmd() *cobra.Command {
 	cmd.Flags().StringVar(&baseURL, "base-url", "", "Base URL")
 	cmd.Flags().StringVar(&projectID, "project", "", "Project ID")
 	cmd.Flags().StringVar(&model, "model", "", "Default model")
+	cmd.Flags().StringSliceVar(&fallbacks, "fallback-model", nil, "Models to try in order when the default model fails (comma-separated)")
 	cmd.Flags().StringVar(&output, "output", "", "Output format (json|table)")
 	cmd.Flags().BoolVar(&allowAll, "allow-all", false, "Allow all bash commands in 'do' command")
 	cmd.Flags().StringSliceVar(&allow, "allow", nil, "Allow bash command prefix in 'do' command (repeatable)")
 	cmd.Flags().BoolVar(&trace, "trace", false, "Show commands being executed in 'do' command")
+	cmd.Flags().StringVar(&approve, "approve", "", "Set to ask to confirm bash commands no allow rule covers in 'do' and 'agent loop' (empty to turn off)")
+	cmd.Flags().BoolVar(&allowURLAttachments, "allow-url-attachments", false, "Allow http(s) URLs as attachments")
+	cmd.Flags().StringSliceVar(&ignoreDirs, "ignore-dir", nil, "Directory name to skip in directory and glob attachments (repeatable)")
 	return cmd
 }
 
@@ -182,10 +182,102 @@ func newUsageCmd() *cobra.Command {
 		Use:   "usage",
 		Short: "Usage reporting",
 	}
-	cmd.AddCommand(newUsageAccountCmd())
+	cmd.AddCommand(newUsageAccountCmd(), newUsageLocalCmd())
 	return cmd
 }
 
+func newUsageLocalCmd() *cobra.Command {
+	var since string
+	var groupBy string
+
+	cmd := &cobra.Command{
+		Use:   "local",
+		Short: "Summarize usage recorded by this machine",
+		Long: `Summarize the local usage ledger (~/.local/share/mrl/usage.jsonl).
+
+mrl appends a record for every prompt, chat turn, batch row, image request,
+and do, agent loop and rlm run, with the command, model, profile, tokens,
+images, latency and estimated cost.
+
+Examples:
+  mrl usage local
+  mrl usage local --since 30d --group-by profile
+  mrl usage local --since 2026-01-01 --group-by command --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := runtimeConfigFrom(cmd)
+			if err != nil {
+				return err
+			}
+			sinceTime, err := parseUsageSince(since, time.Now())
+			if err != nil {
+				return err
+			}
+			records, err := readUsageRecords(sinceTime)
+			if err != nil {
+				return err
+			}
+			groups, total, err := groupUsageRecords(records, groupBy)
+			if err != nil {
+				return err
+			}
+			if cfg.Output == outputFormatJSON {
+				payload := map[string]any{
+					"group_by": strings.ToLower(strings.TrimSpace(groupBy)),
+					"groups":   groups,
+					"total":    total,
+				}
+				if !sinceTime.IsZero() {
+					payload["since"] = sinceTime.UTC().Format(time.RFC3339)
+				}
+				printJSON(payload)
+				return nil
+			}
+			printUsageGroups(strings.ToUpper(strings.TrimSpace(groupBy)), groups, total)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&since, "since", "7d", "Lookback window (e.g. 12h, 7d, 2w) or start date (2006-01-02); empty for all time")
+	cmd.Flags().StringVar(&groupBy, "group-by", "model", "Group by model, profile, or command")
+	return cmd
+}
+
+func printUsageGroups(header string, groups []usageGroup, total usageGroup) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "%s\tRECORDS\tCALLS\tINPUT\tOUTPUT\tTOTAL\tIMAGES\tCOST\tAVG_LATENCY\n", header)
+	for _, group := range append(groups, total) {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			group.Key,
+			group.Records,
+			group.Calls,
+			group.InputTokens,
+			group.OutputTokens,
+			group.TotalTokens,
+			group.Images,
+			formatUsageGroupCost(group),
+			averageLatency(group),
+		)
+	}
+	_ = w.Flush()
+}
+
+func formatUsageGroupCost(group usageGroup) string {
+	if group.CostComplete {
+		return formatCents(group.CostCents)
+	}
+	if group.CostCents == 0 {
+		return "-"
+	}
+	return formatCents(group.CostCents) + "+"
+}
+
+func averageLatency(group usageGroup) string {
+	if group.Records == 0 {
+		return "-"
+	}
+	return (time.Duration(group.LatencyMS/int64(group.Records)) * time.Millisecond).String()
+}
+
 func newUsageAccountCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "account",
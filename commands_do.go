@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -13,13 +14,53 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Limits of the bash tool in `mrl do`.
+const (
+	doBashTimeout        = 30 * time.Second
+	doBashMaxOutputBytes = 64_000
+)
+
+// doOptions configures one `mrl do` run.
+type doOptions struct {
+	model     string
+	system    string
+	allow     []string
+	allowAll  bool
+	maxTurns  int
+	trace     bool
+	showUsage bool
+	maxCost   float64
+	// fallbacks are tried in order when model fails; runDo resolves them
+	// from --fallback-model or the profile. Each model gets its own
+	// attemptTimeout per call.
+	fallbacks      []string
+	attemptTimeout time.Duration
+	// approve asks before running commands no allow rule covers; "always"
+	// answers are saved to the allow list of profile.
+	approve bool
+	profile string
+	sandbox sandboxOptions
+	// dryRun records the proposed commands instead of running them, and
+	// savePlan writes them to a file for apply to run later.
+	dryRun   bool
+	savePlan string
+	apply    string
+	// planner collects the commands of a dry run.
+	planner *doPlanner
+	// noCheckpoint skips the snapshots `mrl agent undo` restores.
+	noCheckpoint bool
+}
+
+// doOutcome is what a finished (or failed) do loop reports back.
+type doOutcome struct {
+	usage sdk.AgentUsage
+	// model is the model that served the last call, which differs from the
+	// requested model after a fallback.
+	model string
+}
+
 func newDoCmd() *cobra.Command {
-	var model string
-	var system string
-	var allowAll bool
-	var allow []string
-	var maxTurns int
-	var trace bool
+	var opts doOptions
 
 	cmd := &cobra.Command{
 		Use:   "do <task>",
@@ -31,50 +72,117 @@ Examples:
   mrl do "list all TODO comments in this repo"
   mrl do "run tests and fix any failures" --allow-all
   mrl do "show git status" --allow "git "
+  mrl do "tidy imports" --allow "go " --usage --max-cost 25
+  mrl do "summarize the changelog" --allow "cat " --fallback-model claude-sonnet-5
+  mrl do --editor --allow "go "
+  mrl do "run the test suite" --allow-all --sandbox --sandbox-memory 2G
+  mrl do "upgrade the go toolchain" --dry-run --save-plan plan.json
+  mrl do --apply plan.json --allow "go " --allow "git "
 
 By default, no commands are allowed. Use --allow to whitelist
-command prefixes, or --allow-all to permit any command.
+command prefixes, or --allow-all to permit any command. With --approve,
+commands no prefix covers are shown for confirmation before they run.
+With --sandbox, commands run in a bubblewrap jail: the host is read-only,
+the working directory is a copy-on-write view whose changes are discarded
+at the end, and there is no network unless --sandbox-network is set.
+Otherwise the working directory is snapshotted before each turn that runs
+commands, and mrl agent undo restores it.
+
+With --dry-run, commands are recorded instead of run and the proposed
+sequence is printed at the end; --save-plan writes it to a file that
+--apply runs later, under the usual permissions and without the model.
 
 Permissions can also be set in config:
   mrl config set --allow-all
-  mrl config set --allow "git " --allow "npm "`,
-		Args: cobra.MinimumNArgs(1),
+  mrl config set --allow "git " --allow "npm "
+  mrl config set --approve ask`,
+		Args: argsOrEditor(func(cmd *cobra.Command, args []string) error {
+			if opts.apply != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		}),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDo(cmd, args, model, system, allow, allowAll, maxTurns, trace)
+			args, err := composeArgs(cmd, args)
+			if err != nil {
+				return err
+			}
+			return runDo(cmd, args, opts)
 		},
 	}
 
-	cmd.Flags().StringVar(&model, "model", "", "Model ID (overrides profile default)")
-	cmd.Flags().StringVar(&system, "system", "", "System prompt")
-	cmd.Flags().StringSliceVar(&allow, "allow", nil, "Allow bash command prefix (repeatable)")
-	cmd.Flags().BoolVar(&allowAll, "allow-all", false, "Allow all bash commands (use with care)")
-	cmd.Flags().IntVar(&maxTurns, "max-turns", 50, "Max tool loop turns")
-	cmd.Flags().BoolVar(&trace, "trace", false, "Print tool calls as they execute")
+	cmd.Flags().StringVar(&opts.model, "model", "", "Model ID (overrides profile default)")
+	cmd.Flags().StringSliceVar(&opts.fallbacks, "fallback-model", nil, "Models to try in order when the model fails (comma-separated)")
+	cmd.Flags().StringVar(&opts.system, "system", "", "System prompt")
+	cmd.Flags().StringSliceVar(&opts.allow, "allow", nil, "Allow bash command prefix (repeatable)")
+	cmd.Flags().BoolVar(&opts.allowAll, "allow-all", false, "Allow all bash commands (use with care)")
+	cmd.Flags().BoolVar(&opts.approve, "approve", false, "Ask before running commands no --allow prefix covers")
+	cmd.Flags().IntVar(&opts.maxTurns, "max-turns", 50, "Max tool loop turns")
+	cmd.Flags().BoolVar(&opts.trace, "trace", false, "Print tool calls as they execute")
+	cmd.Flags().BoolVar(&opts.showUsage, "usage", false, "Show token usage and estimated cost when done")
+	cmd.Flags().Float64Var(&opts.maxCost, "max-cost", 0, "Stop before the estimated cost exceeds this many cents")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Record the proposed commands instead of running them")
+	cmd.Flags().StringVar(&opts.savePlan, "save-plan", "", "Write the --dry-run plan to this JSON file")
+	cmd.Flags().StringVar(&opts.apply, "apply", "", "Run the commands of a saved plan instead of a task")
+	cmd.Flags().BoolVar(&opts.noCheckpoint, "no-checkpoint", false, "Don't snapshot the working directory before each turn (disables mrl agent undo)")
+	addSandboxFlags(cmd, &opts.sandbox)
+	addEditorFlag(cmd)
 
 	return cmd
 }
 
-func runDo(cmd *cobra.Command, args []string, modelFlag, system string, allowFlag []string, allowAllFlag bool, maxTurns int, traceFlag bool) error {
+func runDo(cmd *cobra.Command, args []string, opts doOptions) error {
 	cfg, err := runtimeConfigFrom(cmd)
 	if err != nil {
 		return err
 	}
 
-	model := resolveModel(modelFlag, cfg)
-	if model == "" {
-		return errors.New("model is required (set via --model, MODELRELAY_MODEL, or mrl config set --model)")
+	switch {
+	case opts.apply != "" && (opts.dryRun || editorRequested(cmd)):
+		return errors.New("--apply cannot be combined with --dry-run or --editor")
+	case opts.savePlan != "" && !opts.dryRun:
+		return errors.New("--save-plan requires --dry-run")
 	}
 
 	// Merge CLI flags with config (CLI takes precedence)
-	allowAll := allowAllFlag || cfg.AllowAll
-	allow := allowFlag
-	if len(allow) == 0 {
-		allow = cfg.Allow
+	opts.allowAll = opts.allowAll || cfg.AllowAll
+	opts.trace = opts.trace || cfg.Trace
+	opts.approve = (opts.approve || cfg.Approve == approveAsk) && !opts.allowAll && !opts.dryRun
+	switch {
+	case opts.approve:
+		// Prefixes saved from earlier "always" answers count as rules.
+		for _, prefix := range cfg.Allow {
+			if !containsString(opts.allow, prefix) {
+				opts.allow = append(opts.allow, prefix)
+			}
+		}
+	case len(opts.allow) == 0:
+		opts.allow = cfg.Allow
+	}
+	opts.profile = cfg.Profile
+
+	// A dry run executes nothing, so it needs no permissions.
+	if !opts.allowAll && len(opts.allow) == 0 && !opts.approve && !opts.dryRun {
+		return errors.New("bash permissions required: use --allow <prefix>, --allow-all, --approve, or set allow_all in config")
+	}
+
+	if opts.apply != "" {
+		return runDoApply(cfg, opts)
+	}
+
+	opts.model = resolveModel(opts.model, cfg)
+	if opts.model == "" {
+		return errors.New("model is required (set via --model, MODELRELAY_MODEL, or mrl config set --model)")
+	}
+	opts.fallbacks = modelChain(opts.model, opts.fallbacks, cfg)[1:]
+	opts.attemptTimeout = cfg.Timeout
+	if opts.dryRun {
+		opts.planner = &doPlanner{}
 	}
-	trace := traceFlag || cfg.Trace
 
-	if !allowAll && len(allow) == 0 {
-		return errors.New("bash permissions required: use --allow <prefix>, --allow-all, or set allow_all in config")
+	costs, err := newCostTracker(cfg, opts.maxCost)
+	if err != nil {
+		return err
 	}
 
 	client, err := newPromptClient(cfg)
@@ -84,40 +192,77 @@ func runDo(cmd *cobra.Command, args []string, modelFlag, system string, allowFla
 
 	prompt := strings.Join(args, " ")
 
-	ctx, cancel := contextWithTimeout(cfg.Timeout)
+	ctx, cancel := contextWithTimeout(fallbackTimeout(cfg.Timeout, append([]string{opts.model}, opts.fallbacks...)))
 	defer cancel()
 
-	return runDoLoop(ctx, client, model, system, prompt, allow, allowAll, maxTurns, trace)
+	start := time.Now()
+	outcome, err := runDoLoop(ctx, client, prompt, opts, costs)
+	usage := outcome.usage
+	recordUsage(ledgerCommandName(cmd), cfg, firstNonEmpty(outcome.model, opts.model), usage.LLMCalls, agentUsageTotals(usage), time.Since(start), costs.jsonCents())
+	if opts.planner != nil {
+		// A plan cut short by an error is still worth reviewing.
+		plan := opts.planner.plan(prompt, firstNonEmpty(outcome.model, opts.model), time.Now())
+		savedTo := ""
+		if opts.savePlan != "" && len(plan.Steps) > 0 {
+			if saveErr := saveDoPlan(opts.savePlan, plan); saveErr != nil {
+				return errors.Join(err, fmt.Errorf("save plan: %w", saveErr))
+			}
+			savedTo = opts.savePlan
+		}
+		printDoPlan(os.Stdout, plan, savedTo)
+	}
+	return err
 }
 
-func runDoLoop(ctx context.Context, client *sdk.Client, model, system, prompt string, allow []string, allowAll bool, maxTurns int, trace bool) error {
-	// Build bash tool options
-	bashOpts := []sdk.LocalBashOption{
-		sdk.WithLocalBashTimeout(30 * time.Second),
-		sdk.WithLocalBashMaxOutputBytes(64_000),
-		sdk.WithLocalBashInheritEnv(),
+// runDoApply runs a plan saved by --dry-run --save-plan.
+func runDoApply(cfg runtimeConfig, opts doOptions) error {
+	plan, err := loadDoPlan(opts.apply)
+	if err != nil {
+		return err
 	}
-	if allowAll {
-		bashOpts = append(bashOpts, sdk.WithLocalBashAllowAllCommands())
+	ctx, cancel := contextWithTimeout(cfg.Timeout)
+	defer cancel()
+	registry, cleanup, err := newDoRegistry(ctx, opts)
+	if err != nil {
+		return err
 	}
-	if len(allow) > 0 {
-		rules := make([]sdk.BashCommandRule, len(allow))
-		for i, prefix := range allow {
-			rules[i] = sdk.BashCommandPrefix(prefix)
-		}
-		bashOpts = append(bashOpts, sdk.WithLocalBashAllowRules(rules...))
+	defer cleanup()
+	// One checkpoint covers the whole plan, so `mrl agent undo` reverts it.
+	if !opts.noCheckpoint && !opts.sandbox.enabled {
+		newRunCheckpointer("do", ".").beforeTools(0)
 	}
+	return applyDoPlan(registry, plan)
+}
 
+// runDoLoop runs the tool loop and returns the usage accumulated so far, also
+// when it fails part way. When the current model fails with a provider error
+// the call is retried on the next fallback, which then serves the rest of
+// the run.
+func runDoLoop(ctx context.Context, client *sdk.Client, prompt string, opts doOptions, costs *costTracker) (doOutcome, error) {
 	// Create tool registry and definitions
-	registry := sdk.NewToolRegistry()
-	sdk.NewLocalBashToolPack(".", bashOpts...).RegisterInto(registry)
+	var registry *sdk.ToolRegistry
+	if opts.planner != nil {
+		registry = opts.planner.registry()
+	} else {
+		var cleanup func()
+		var err error
+		if registry, cleanup, err = newDoRegistry(ctx, opts); err != nil {
+			return doOutcome{}, err
+		}
+		defer cleanup()
+	}
+	// The sandbox and dry runs leave the working directory alone.
+	var checkpoints *runCheckpointer
+	if !opts.noCheckpoint && !opts.sandbox.enabled && opts.planner == nil {
+		checkpoints = newRunCheckpointer("do", ".")
+	}
 
 	bashTool := sdk.MustFunctionToolFromType[bashToolArgs](sdk.ToolNameBash, "Execute a shell command")
 	tools := []llm.Tool{bashTool}
 
 	// Build initial messages
 	var messages []llm.InputItem
-	sysPrompt := system
+	sysPrompt := opts.system
 	if sysPrompt == "" {
 		sysPrompt = `You are an agent that completes tasks by executing shell commands. Use the bash tool to run commands. Do not explain how to do things - actually do them. Be concise - when done, just say what you did in one short sentence.
 
@@ -126,25 +271,41 @@ When writing git commits:
 - Use conventional commit format when appropriate (feat:, fix:, docs:, refactor:, etc.)
 - Look at the actual diff to understand what changed before writing the message`
 	}
+	if opts.planner != nil {
+		sysPrompt += "\n\n" + dryRunInstructions
+	}
 	messages = append(messages, llm.NewSystemText(sysPrompt), llm.NewUserText(prompt))
 
-	modelID := sdk.NewModelID(model)
-	var usage sdk.AgentUsage
+	chain := append([]string{opts.model}, opts.fallbacks...)
+	var out doOutcome
+	usage := &out.usage
 
-	for range maxTurns {
-		req, callOpts, err := client.Responses.New().
-			Model(modelID).
-			Input(messages).
-			Tools(tools).
-			Build()
-		if err != nil {
-			return err
+	for turn := range opts.maxTurns {
+		if turn == 0 {
+			if err := costs.checkInput(ctx, opts.model, estimateInputTokens(messages)); err != nil {
+				return out, err
+			}
+		} else if err := costs.checkNext(); err != nil {
+			printDoUsage(out, costs, opts.showUsage)
+			return out, err
 		}
 
-		resp, err := client.Responses.Create(ctx, req, callOpts...)
+		resp, served, err := callWithFallback(ctx, chain, opts.attemptTimeout, func(ctx context.Context, model string) (*sdk.Response, error) {
+			req, callOpts, buildErr := client.Responses.New().
+				Model(sdk.NewModelID(model)).
+				Input(messages).
+				Tools(tools).
+				Build()
+			if buildErr != nil {
+				return nil, noFallback(buildErr)
+			}
+			return client.Responses.Create(ctx, req, callOpts...)
+		})
 		if err != nil {
-			return err
+			return out, err
 		}
+		chain = chain[served:]
+		out.model = firstNonEmpty(resp.Model.String(), chain[0])
 
 		usage.LLMCalls++
 		usage.InputTokens += resp.Usage.InputTokens
@@ -153,14 +314,20 @@ When writing git commits:
 		usage.ReasoningTokens += resp.Usage.ReasoningTokens
 		usage.CacheReadInputTokens += resp.Usage.CacheReadInputTokens
 		usage.CacheWriteInputTokens += resp.Usage.CacheWriteInputTokens
+		costs.record(ctx, out.model, resp.Usage)
 
 		toolCalls := resp.ToolCalls()
 		if len(toolCalls) == 0 {
 			// Done - print final response and exit
 			if text := resp.AssistantText(); text != "" {
 				fmt.Println(text)
+				if opts.planner != nil {
+					opts.planner.setSummary(text)
+				}
 			}
-			return nil
+			printDoUsage(out, costs, opts.showUsage)
+			costs.warnIfOverBudget()
+			return out, nil
 		}
 
 		usage.ToolCalls += len(toolCalls)
@@ -169,7 +336,7 @@ When writing git commits:
 		messages = append(messages, sdk.AssistantMessageWithToolCalls(resp.AssistantText(), toolCalls))
 
 		// Print tool calls before execution
-		if trace {
+		if opts.trace {
 			for _, tc := range toolCalls {
 				if tc.Function != nil {
 					var args bashToolArgs
@@ -183,20 +350,21 @@ When writing git commits:
 		}
 
 		// Execute tools and add results
+		checkpoints.beforeTools(turn)
 		results := registry.ExecuteAll(toolCalls)
 		messages = append(messages, registry.ResultsToMessages(results)...)
 
-		// Print tool execution output
+		// Print tool execution output; a dry run has none
+		if opts.planner != nil {
+			continue
+		}
 		for _, result := range results {
 			if result.Result != nil {
 				switch r := result.Result.(type) {
 				case sdk.BashResult:
-					if r.Output != "" {
-						fmt.Printf("\033[2m%s\033[0m\n", r.Output)
-					}
-					if r.Error != "" {
-						fmt.Printf("\033[31merror: %s\033[0m\n", r.Error)
-					}
+					printBashResult(r)
+				case bashApprovalResult:
+					printBashResult(r.BashResult)
 				case string:
 					if r != "" {
 						fmt.Println(r)
@@ -209,5 +377,82 @@ When writing git commits:
 		}
 	}
 
-	return fmt.Errorf("max turns (%d) reached without completion", maxTurns)
+	printDoUsage(out, costs, opts.showUsage)
+	return out, fmt.Errorf("max turns (%d) reached without completion", opts.maxTurns)
+}
+
+// newDoRegistry builds the bash tool for a do run or --apply from the allow,
+// approve and sandbox options. cleanup releases the sandbox.
+func newDoRegistry(ctx context.Context, opts doOptions) (*sdk.ToolRegistry, func(), error) {
+	// Build bash tool options
+	bashOpts := []sdk.LocalBashOption{
+		sdk.WithLocalBashTimeout(doBashTimeout),
+		sdk.WithLocalBashMaxOutputBytes(doBashMaxOutputBytes),
+		sdk.WithLocalBashInheritEnv(),
+	}
+	rules := make([]sdk.BashCommandRule, len(opts.allow))
+	for i, prefix := range opts.allow {
+		rules[i] = sdk.BashCommandPrefix(prefix)
+	}
+
+	cleanup := func() {}
+	var jailed *sandboxBash
+	if opts.sandbox.enabled {
+		box, err := newToolSandbox(ctx, ".", opts.sandbox)
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() {
+			if err := box.close(os.Stderr); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: sandbox cleanup: %v\n", err)
+			}
+		}
+		jailed = &sandboxBash{box: box, allowAll: opts.allowAll, allow: rules, timeout: doBashTimeout, maxOutput: doBashMaxOutputBytes}
+	}
+
+	registry := sdk.NewToolRegistry()
+	if opts.approve {
+		runner := approveRunner(".", bashOpts, nil)
+		if jailed != nil {
+			runner = (&sandboxBash{box: jailed.box, allowAll: true, timeout: doBashTimeout, maxOutput: doBashMaxOutputBytes}).registry()
+		}
+		approver := newBashApprover(rules, nil, runner, func(prefix string) error {
+			return rememberAllowPrefix(opts.profile, prefix)
+		})
+		registry.Register(sdk.ToolNameBash, approver.handle)
+	} else if jailed != nil {
+		registry.Register(sdk.ToolNameBash, jailed.handle)
+	} else {
+		if opts.allowAll {
+			bashOpts = append(bashOpts, sdk.WithLocalBashAllowAllCommands())
+		}
+		if len(rules) > 0 {
+			bashOpts = append(bashOpts, sdk.WithLocalBashAllowRules(rules...))
+		}
+		sdk.NewLocalBashToolPack(".", bashOpts...).RegisterInto(registry)
+	}
+
+	return registry, cleanup, nil
+}
+
+func printBashResult(r sdk.BashResult) {
+	if r.Output != "" {
+		fmt.Printf("\033[2m%s\033[0m\n", r.Output)
+	}
+	if r.Error != "" {
+		fmt.Printf("\033[31merror: %s\033[0m\n", r.Error)
+	}
+}
+
+func printDoUsage(out doOutcome, costs *costTracker, show bool) {
+	if !show {
+		return
+	}
+	fmt.Printf("\nUsage: %d LLM calls | %d tool calls | %d tokens | Cost: %s | Model: %s\n",
+		out.usage.LLMCalls,
+		out.usage.ToolCalls,
+		out.usage.TotalTokens,
+		costs.summary(),
+		out.model,
+	)
 }
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// imageCapability selects image models from the catalog when --model is
+	// not set, as in `mrl model list --capability image_generation`.
+	imageCapability = "image_generation"
+	// imageTimeout is the minimum request timeout for image calls unless
+	// --timeout is given, since generation routinely exceeds the default.
+	imageTimeout = 2 * time.Minute
+	// maxImageInputBytes caps each input image of mrl image edit.
+	maxImageInputBytes = 20 << 20
+)
+
+type imageFlags struct {
+	model  string
+	size   string
+	count  int
+	output string
+	force  bool
+	inputs []string
+}
+
+type imageRequest struct {
+	Model          string       `json:"model"`
+	Prompt         string       `json:"prompt"`
+	Size           string       `json:"size,omitempty"`
+	N              int          `json:"n,omitempty"`
+	ResponseFormat string       `json:"response_format,omitempty"`
+	Images         []imageInput `json:"images,omitempty"`
+}
+
+// imageInput is a source image for an edit.
+type imageInput struct {
+	DataBase64 string `json:"data_base64"`
+	MimeType   string `json:"mime_type"`
+}
+
+type imageResponse struct {
+	ID    string      `json:"id,omitempty"`
+	Model string      `json:"model"`
+	Data  []imageData `json:"data"`
+	Usage imageUsage  `json:"usage"`
+}
+
+type imageData struct {
+	URL      string `json:"url,omitempty"`
+	B64JSON  string `json:"b64_json,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+}
+
+type imageUsage struct {
+	Images int64 `json:"images"`
+}
+
+// savedImage is one image written to disk.
+type savedImage struct {
+	Path     string `json:"path"`
+	MimeType string `json:"mime_type"`
+	Bytes    int    `json:"bytes"`
+}
+
+func newImageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "image",
+		Short: "Generate and edit images",
+	}
+	cmd.AddCommand(newImageGenerateCmd(), newImageEditCmd())
+	return cmd
+}
+
+func newImageGenerateCmd() *cobra.Command {
+	flags := &imageFlags{}
+	cmd := &cobra.Command{
+		Use:   "generate <prompt>",
+		Short: "Generate images from a prompt",
+		Long: `Generate images from a prompt and save them to files.
+
+Without --model, the first image generation model from the catalog is used
+(see mrl model list --capability image_generation).
+
+Examples:
+  mrl image generate "A lighthouse at dusk, flat illustration" -o lighthouse.png
+  mrl image generate "App icon, a paper plane" --size 1024x1024 -n 4 -o icon.png
+  mrl image generate "Hero banner" --model gpt-image-1 --json`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImage(cmd, strings.Join(args, " "), flags)
+		},
+	}
+	addImageFlags(cmd, flags)
+	return cmd
+}
+
+func newImageEditCmd() *cobra.Command {
+	flags := &imageFlags{}
+	cmd := &cobra.Command{
+		Use:   "edit <prompt>",
+		Short: "Edit images with a prompt",
+		Long: `Edit one or more input images with a prompt and save the results.
+
+Examples:
+  mrl image edit -a photo.png "Replace the sky with a sunset" -o sunset.png
+  mrl image edit -a logo.png -a palette.png "Recolor the logo with this palette"`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(flags.inputs) == 0 {
+				return errors.New("at least one input image is required (-a)")
+			}
+			return runImage(cmd, strings.Join(args, " "), flags)
+		},
+	}
+	addImageFlags(cmd, flags)
+	cmd.Flags().StringArrayVarP(&flags.inputs, "attachment", "a", nil, "Input image to edit (repeatable)")
+	return cmd
+}
+
+func addImageFlags(cmd *cobra.Command, flags *imageFlags) {
+	cmd.Flags().StringVar(&flags.model, "model", "", "Image model (default: first image_generation model in the catalog)")
+	cmd.Flags().StringVar(&flags.size, "size", "", "Image size, e.g. 1024x1024")
+	cmd.Flags().IntVarP(&flags.count, "count", "n", 1, "Number of images")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output file; numbered when --count > 1 (default: image-<timestamp>.<ext>)")
+	cmd.Flags().BoolVar(&flags.force, "force", false, "Overwrite existing output files")
+}
+
+func runImage(cmd *cobra.Command, prompt string, flags *imageFlags) error {
+	cfg, err := runtimeConfigFrom(cmd)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(cfg.APIKey) == "" {
+		return errors.New("api key required")
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return errors.New("prompt is required")
+	}
+	if flags.count < 1 {
+		return errors.New("--count must be at least 1")
+	}
+	inputs, err := readImageInputs(flags.inputs)
+	if err != nil {
+		return err
+	}
+
+	timeout := cfg.Timeout
+	if !cmd.Flags().Changed("timeout") {
+		timeout = max(timeout, imageTimeout)
+	}
+	ctx, cancel := contextWithTimeout(timeout)
+	defer cancel()
+
+	model := strings.TrimSpace(flags.model)
+	if model == "" {
+		model, err = defaultCapabilityModel(ctx, cfg, imageCapability)
+		if err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	var resp imageResponse
+	err = postModelRoute(ctx, cfg, "/images/generate", imageRequest{
+		Model:          model,
+		Prompt:         prompt,
+		Size:           strings.TrimSpace(flags.size),
+		N:              flags.count,
+		ResponseFormat: "b64_json",
+		Images:         inputs,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+	latency := time.Since(start)
+	servedModel := firstNonEmpty(resp.Model, model)
+	images := resp.Usage.Images
+	if images == 0 {
+		images = int64(len(resp.Data))
+	}
+	recordImageUsage(ledgerCommandName(cmd), cfg, servedModel, images, latency)
+
+	saved, err := saveImages(ctx, resp.Data, flags.output, flags.force, start)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output == outputFormatJSON {
+		printJSON(map[string]any{
+			"id":     resp.ID,
+			"model":  servedModel,
+			"images": saved,
+			"usage":  imageUsage{Images: images},
+		})
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FILE\tMIME\tBYTES")
+	for _, image := range saved {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\n", image.Path, image.MimeType, image.Bytes)
+	}
+	_ = w.Flush()
+	fmt.Fprintf(os.Stderr, "Model: %s | Images: %d | Latency: %s\n", servedModel, images, latency.Round(time.Millisecond))
+	return nil
+}
+
+func readImageInputs(paths []string) ([]imageInput, error) {
+	inputs := make([]imageInput, 0, len(paths))
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() > maxImageInputBytes {
+			return nil, fmt.Errorf("%s is larger than the %d MB input image limit", path, maxImageInputBytes>>20)
+		}
+		data, err := os.ReadFile(path) //nolint:gosec // input images are explicitly selected by the CLI user
+		if err != nil {
+			return nil, err
+		}
+		mimeType := detectMimeType(path, data)
+		if !strings.HasPrefix(mimeType, "image/") {
+			return nil, fmt.Errorf("%s is not an image (%s)", path, mimeType)
+		}
+		inputs = append(inputs, imageInput{DataBase64: base64.StdEncoding.EncodeToString(data), MimeType: mimeType})
+	}
+	return inputs, nil
+}
+
+// saveImages writes the returned images, downloading any returned by URL.
+// One image goes to output as given; several are numbered (out-1.png, ...).
+// A missing extension is taken from the image type.
+func saveImages(ctx context.Context, images []imageData, output string, force bool, now time.Time) ([]savedImage, error) {
+	if len(images) == 0 {
+		return nil, errors.New("no images returned")
+	}
+	if strings.TrimSpace(output) == "" {
+		output = "image-" + now.Format("20060102-150405")
+	}
+	saved := make([]savedImage, 0, len(images))
+	for i, image := range images {
+		data, mimeType, err := imageBytes(ctx, image)
+		if err != nil {
+			return saved, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		path := imageOutputPath(output, i, len(images), mimeType)
+		if !force {
+			if _, err := os.Stat(path); err == nil {
+				return saved, fmt.Errorf("%s already exists (use --force to overwrite)", path)
+			}
+		}
+		if dir := filepath.Dir(path); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec // output directory is chosen by the CLI user
+				return saved, err
+			}
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec // generated images are meant to be shared
+			return saved, err
+		}
+		saved = append(saved, savedImage{Path: path, MimeType: mimeType, Bytes: len(data)})
+	}
+	return saved, nil
+}
+
+func imageBytes(ctx context.Context, image imageData) ([]byte, string, error) {
+	var data []byte
+	switch {
+	case image.B64JSON != "":
+		decoded, err := base64.StdEncoding.DecodeString(image.B64JSON)
+		if err != nil {
+			return nil, "", fmt.Errorf("decode image: %w", err)
+		}
+		data = decoded
+	case image.URL != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, image.URL, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		resp, err := apiHTTPClient.Do(req)
+		if err != nil {
+			return nil, "", fmt.Errorf("download image: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("download image: %s", resp.Status)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, urlAttachmentMaxBytes+1))
+		if err != nil {
+			return nil, "", fmt.Errorf("download image: %w", err)
+		}
+		if len(data) > urlAttachmentMaxBytes {
+			return nil, "", fmt.Errorf("download image: exceeds the %d MB limit", urlAttachmentMaxBytes>>20)
+		}
+	default:
+		return nil, "", errors.New("response has neither data nor url")
+	}
+	mimeType := strings.TrimSpace(image.MimeType)
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return data, mimeType, nil
+}
+
+func imageOutputPath(output string, index, count int, mimeType string) string {
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	if ext == "" {
+		ext = ".png"
+		if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+			ext = preferredImageExtension(exts)
+		}
+	}
+	if count > 1 {
+		base = fmt.Sprintf("%s-%d", base, index+1)
+	}
+	return base + ext
+}
+
+// preferredImageExtension avoids rare aliases such as .jfif for JPEG.
+func preferredImageExtension(exts []string) string {
+	for _, preferred := range []string{".png", ".jpg", ".webp", ".gif"} {
+		for _, ext := range exts {
+			if ext == preferred {
+				return ext
+			}
+		}
+	}
+	return exts[0]
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestSaveImagesNamesAndOverwrite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(testPNG)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	images := []imageData{
+		{B64JSON: base64.StdEncoding.EncodeToString(testPNG), MimeType: "image/png"},
+		{URL: server.URL + "/second.png"},
+	}
+	saved, err := saveImages(context.Background(), images, filepath.Join(dir, "out"), false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 2 || saved[0].Path != filepath.Join(dir, "out-1.png") || saved[1].Path != filepath.Join(dir, "out-2.png") {
+		t.Fatalf("saved = %+v", saved)
+	}
+	for _, image := range saved {
+		data, err := os.ReadFile(image.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, testPNG) || image.MimeType != "image/png" {
+			t.Fatalf("%s: %q (%s)", image.Path, data, image.MimeType)
+		}
+	}
+
+	single := []imageData{{B64JSON: base64.StdEncoding.EncodeToString(testPNG)}}
+	if _, err := saveImages(context.Background(), single, filepath.Join(dir, "out-1.png"), false, time.Now()); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("expected overwrite refusal, got %v", err)
+	}
+	if _, err := saveImages(context.Background(), single, filepath.Join(dir, "out-1.png"), true, time.Now()); err != nil {
+		t.Fatalf("force overwrite: %v", err)
+	}
+}
+
+func TestImageBytesRejectsOversizedDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		chunk := make([]byte, 1<<20)
+		for written := 0; written <= urlAttachmentMaxBytes; written += len(chunk) {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	_, _, err := imageBytes(context.Background(), imageData{URL: server.URL + "/huge.png"})
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("expected size limit error instead of a truncated image, got %v", err)
+	}
+}
+
+func TestImageOutputPath(t *testing.T) {
+	cases := []struct {
+		output     string
+		index, n   int
+		mime, want string
+	}{
+		{"cat.png", 0, 1, "image/png", "cat.png"},
+		{"cat", 0, 1, "image/jpeg", "cat.jpg"},
+		{"cat", 1, 3, "image/webp", "cat-2.webp"},
+		{"cat.jpeg", 2, 3, "image/png", "cat-3.jpeg"},
+	}
+	for _, tc := range cases {
+		if got := imageOutputPath(tc.output, tc.index, tc.n, tc.mime); got != tc.want {
+			t.Fatalf("imageOutputPath(%q, %d, %d, %q) = %q, want %q", tc.output, tc.index, tc.n, tc.mime, got, tc.want)
+		}
+	}
+}
+
+func TestDefaultCapabilityModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" || r.URL.Query().Get("capability") != imageCapability {
+			http.Error(w, "unexpected "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"models":[{"provider":"openai","model_id":"old-image","deprecated":true},{"provider":"openai","model_id":"gpt-image-1"}]}`))
+	}))
+	defer server.Close()
+
+	model, err := defaultCapabilityModel(context.Background(), runtimeConfig{BaseURL: server.URL}, imageCapability)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model != "gpt-image-1" {
+		t.Fatalf("model = %q", model)
+	}
+}
+
+func TestReadImageInputsRejectsNonImages(t *testing.T) {
+	dir := t.TempDir()
+	png := filepath.Join(dir, "in.png")
+	notes := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(png, testPNG, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(notes, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := readImageInputs([]string{png})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 1 || inputs[0].MimeType != "image/png" {
+		t.Fatalf("inputs = %+v", inputs)
+	}
+	if _, err := readImageInputs([]string{notes}); err == nil {
+		t.Fatal("expected an error for a text input")
+	}
+}
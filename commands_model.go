@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"text/tabwriter"
@@ -41,35 +43,11 @@ func newModelListCmd() *cobra.Command {
 			ctx, cancel := contextWithTimeout(cfg.Timeout)
 			defer cancel()
 
-			path := "/models"
-			query := []string{}
-			if strings.TrimSpace(provider) != "" {
-				query = append(query, "provider="+strings.TrimSpace(provider))
-			}
-			if strings.TrimSpace(capability) != "" {
-				query = append(query, "capability="+strings.TrimSpace(capability))
-			}
-			if len(query) > 0 {
-				path = path + "?" + strings.Join(query, "&")
-			}
-
-			var resp modelsResponse
-			if err := doJSON(ctx, cfg, authModeNone, http.MethodGet, path, nil, &resp); err != nil {
+			models, err := listModels(ctx, cfg, provider, capability, includeDeprecated)
+			if err != nil {
 				return err
 			}
 
-			models := resp.Models
-			if !includeDeprecated {
-				filtered := make([]generated.Model, 0, len(models))
-				for index := range models {
-					if models[index].Deprecated {
-						continue
-					}
-					filtered = append(filtered, models[index])
-				}
-				models = filtered
-			}
-
 			if cfg.Output == outputFormatJSON {
 				printJSON(modelsResponse{Models: models})
 				return nil
@@ -85,6 +63,51 @@ func newModelListCmd() *cobra.Command {
 	return cmd
 }
 
+// listModels fetches the model catalog, filtered by provider and capability
+// (e.g. text_generation, image_generation) when set.
+func listModels(ctx context.Context, cfg runtimeConfig, provider, capability string, includeDeprecated bool) ([]generated.Model, error) {
+	query := url.Values{}
+	if strings.TrimSpace(provider) != "" {
+		query.Set("provider", strings.TrimSpace(provider))
+	}
+	if strings.TrimSpace(capability) != "" {
+		query.Set("capability", strings.TrimSpace(capability))
+	}
+	path := "/models"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var resp modelsResponse
+	if err := doJSON(ctx, cfg, authModeNone, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	if includeDeprecated {
+		return resp.Models, nil
+	}
+	models := make([]generated.Model, 0, len(resp.Models))
+	for index := range resp.Models {
+		if resp.Models[index].Deprecated {
+			continue
+		}
+		models = append(models, resp.Models[index])
+	}
+	return models, nil
+}
+
+// defaultCapabilityModel picks the first non-deprecated model with the
+// capability from the catalog.
+func defaultCapabilityModel(ctx context.Context, cfg runtimeConfig, capability string) (string, error) {
+	models, err := listModels(ctx, cfg, "", capability, false)
+	if err != nil {
+		return "", fmt.Errorf("list %s models: %w", capability, err)
+	}
+	if len(models) == 0 {
+		return "", fmt.Errorf("no %s models available; pass --model", capability)
+	}
+	return string(models[0].ModelId), nil
+}
+
 func printModelsTable(models []generated.Model) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	_, _ = fmt.Fprintln(w, "PROVIDER\tMODEL\tDISPLAY_NAME\tCTX\tMAX_OUT\tDEPRECATED")
@@ -39,14 +39,18 @@ func newRLMCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "rlm <query>",
 		Short: "Run an RLM session (local Python by default; use --remote for hosted)",
-		Args:  cobra.MinimumNArgs(1),
+		Args:  argsOrEditor(cobra.MinimumNArgs(1)),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			args, err := composeArgs(cmd, args)
+			if err != nil {
+				return err
+			}
 			return runRLM(cmd, args, &flags)
 		},
 	}
 
 	cmd.Flags().StringVar(&flags.model, "model", "", "Model ID (overrides profile default)")
-	cmd.Flags().StringArrayVarP(&flags.attachments, "attachment", "a", nil, "Attach a local file (repeatable; use '-' for stdin)")
+	cmd.Flags().StringArrayVarP(&flags.attachments, "attachment", "a", nil, "Attach a file, directory or glob (repeatable; use '-' for stdin)")
 	cmd.Flags().StringVar(&flags.attachmentType, "attachment-type", "", "Override attachment MIME type (useful for stdin)")
 	cmd.Flags().BoolVar(&flags.attachStdin, "attach-stdin", false, "Attach stdin as a file")
 	cmd.Flags().IntVar(&flags.maxSubcalls, "max-subcalls", 50, "Max llm_query/llm_batch calls")
@@ -71,10 +75,18 @@ func newRLMCmd() *cobra.Command {
 	cmd.Flags().StringVar(&flags.dbName, "db-name", "db", "Sandbox name for the SQL data source (e.g. db.query(...))")
 	cmd.Flags().StringVar(&flags.sqlProfile, "sql-profile", "", "SQL profile ID for the read-only policy (default: permissive read-only policy)")
 	cmd.Flags().StringArrayVar(&flags.mcpConfigs, "mcp-config", nil, "Trusted remote MCP source config file (local/VPC mode; repeatable)")
+	cmd.Flags().StringVar(&flags.index, "index", "", "Document folder to mount as a searchable full-text index (BM25)")
+	cmd.Flags().StringVar(&flags.indexName, "index-name", "docs", "Sandbox name for the --index source (e.g. docs.search(...))")
+	cmd.Flags().BoolVar(&flags.indexEmbeddings, "index-embeddings", false, "Also embed --index chunks and fuse cosine similarity into the ranking")
+	cmd.Flags().StringVar(&flags.indexEmbeddingModel, "index-embedding-model", "", "Embedding model for --index-embeddings (default: first embedding model in the catalog)")
 	cmd.Flags().StringVar(&flags.defaultSource, "default-source", "", "Default generated-code data source when more than one is mounted")
 	cmd.Flags().Int64Var(&flags.subcallMaxOutputTokens, "subcall-max-output-tokens", 0, "Max output tokens per llm_query/llm_batch subcall (0 = server default, 2048)")
 	cmd.Flags().StringVar(&flags.subcallModel, "subcall-model", "", "Model for llm_query/llm_batch subcalls, e.g. a cheaper non-reasoning model (default: the root model)")
 	cmd.Flags().StringVar(&flags.subcallReasoningEffort, "subcall-reasoning-effort", "", "Reasoning effort for subcalls: none, minimal, low, medium, high, or xhigh (default: server default, none)")
+	cmd.Flags().Int64Var(&flags.maxContextTokens, "max-context-tokens", 0, "Token budget for text files attached from directories and globs (0 = no limit)")
+	cmd.Flags().BoolVar(&flags.showUsage, "usage", false, "Print token usage and estimated cost to stderr (local mode)")
+	cmd.Flags().Float64Var(&flags.maxCost, "max-cost", 0, "Stop model calls once the estimated cost would exceed this many cents (local mode)")
+	addEditorFlag(cmd)
 
 	return cmd
 }
@@ -95,6 +107,7 @@ type rlmFlags struct {
 	maxInlineBytes          int64
 	maxTotalBytes           int64
 	inlineTextMaxBytes      int64
+	maxContextTokens        int64
 	toolChoice              string
 	remote                  bool
 	stream                  bool
@@ -107,11 +120,17 @@ type rlmFlags struct {
 	dbName                  string
 	sqlProfile              string
 	mcpConfigs              []string
+	index                   string
+	indexName               string
+	indexEmbeddings         bool
+	indexEmbeddingModel     string
 	defaultSource           string
 	// Subcall cost controls (rlm-core#25); zero values mean server defaults.
 	subcallMaxOutputTokens int64
 	subcallModel           string
 	subcallReasoningEffort string
+	showUsage              bool
+	maxCost                float64
 }
 
 // Subcall cost defaults applied by the local subcall proxy when neither the
@@ -140,11 +159,16 @@ const (
 type rlmUsage struct {
 	mu    sync.Mutex
 	usage workflow.TokenUsage
+	calls int
+	// cost is optional; when set, every proxied model call is priced and
+	// checked against the --max-cost budget.
+	cost *costTracker
 }
 
 func (u *rlmUsage) add(usage sdk.Usage) {
 	u.mu.Lock()
 	defer u.mu.Unlock()
+	u.calls++
 	u.usage.InputTokens += usage.InputTokens
 	u.usage.OutputTokens += usage.OutputTokens
 	u.usage.TotalTokens += usage.TotalTokens
@@ -152,6 +176,22 @@ func (u *rlmUsage) add(usage sdk.Usage) {
 	u.usage.CacheWriteInputTokens += usage.CacheWriteInputTokens
 }
 
+// record adds the usage of one proxied model call and prices it.
+func (u *rlmUsage) record(ctx context.Context, model string, usage sdk.Usage) {
+	u.add(usage)
+	if u.cost != nil {
+		u.cost.record(ctx, model, usage)
+	}
+}
+
+// checkBudget reports whether another model call fits in the --max-cost budget.
+func (u *rlmUsage) checkBudget() error {
+	if u == nil {
+		return nil
+	}
+	return u.cost.checkNext()
+}
+
 func (u *rlmUsage) snapshot() workflow.TokenUsage {
 	u.mu.Lock()
 	defer u.mu.Unlock()
@@ -182,10 +222,29 @@ func runRLM(cmd *cobra.Command, args []string, flags *rlmFlags) error {
 		strings.TrimSpace(flags.attachmentType) == "" &&
 		!flags.attachStdin
 	attachStdin := flags.attachStdin || autoAttachStdin
+	if flags.maxContextTokens < 0 {
+		return errors.New("max-context-tokens must be >= 0")
+	}
 	attachmentPaths, err := resolveAttachmentInputs(flags.attachments, flags.attachmentType, attachStdin, stdinIsTTY)
 	if err != nil {
 		return err
 	}
+	attachmentPaths, err = fetchURLAttachments(cmd.Context(), cfg, attachmentPaths)
+	if err != nil {
+		return err
+	}
+	// Directories and globs become one attachment per text file, named by
+	// path so files with the same base name stay apart.
+	expanded, err := expandAttachmentPaths(attachmentPaths, cfg.IgnoreDirs)
+	if err != nil {
+		return err
+	}
+	contextFiles, report := budgetContextFiles(expanded.Files, flags.maxContextTokens)
+	warnContextPack(os.Stderr, expanded.Skipped, report)
+	attachmentPaths = expanded.Paths
+	for _, file := range contextFiles {
+		attachmentPaths = append(attachmentPaths, file.Path)
+	}
 
 	textInlineLimit := resolveRLMInlineTextLimit(flags.inlineTextMaxBytes, flags.maxInlineBytes)
 	files, cleanup, err := buildRLMFileAttachments(attachmentPaths, flags.attachmentType, os.Stdin, textInlineLimit)
@@ -195,6 +254,16 @@ func runRLM(cmd *cobra.Command, args []string, flags *rlmFlags) error {
 	if cleanup != nil {
 		defer cleanup()
 	}
+	for i, file := range contextFiles {
+		attachment := &files[len(expanded.Paths)+i]
+		attachment.Name = filepath.ToSlash(file.Path)
+		if file.Note != "" {
+			// Only the head fits the budget, so send it instead of the file.
+			attachment.Path = ""
+			attachment.Text = strings.TrimSuffix(string(file.Data), "\n") + "\n" + file.Note + "\n"
+			attachment.Size = int64(len(attachment.Text))
+		}
+	}
 
 	if flags.remote && flags.relaySession {
 		return errors.New("--remote and --relay-session are mutually exclusive")
@@ -202,6 +271,9 @@ func runRLM(cmd *cobra.Command, args []string, flags *rlmFlags) error {
 	if strings.TrimSpace(flags.customer) != "" && !flags.relaySession {
 		return errors.New("--customer requires --relay-session")
 	}
+	if (flags.remote || flags.relaySession) && (flags.showUsage || flags.maxCost > 0) {
+		return errors.New("--usage and --max-cost are local-mode only")
+	}
 	if flags.remote {
 		if validationErr := validateRLMRemoteAttachments(files); validationErr != nil {
 			return validationErr
@@ -299,24 +371,41 @@ func runRLM(cmd *cobra.Command, args []string, flags *rlmFlags) error {
 				return fmt.Errorf("--%s cannot override an immutable execution profile in --relay-session mode", name)
 			}
 		}
-		if strings.TrimSpace(flags.db) != "" || strings.TrimSpace(flags.postgresDSNEnv) != "" || strings.TrimSpace(flags.snowflakeBrokerURL) != "" || len(flags.mcpConfigs) > 0 {
-			return errors.New("--relay-session currently supports message/context workloads only; SQL and MCP transports remain local-mode only")
+		if strings.TrimSpace(flags.db) != "" || strings.TrimSpace(flags.postgresDSNEnv) != "" || strings.TrimSpace(flags.snowflakeBrokerURL) != "" || len(flags.mcpConfigs) > 0 || strings.TrimSpace(flags.index) != "" {
+			return errors.New("--relay-session currently supports message/context workloads only; SQL, MCP and --index transports remain local-mode only")
 		}
 		return runRLMRelaySession(ctx, cfg, relayAuthority, model, strings.Join(args, " "), plan, flags)
 	}
 
 	if flags.remote {
-		if strings.TrimSpace(flags.db) != "" || strings.TrimSpace(flags.postgresDSNEnv) != "" || strings.TrimSpace(flags.snowflakeBrokerURL) != "" || len(flags.mcpConfigs) > 0 {
-			return errors.New("--db, --postgres-dsn-env, --snowflake-broker-url, and --mcp-config are local/VPC-mode only: trusted data-provider transports execute at the customer-controlled edge")
+		if strings.TrimSpace(flags.db) != "" || strings.TrimSpace(flags.postgresDSNEnv) != "" || strings.TrimSpace(flags.snowflakeBrokerURL) != "" || len(flags.mcpConfigs) > 0 || strings.TrimSpace(flags.index) != "" {
+			return errors.New("--db, --postgres-dsn-env, --snowflake-broker-url, --mcp-config, and --index are local/VPC-mode only: trusted data-provider transports execute at the customer-controlled edge")
 		}
 		return runRLMRemote(ctx, cfg, apiKey, model, strings.Join(args, " "), contextPayload, plan, flags, len(files) > 0)
 	}
 
 	usage := &rlmUsage{}
+	usage.cost, err = newCostTracker(cfg, flags.maxCost)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	defer recordRLMUsage(ledgerCommandName(cmd), cfg, model, usage, start)
+	if flags.showUsage && cfg.Output != outputFormatJSON {
+		defer printRLMUsage(usage)
+	}
 	mcpMounts, err := loadLocalMCPMounts(flags.mcpConfigs)
 	if err != nil {
 		return err
 	}
+	index, err := loadRLMDocumentIndex(ctx, cmd, cfg, flags)
+	if err != nil {
+		return err
+	}
+	if index != nil && mcpMounts.Secrets == nil {
+		mcpMounts.Secrets = make(map[localMCPSecretKey]string)
+		mcpMounts.AllowedNetworks = make(map[string][]string)
+	}
 	postgresConnector, err := openLocalPostgresConnector(ctx, flags, cfg)
 	if err != nil {
 		return err
@@ -332,11 +421,21 @@ func runRLM(cmd *cobra.Command, args []string, flags *rlmFlags) error {
 		MaxOutputTokens: flags.subcallMaxOutputTokens,
 		Model:           flags.subcallModel,
 		ReasoningEffort: flags.subcallReasoningEffort,
-	}, newLocalPostgresBrokerConfig(flags, postgresConnector), mcpMounts.Secrets)
+	}, newLocalPostgresBrokerConfig(flags, postgresConnector), mcpMounts.Secrets, index)
 	if err != nil {
 		return err
 	}
 	defer server.Close()
+	if index != nil {
+		name := strings.TrimSpace(flags.indexName)
+		source, sourceErr := rlmIndexDataSource(name, server.IndexURL)
+		if sourceErr != nil {
+			return sourceErr
+		}
+		mcpMounts.Sources = append(mcpMounts.Sources, source)
+		mcpMounts.Secrets[localMCPSecretKey{TenantID: rlmIndexTenant, SourceID: name, Ref: rlmIndexTokenRef}] = server.Token
+		mcpMounts.AllowedNetworks[name] = append(mcpMounts.AllowedNetworks[name], rlmIndexNetwork)
+	}
 
 	dataSources, defaultSource, err := buildLocalSQLDataSource(flags, cfg, server)
 	if err != nil {
@@ -507,6 +606,7 @@ type rlmJSONResult struct {
 	Subcalls           int                                      `json:"subcalls"`
 	DataSourceRequests *int                                     `json:"data_source_requests,omitempty"`
 	TotalUsage         workflow.TokenUsage                      `json:"total_usage,omitempty"`
+	EstimatedCostCents *float64                                 `json:"estimated_cost_cents,omitempty"`
 	Trajectory         workflow.RLMContentFact                  `json:"trajectory"`
 	Ready              bool                                     `json:"ready"`
 	Extracted          bool                                     `json:"extracted,omitempty"`
@@ -522,8 +622,12 @@ func buildRLMJSONResult(usage *rlmUsage, resp rlmrunner.RunnerResponse, runErr e
 		return rlmJSONResult{}, err
 	}
 	totalUsage := workflow.TokenUsage{}
+	var costCents *float64
 	if usage != nil {
 		totalUsage = usage.snapshot()
+		if usage.cost != nil {
+			costCents = usage.cost.jsonCents()
+		}
 	}
 	result := rlmJSONResult{
 		Answer:             answerPayload,
@@ -531,6 +635,7 @@ func buildRLMJSONResult(usage *rlmUsage, resp rlmrunner.RunnerResponse, runErr e
 		Subcalls:           resp.Subcalls,
 		DataSourceRequests: resp.DataSourceRequests,
 		TotalUsage:         totalUsage,
+		EstimatedCostCents: costCents,
 		Trajectory:         workflow.UnavailableRLMContent("default_no_content_retention"),
 		Ready:              resp.Ready,
 		Extracted:          resp.Extracted,
@@ -589,6 +694,29 @@ func writeRLMLocalOutcomeWithEvidenceTo(w io.Writer, cfg runtimeConfig, usage *r
 	return err
 }
 
+// printRLMUsage writes the local-mode usage line to stderr so the answer on
+// stdout stays machine-readable.
+func printRLMUsage(usage *rlmUsage) {
+	total := usage.snapshot()
+	fmt.Fprintf(os.Stderr, "Usage: %d tokens in / %d out | Cost: %s\n",
+		total.InputTokens,
+		total.OutputTokens,
+		usage.cost.summary(),
+	)
+}
+
+func recordRLMUsage(command string, cfg runtimeConfig, model string, usage *rlmUsage, start time.Time) {
+	usage.mu.Lock()
+	calls := usage.calls
+	total := sdk.Usage{
+		InputTokens:  usage.usage.InputTokens,
+		OutputTokens: usage.usage.OutputTokens,
+		TotalTokens:  usage.usage.TotalTokens,
+	}
+	usage.mu.Unlock()
+	recordUsage(command, cfg, model, calls, total, time.Since(start), usage.cost.jsonCents())
+}
+
 func callLLM(ctx context.Context, client *sdk.Client, model string, input []llm.InputItem, maxOutputTokens int64, reasoningEffort string) (*sdk.Response, error) {
 	builder := client.Responses.New().Model(sdk.NewModelID(model)).Input(input)
 	if maxOutputTokens > 0 {
@@ -787,13 +915,15 @@ func newLocalPostgresBrokerConfig(flags *rlmFlags, source *postgressource.Connec
 
 // localRLMServer is the loopback server the local runner talks to: LLM
 // root/subcall proxies, plus a /sql/validate forwarder when a SQL data source
-// is attached (the runner sends only the SQL string; mrl adds the API key).
+// is attached (the runner sends only the SQL string; mrl adds the API key)
+// and an MCP endpoint for an --index document index.
 type localRLMServer struct {
 	SubcallEndpoint string
 	RootEndpoint    string
 	ValidateURL     string
 	BrokerURL       string
 	MCPSecretURL    string
+	IndexURL        string
 	Token           string
 	Close           func()
 }
@@ -807,7 +937,7 @@ type localSubcallDefaults struct {
 	ReasoningEffort string
 }
 
-func startLocalRLMServer(ctx context.Context, client *sdk.Client, cfg runtimeConfig, defaultModel string, maxDepth, maxSubcalls int, usage *rlmUsage, subcallDefaults localSubcallDefaults, postgresBroker *localPostgresBrokerConfig, mcpSecrets map[localMCPSecretKey]string) (localRLMServer, error) {
+func startLocalRLMServer(ctx context.Context, client *sdk.Client, cfg runtimeConfig, defaultModel string, maxDepth, maxSubcalls int, usage *rlmUsage, subcallDefaults localSubcallDefaults, postgresBroker *localPostgresBrokerConfig, mcpSecrets map[localMCPSecretKey]string, index *rlmIndex) (localRLMServer, error) {
 	if maxSubcalls < 0 {
 		return localRLMServer{}, errors.New("max_subcalls must be >= 0")
 	}
@@ -861,15 +991,24 @@ func startLocalRLMServer(ctx context.Context, client *sdk.Client, cfg runtimeCon
 	if mcpSecrets != nil {
 		mux.Handle("/mcp/secret", &localMCPSecretBrokerHandler{token: token, secrets: mcpSecrets})
 	}
+	if index != nil {
+		mux.Handle("/index/mcp", &rlmIndexMCPHandler{ctx: ctx, index: index, token: token})
+	}
 	server := httptest.NewServer(mux)
+	host := server.Listener.Addr().String()
+	localRLMHosts.Store(host, struct{}{})
 	return localRLMServer{
 		SubcallEndpoint: server.URL + "/rlm/subcall",
 		RootEndpoint:    server.URL + "/rlm/root",
 		ValidateURL:     server.URL + "/sql/validate",
 		BrokerURL:       server.URL + "/sql/source",
 		MCPSecretURL:    server.URL + "/mcp/secret",
+		IndexURL:        server.URL + "/index/mcp",
 		Token:           token,
-		Close:           server.Close,
+		Close: func() {
+			server.Close()
+			localRLMHosts.Delete(host)
+		},
 	}, nil
 }
 
@@ -996,19 +1135,8 @@ func writeSQLBrokerError(w http.ResponseWriter, status int, message string) {
 }
 
 func extractAPIErrorMessage(raw []byte, fallback string) string {
-	var response struct {
-		Error struct {
-			Message string `json:"message"`
-		} `json:"error"`
-		Message string `json:"message"`
-	}
-	if json.Unmarshal(raw, &response) == nil {
-		if message := strings.TrimSpace(response.Error.Message); message != "" {
-			return message
-		}
-		if message := strings.TrimSpace(response.Message); message != "" {
-			return message
-		}
+	if _, message, _ := parseAPIErrorBody(raw); message != "" {
+		return message
 	}
 	return fallback
 }
@@ -1055,7 +1183,7 @@ func (h *localSQLValidateHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 	if header := strings.TrimSpace(clientHeader()); header != "" {
 		req.Header.Set("X-ModelRelay-Client", header)
 	}
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := apiHTTPClient.Do(req)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("validate call failed: %v", err), http.StatusBadGateway)
 		return
@@ -1171,13 +1299,17 @@ func (h *localSubcallHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		reasoningEffort = effort
 	}
 
+	if err := h.usage.checkBudget(); err != nil {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
 	resp, err := callLLM(h.ctx, h.client, model, []llm.InputItem{llm.NewUserText(req.Prompt)}, maxOutputTokens, reasoningEffort)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadGateway)
 		return
 	}
 	if h.usage != nil {
-		h.usage.add(resp.Usage)
+		h.usage.record(h.ctx, firstNonEmpty(resp.Model.String(), model), resp.Usage)
 	}
 
 	text := strings.TrimSpace(resp.AssistantText())
@@ -1289,13 +1421,17 @@ func (h *localRootHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
+	if err := h.usage.checkBudget(); err != nil {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
 	resp, err := h.client.Responses.Create(h.ctx, request, opts...)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadGateway)
 		return
 	}
 	if h.usage != nil {
-		h.usage.add(resp.Usage)
+		h.usage.record(h.ctx, firstNonEmpty(resp.Model.String(), model), resp.Usage)
 	}
 
 	text := strings.TrimSpace(resp.AssistantText())
@@ -1452,7 +1588,7 @@ func applyRLMLeaseAuthority(req *http.Request, authority rlmLeaseAuthority) erro
 
 func runRLMRelaySession(ctx context.Context, cfg runtimeConfig, authority rlmLeaseAuthority, model, query string, plan rlm.ContextPlan, flags *rlmFlags) error {
 	var resolution rlmLeaseResolutionResponse
-	if err := doRLMLeaseJSON(ctx, nil, cfg.BaseURL, authority, http.MethodPost, "/rlm/executions/resolve", rlmLeaseResolutionRequest{
+	if err := doRLMLeaseJSON(ctx, nil, cfg.retryPolicy(), cfg.BaseURL, authority, http.MethodPost, "/rlm/executions/resolve", rlmLeaseResolutionRequest{
 		Model: model, Seed: flags.seed,
 	}, &resolution); err != nil {
 		return fmt.Errorf("resolve RLM execution lease: %w", err)
@@ -1547,7 +1683,7 @@ func runRLMRelaySession(ctx context.Context, cfg runtimeConfig, authority rlmLea
 		return fmt.Errorf("local Droste preflight returned no scaffold manifest (correlation_id=%s)", preflightCorrelationID)
 	}
 	var lease rlmLeaseCreateResponse
-	if err := doRLMLeaseJSON(ctx, nil, cfg.BaseURL, authority, http.MethodPost, "/rlm/executions", rlmLeaseCreateRequest{
+	if err := doRLMLeaseJSON(ctx, nil, cfg.retryPolicy(), cfg.BaseURL, authority, http.MethodPost, "/rlm/executions", rlmLeaseCreateRequest{
 		Model: model, Seed: flags.seed, ScaffoldManifest: preflight.Preflight.ScaffoldManifest,
 		ExpectedRevisionID:           profile.RevisionID,
 		ExpectedRevisionContentHash:  profile.RevisionContentHash,
@@ -1563,7 +1699,7 @@ func runRLMRelaySession(ctx context.Context, cfg runtimeConfig, authority rlmLea
 	finalizeLease := func() error {
 		finalizeCtx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
-		return doRLMLeaseJSON(finalizeCtx, nil, baseURL, authority, http.MethodPost, "/rlm/executions/"+url.PathEscape(lease.ExecutionID)+"/finalize", struct{}{}, &executionEvidence)
+		return doRLMLeaseJSON(finalizeCtx, nil, cfg.retryPolicy(), baseURL, authority, http.MethodPost, "/rlm/executions/"+url.PathEscape(lease.ExecutionID)+"/finalize", struct{}{}, &executionEvidence)
 	}
 	if lease.MaxSettledSpendMicrocents != resolution.MaxSettledSpendMicrocents {
 		if finalizeErr := finalizeLease(); finalizeErr != nil {
@@ -1593,7 +1729,7 @@ func runRLMRelaySession(ctx context.Context, cfg runtimeConfig, authority rlmLea
 	return writeRLMLocalOutcomeWithEvidenceTo(os.Stdout, cfg, nil, runResult.Response, nil, &executionEvidence)
 }
 
-func doRLMLeaseJSON(ctx context.Context, httpClient *http.Client, baseURL string, authority rlmLeaseAuthority, method, path string, requestBody, responseBody any) error {
+func doRLMLeaseJSON(ctx context.Context, httpClient *http.Client, policy retryPolicy, baseURL string, authority rlmLeaseAuthority, method, path string, requestBody, responseBody any) error {
 	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
 	if baseURL == "" {
 		return errors.New("base URL is required")
@@ -1602,36 +1738,24 @@ func doRLMLeaseJSON(ctx context.Context, httpClient *http.Client, baseURL string
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, bytes.NewReader(payload))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if authorityErr := applyRLMLeaseAuthority(req, authority); authorityErr != nil {
-		return authorityErr
-	}
-	if header := strings.TrimSpace(clientHeader()); header != "" {
-		req.Header.Set("X-ModelRelay-Client", header)
-	}
-	if httpClient == nil {
-		httpClient = http.DefaultClient
-	}
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
+	newRequest := func() (*http.Request, error) {
+		req, reqErr := http.NewRequestWithContext(ctx, method, baseURL+path, bytes.NewReader(payload))
+		if reqErr != nil {
+			return nil, reqErr
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if authorityErr := applyRLMLeaseAuthority(req, authority); authorityErr != nil {
+			return nil, authorityErr
+		}
+		if header := strings.TrimSpace(clientHeader()); header != "" {
+			req.Header.Set("X-ModelRelay-Client", header)
+		}
+		return req, nil
 	}
-	defer func() { _ = resp.Body.Close() }()
-	body, err := io.ReadAll(resp.Body)
+	body, err := doAPIRequest(ctx, httpClient, policy, newRequest, isIdempotentRequest(method, path))
 	if err != nil {
 		return err
 	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		message := strings.TrimSpace(string(body))
-		if message == "" {
-			message = resp.Status
-		}
-		return fmt.Errorf("request failed (%d): %s", resp.StatusCode, message)
-	}
 	if responseBody == nil {
 		return nil
 	}
@@ -1693,7 +1817,7 @@ func runRLMRemote(ctx context.Context, cfg runtimeConfig, apiKey sdk.APIKeyAuth,
 			contextInline = contextPayload
 		}
 	case rlm.ContextLoadFile:
-		ref, err := createRLMContextRemote(ctx, nil, cfg.BaseURL, apiKey, contextPayload)
+		ref, err := createRLMContextRemote(ctx, nil, cfg.retryPolicy(), cfg.BaseURL, apiKey, contextPayload)
 		if err != nil {
 			return err
 		}
@@ -1720,7 +1844,7 @@ func runRLMRemote(ctx context.Context, cfg runtimeConfig, apiKey sdk.APIKeyAuth,
 		return executeRLMRemoteStream(ctx, nil, cfg.BaseURL, apiKey, req, os.Stdout)
 	}
 
-	result, err := executeRLMRemote(ctx, nil, cfg.BaseURL, apiKey, req)
+	result, err := executeRLMRemote(ctx, nil, cfg.retryPolicy(), cfg.BaseURL, apiKey, req)
 	if err != nil {
 		return err
 	}
@@ -1733,7 +1857,7 @@ func runRLMRemote(ctx context.Context, cfg runtimeConfig, apiKey sdk.APIKeyAuth,
 	return writeRLMAnswer(os.Stdout, result.Answer)
 }
 
-func executeRLMRemote(ctx context.Context, httpClient *http.Client, baseURL string, apiKey sdk.APIKeyAuth, req rlmExecuteRemoteRequest) (rlmExecuteRemoteResult, error) {
+func executeRLMRemote(ctx context.Context, httpClient *http.Client, policy retryPolicy, baseURL string, apiKey sdk.APIKeyAuth, req rlmExecuteRemoteRequest) (rlmExecuteRemoteResult, error) {
 	endpoint := strings.TrimRight(strings.TrimSpace(baseURL), "/") + "/rlm/execute"
 	if endpoint == "" {
 		return rlmExecuteRemoteResult{}, errors.New("base URL is required")
@@ -1743,38 +1867,11 @@ func executeRLMRemote(ctx context.Context, httpClient *http.Client, baseURL stri
 		return rlmExecuteRemoteResult{}, fmt.Errorf("encode rlm execute request: %w", err)
 	}
 
-	if httpClient == nil {
-		httpClient = http.DefaultClient
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
-	if err != nil {
-		return rlmExecuteRemoteResult{}, fmt.Errorf("build rlm execute request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	if apiKey != nil && strings.TrimSpace(apiKey.String()) != "" {
-		httpReq.Header.Set("X-ModelRelay-Api-Key", apiKey.String())
-	}
-	if header := strings.TrimSpace(clientHeader()); header != "" {
-		httpReq.Header.Set("X-ModelRelay-Client", header)
-	}
-
-	resp, err := httpClient.Do(httpReq)
+	body, err := doAPIRequest(ctx, httpClient, policy, func() (*http.Request, error) {
+		return newRLMRemoteRequest(ctx, endpoint, apiKey, payload)
+	}, false)
 	if err != nil {
-		return rlmExecuteRemoteResult{}, err
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return rlmExecuteRemoteResult{}, fmt.Errorf("read rlm execute response: %w", err)
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		msg := strings.TrimSpace(string(body))
-		if msg == "" {
-			msg = resp.Status
-		}
-		return rlmExecuteRemoteResult{}, fmt.Errorf("rlm execute failed (%d): %s", resp.StatusCode, msg)
+		return rlmExecuteRemoteResult{}, fmt.Errorf("rlm execute: %w", err)
 	}
 
 	var partial struct {
@@ -1792,7 +1889,7 @@ func executeRLMRemote(ctx context.Context, httpClient *http.Client, baseURL stri
 	}, nil
 }
 
-func createRLMContextRemote(ctx context.Context, httpClient *http.Client, baseURL string, apiKey sdk.APIKeyAuth, contextPayload json.RawMessage) (string, error) {
+func createRLMContextRemote(ctx context.Context, httpClient *http.Client, policy retryPolicy, baseURL string, apiKey sdk.APIKeyAuth, contextPayload json.RawMessage) (string, error) {
 	endpoint := strings.TrimRight(strings.TrimSpace(baseURL), "/") + "/rlm/context"
 	if endpoint == "" {
 		return "", errors.New("base URL is required")
@@ -1801,37 +1898,11 @@ func createRLMContextRemote(ctx context.Context, httpClient *http.Client, baseUR
 	if err != nil {
 		return "", fmt.Errorf("encode rlm context request: %w", err)
 	}
-	if httpClient == nil {
-		httpClient = http.DefaultClient
-	}
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	body, err := doAPIRequest(ctx, httpClient, policy, func() (*http.Request, error) {
+		return newRLMRemoteRequest(ctx, endpoint, apiKey, payload)
+	}, false)
 	if err != nil {
-		return "", fmt.Errorf("build rlm context request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	if apiKey != nil && strings.TrimSpace(apiKey.String()) != "" {
-		httpReq.Header.Set("X-ModelRelay-Api-Key", apiKey.String())
-	}
-	if header := strings.TrimSpace(clientHeader()); header != "" {
-		httpReq.Header.Set("X-ModelRelay-Client", header)
-	}
-
-	resp, err := httpClient.Do(httpReq)
-	if err != nil {
-		return "", err
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("read rlm context response: %w", err)
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		msg := strings.TrimSpace(string(body))
-		if msg == "" {
-			msg = resp.Status
-		}
-		return "", fmt.Errorf("rlm context upload failed (%d): %s", resp.StatusCode, msg)
+		return "", fmt.Errorf("rlm context upload: %w", err)
 	}
 
 	var parsed rlmContextCreateResponse
@@ -1844,6 +1915,22 @@ func createRLMContextRemote(ctx context.Context, httpClient *http.Client, baseUR
 	return parsed.ID, nil
 }
 
+// newRLMRemoteRequest builds a JSON POST to a remote RLM endpoint.
+func newRLMRemoteRequest(ctx context.Context, endpoint string, apiKey sdk.APIKeyAuth, payload []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("build rlm request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != nil && strings.TrimSpace(apiKey.String()) != "" {
+		req.Header.Set("X-ModelRelay-Api-Key", apiKey.String())
+	}
+	if header := strings.TrimSpace(clientHeader()); header != "" {
+		req.Header.Set("X-ModelRelay-Client", header)
+	}
+	return req, nil
+}
+
 func validateRLMRemoteAttachments(files []rlmFileAttachment) error {
 	for _, file := range files {
 		if strings.TrimSpace(file.Text) == "" {
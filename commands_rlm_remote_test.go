@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -66,7 +67,7 @@ func TestExecuteRLMRemote_Progress(t *testing.T) {
 
 	seed := int64(42)
 	req := rlmExecuteRemoteRequest{Model: "demo", Query: "hi", Seed: &seed}
-	result, err := executeRLMRemote(context.Background(), server.Client(), server.URL, sdk.SecretKey("mr_sk_test"), req)
+	result, err := executeRLMRemote(context.Background(), server.Client(), retryPolicy{}, server.URL, sdk.SecretKey("mr_sk_test"), req)
 	if err != nil {
 		t.Fatalf("executeRLMRemote error: %v", err)
 	}
@@ -91,6 +92,20 @@ func TestExecuteRLMRemote_Progress(t *testing.T) {
 	}
 }
 
+func TestExecuteRLMRemote_ReturnsAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"error":{"code":"invalid_request","message":"query is required"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := executeRLMRemote(t.Context(), server.Client(), retryPolicy{}, server.URL, nil, rlmExecuteRemoteRequest{})
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Message != "query is required" {
+		t.Fatalf("error = %v, want a 422 apiError", err)
+	}
+}
+
 func TestValidateRLMRemoteAttachments_RejectsMissingText(t *testing.T) {
 	err := validateRLMRemoteAttachments([]rlmFileAttachment{{Name: "data.csv"}})
 	if err == nil {
@@ -123,7 +138,7 @@ func TestDoRLMLeaseJSONUsesOneCustomerAuthority(t *testing.T) {
 			Selector string `json:"selector"`
 		} `json:"profile"`
 	}
-	if err := doRLMLeaseJSON(t.Context(), server.Client(), server.URL, rlmTestProjectAuthority("customer-123"), http.MethodPost, "/rlm/executions/resolve", rlmLeaseResolutionRequest{Model: "preset:test"}, &response); err != nil {
+	if err := doRLMLeaseJSON(t.Context(), server.Client(), retryPolicy{}, server.URL, rlmTestProjectAuthority("customer-123"), http.MethodPost, "/rlm/executions/resolve", rlmLeaseResolutionRequest{Model: "preset:test"}, &response); err != nil {
 		t.Fatalf("doRLMLeaseJSON: %v", err)
 	}
 	if gotPath != "/rlm/executions/resolve" || gotKey != "mr_sk_test" || gotClient == "" || gotCustomer != "customer-123" {
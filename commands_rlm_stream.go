@@ -67,7 +67,7 @@ func executeRLMRemoteStream(
 		request.Header.Set("X-ModelRelay-Client", header)
 	}
 	if httpClient == nil {
-		httpClient = http.DefaultClient
+		httpClient = apiHTTPClient
 	}
 
 	response, err := httpClient.Do(request)
@@ -81,10 +81,7 @@ func executeRLMRemoteStream(
 		if readErr != nil {
 			return fmt.Errorf("read rlm execute error response: %w", readErr)
 		}
-		if message == "" {
-			message = response.Status
-		}
-		return fmt.Errorf("rlm execute failed (%d): %s", response.StatusCode, message)
+		return fmt.Errorf("rlm execute: %w", newAPIError(response, []byte(message)))
 	}
 	if err := validateRLMStreamContentType(response.Header.Get("Content-Type")); err != nil {
 		return err
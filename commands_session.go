@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newSessionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Manage saved conversation sessions",
+		Long: `Manage conversations saved with --session.
+
+Examples:
+  mrl --session refactor "Plan the parser refactor"
+  mrl --session refactor "next step?"
+  mrl session list
+  mrl session export refactor --format markdown`,
+	}
+	cmd.AddCommand(newSessionListCmd(), newSessionShowCmd(), newSessionRmCmd(), newSessionExportCmd())
+	return cmd
+}
+
+func newSessionListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List saved sessions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := runtimeConfigFrom(cmd)
+			if err != nil {
+				return err
+			}
+			sessions, err := listPromptSessions()
+			if err != nil {
+				return err
+			}
+			if cfg.Output == outputFormatJSON {
+				summaries := make([]map[string]any, 0, len(sessions))
+				for index := range sessions {
+					session := &sessions[index]
+					summaries = append(summaries, map[string]any{
+						"name":       session.Name,
+						"model":      session.Model,
+						"turns":      len(session.Turns),
+						"usage":      session.totalUsage(),
+						"created_at": session.CreatedAt,
+						"updated_at": session.UpdatedAt,
+					})
+				}
+				printJSON(map[string]any{"sessions": summaries})
+				return nil
+			}
+			printSessionsTable(sessions)
+			return nil
+		},
+	}
+}
+
+func newSessionShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <name>",
+		Short: "Show a saved session",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := runtimeConfigFrom(cmd)
+			if err != nil {
+				return err
+			}
+			session, err := requirePromptSession(args[0])
+			if err != nil {
+				return err
+			}
+			if cfg.Output == outputFormatJSON {
+				printJSON(session)
+				return nil
+			}
+			total := session.totalUsage()
+			printKeyValueTable([]kvPair{
+				{Key: "name", Value: session.Name},
+				{Key: "model", Value: session.Model},
+				{Key: "system", Value: session.System},
+				{Key: "turns", Value: fmt.Sprintf("%d", len(session.Turns))},
+				{Key: "input_tokens", Value: fmt.Sprintf("%d", total.InputTokens)},
+				{Key: "output_tokens", Value: fmt.Sprintf("%d", total.OutputTokens)},
+				{Key: "created_at", Value: session.CreatedAt.Format(time.RFC3339)},
+				{Key: "updated_at", Value: session.UpdatedAt.Format(time.RFC3339)},
+			})
+			for index, turn := range session.Turns {
+				fmt.Printf("\n[%d] %s | %d in / %d out\n", index+1, turn.Model, turn.Usage.InputTokens, turn.Usage.OutputTokens)
+				fmt.Printf("> %s\n%s\n", turn.Prompt, turn.Response)
+			}
+			return nil
+		},
+	}
+}
+
+func newSessionRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm <name>...",
+		Aliases: []string{"remove", "delete"},
+		Short:   "Delete saved sessions",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, name := range args {
+				if err := removePromptSession(strings.TrimSpace(name)); err != nil {
+					return err
+				}
+				fmt.Printf("removed session %s\n", strings.TrimSpace(name))
+			}
+			return nil
+		},
+	}
+}
+
+func newSessionExportCmd() *cobra.Command {
+	var format string
+	var outputPath string
+
+	cmd := &cobra.Command{
+		Use:   "export <name>",
+		Short: "Export a session transcript",
+		Long: `Export a session transcript.
+
+The json format writes {"input": [...]}, which 'mrl agent loop --input-file'
+accepts directly. The markdown format writes a readable transcript.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			session, err := requirePromptSession(args[0])
+			if err != nil {
+				return err
+			}
+			var data []byte
+			switch strings.ToLower(strings.TrimSpace(format)) {
+			case "json":
+				data, err = marshalSessionExport(session)
+				if err != nil {
+					return err
+				}
+			case "markdown", "md":
+				data = []byte(renderSessionMarkdown(session))
+			default:
+				return errors.New("format must be json or markdown")
+			}
+			if strings.TrimSpace(outputPath) == "" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0o700); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			return os.WriteFile(outputPath, data, 0o600)
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "json", "Export format (json|markdown)")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Write to file instead of stdout")
+	return cmd
+}
+
+func requirePromptSession(name string) (*promptSession, error) {
+	name = strings.TrimSpace(name)
+	session, found, err := loadPromptSession(name)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("session %s not found", name)
+	}
+	return session, nil
+}
+
+func marshalSessionExport(session *promptSession) ([]byte, error) {
+	payload := map[string]any{"input": promptInput(session.System, session.Items)}
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func renderSessionMarkdown(session *promptSession) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Session %s\n\n", session.Name)
+	if session.Model != "" {
+		fmt.Fprintf(&b, "Model: %s\n\n", session.Model)
+	}
+	if strings.TrimSpace(session.System) != "" {
+		fmt.Fprintf(&b, "## System\n\n%s\n\n", strings.TrimSpace(session.System))
+	}
+	for _, turn := range session.Turns {
+		fmt.Fprintf(&b, "## User\n\n%s\n\n## Assistant\n\n%s\n\n", strings.TrimSpace(turn.Prompt), strings.TrimSpace(turn.Response))
+	}
+	return b.String()
+}
+
+func printSessionsTable(sessions []promptSession) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tMODEL\tTURNS\tTOKENS\tUPDATED_AT")
+	for index := range sessions {
+		session := &sessions[index]
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n",
+			session.Name,
+			session.Model,
+			len(session.Turns),
+			session.totalUsage().TotalTokens,
+			session.UpdatedAt.Format(time.RFC3339),
+		)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+)
+
+func newTemplateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "template",
+		Aliases: []string{"templates"},
+		Short:   "Manage reusable prompt templates",
+		Long: `Manage reusable prompt templates stored next to the CLI config
+(~/.config/mrl/templates/<name>.toml).
+
+A template bundles a prompt, system prompt, model, attachments and schema.
+{{name}} placeholders are filled with --var name=value when the template is
+run; {{stdin}} in the prompt is replaced with piped input.
+
+Examples:
+  mrl template add review --system "You are a strict code reviewer" \
+    --prompt "Review this diff, focusing on {{focus}}:\n\n{{stdin}}" --var focus=correctness
+  git diff | mrl run review --var focus=security
+  mrl template list`,
+	}
+	cmd.AddCommand(newTemplateAddCmd(), newTemplateListCmd(), newTemplateShowCmd(), newTemplateRmCmd())
+	return cmd
+}
+
+func newTemplateAddCmd() *cobra.Command {
+	var tmpl promptTemplate
+	var vars []string
+	var fromFile string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Create or replace a template",
+		Long: `Create a template from flags, or import one from a TOML file with --file.
+
+The TOML keys are description, model, system, prompt, attachments,
+attachment_type, schema, schema_retries and a [vars] table of defaults.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(fromFile) != "" {
+				data, err := os.ReadFile(fromFile) //nolint:gosec // template file is explicitly selected by the CLI user
+				if err != nil {
+					return err
+				}
+				imported, err := decodePromptTemplate(data)
+				if err != nil {
+					return fmt.Errorf("invalid template file %s: %w", fromFile, err)
+				}
+				tmpl = *imported
+			} else {
+				defaults, err := parseTemplateVars(vars)
+				if err != nil {
+					return err
+				}
+				if len(defaults) > 0 {
+					tmpl.Vars = defaults
+				}
+				tmpl.Prompt = unescapeTemplateFlag(tmpl.Prompt)
+				tmpl.System = unescapeTemplateFlag(tmpl.System)
+			}
+			tmpl.Name = strings.TrimSpace(args[0])
+			if err := savePromptTemplate(&tmpl, force); err != nil {
+				return err
+			}
+			fmt.Printf("saved template %s\n", tmpl.Name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&tmpl.Prompt, "prompt", "", "Prompt text with {{var}} placeholders ('\\n' for newlines)")
+	cmd.Flags().StringVar(&tmpl.System, "system", "", "System prompt")
+	cmd.Flags().StringVar(&tmpl.Model, "model", "", "Model ID")
+	cmd.Flags().StringVar(&tmpl.Description, "description", "", "Short description shown in template list")
+	cmd.Flags().StringArrayVarP(&tmpl.Attachments, "attachment", "a", nil, "Attach a local file (repeatable; placeholders allowed)")
+	cmd.Flags().StringVar(&tmpl.AttachmentType, "attachment-type", "", "Override attachment MIME type")
+	cmd.Flags().StringVar(&tmpl.Schema, "schema", "", "JSON Schema file the response must match")
+	cmd.Flags().IntVar(&tmpl.SchemaRetries, "schema-retries", 0, "Re-prompt up to N times when the response fails schema validation")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Default variable value (key=value, repeatable)")
+	cmd.Flags().StringVar(&fromFile, "file", "", "Import the template from a TOML file")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace an existing template")
+	return cmd
+}
+
+func newTemplateListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List templates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := runtimeConfigFrom(cmd)
+			if err != nil {
+				return err
+			}
+			templates, err := listPromptTemplates()
+			if err != nil {
+				return err
+			}
+			if cfg.Output == outputFormatJSON {
+				if templates == nil {
+					templates = []promptTemplate{}
+				}
+				printJSON(map[string]any{"templates": templates})
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "NAME\tMODEL\tVARS\tDESCRIPTION")
+			for index := range templates {
+				tmpl := &templates[index]
+				vars := tmpl.variables()
+				if tmpl.usesStdin() {
+					vars = append(vars, "stdin")
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", tmpl.Name, tmpl.Model, strings.Join(vars, ","), tmpl.Description)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newTemplateShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <name>",
+		Short: "Show a template",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := runtimeConfigFrom(cmd)
+			if err != nil {
+				return err
+			}
+			tmpl, err := loadPromptTemplate(args[0])
+			if err != nil {
+				return err
+			}
+			if cfg.Output == outputFormatJSON {
+				printJSON(tmpl)
+				return nil
+			}
+			var buf bytes.Buffer
+			if err := toml.NewEncoder(&buf).Encode(tmpl); err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(buf.Bytes())
+			return err
+		},
+	}
+}
+
+func newTemplateRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm <name>...",
+		Aliases: []string{"remove", "delete"},
+		Short:   "Delete templates",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, name := range args {
+				if err := removePromptTemplate(strings.TrimSpace(name)); err != nil {
+					return err
+				}
+				fmt.Printf("removed template %s\n", strings.TrimSpace(name))
+			}
+			return nil
+		},
+	}
+}
+
+func newRunTemplateCmd() *cobra.Command {
+	var vars []string
+	var opts promptOptions
+
+	cmd := &cobra.Command{
+		Use:   "run <template> [extra prompt]",
+		Short: "Run a prompt template",
+		Long: `Run a stored prompt template.
+
+Placeholders are filled from --var key=value, falling back to the template's
+defaults. If the template prompt contains {{stdin}}, piped input is inserted
+there; otherwise piped input is handled as for a plain prompt. Extra arguments
+are appended to the rendered prompt. Flags override the template's settings.
+
+Examples:
+  git diff | mrl run review --var focus=security
+  mrl run summarize --var file=notes.pdf --model gpt-5.2 --usage`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tmpl, err := loadPromptTemplate(args[0])
+			if err != nil {
+				return err
+			}
+			values, err := parseTemplateVars(vars)
+			if err != nil {
+				return err
+			}
+			rendered, err := tmpl.render(values)
+			if err != nil {
+				return err
+			}
+			prompt := rendered.Prompt
+			if extra := strings.TrimSpace(strings.Join(args[1:], " ")); extra != "" {
+				if strings.TrimSpace(prompt) == "" {
+					prompt = extra
+				} else {
+					prompt += "\n\n" + extra
+				}
+			}
+			return runPrompt(cmd, prompt, mergeTemplateOptions(rendered, opts))
+		},
+	}
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Template variable (key=value, repeatable)")
+	cmd.Flags().StringVar(&opts.model, "model", "", "Model ID (overrides the template and profile default)")
+	cmd.Flags().StringSliceVar(&opts.fallbacks, "fallback-model", nil, "Models to try in order when the model fails (comma-separated)")
+	cmd.Flags().StringVar(&opts.system, "system", "", "System prompt (overrides the template)")
+	cmd.Flags().StringArrayVarP(&opts.attachments, "attachment", "a", nil, "Attach an additional file, directory or glob (repeatable)")
+	cmd.Flags().BoolVar(&opts.extract, "extract", false, "Send text extracted from PDF, DOCX, XLSX, CSV and HTML attachments instead of the files")
+	cmd.Flags().Int64Var(&opts.maxContextTokens, "max-context-tokens", 0, "Token budget for text packed from directory and glob attachments (0 = no limit)")
+	cmd.Flags().BoolVar(&opts.stream, "stream", false, "Stream output as it's generated")
+	cmd.Flags().BoolVar(&opts.raw, "raw", false, "Print the response as is instead of rendering markdown")
+	cmd.Flags().BoolVar(&opts.extractCode, "extract-code", false, "Print only the fenced code blocks of the response")
+	cmd.Flags().BoolVar(&opts.showUsage, "usage", false, "Show token usage after response")
+	cmd.Flags().Float64Var(&opts.maxCost, "max-cost", 0, "Refuse prompts whose estimated cost exceeds this many cents")
+	cmd.Flags().StringVar(&opts.session, "session", "", "Continue (or start) a named conversation saved on disk")
+	return cmd
+}
+
+// mergeTemplateOptions layers command-line flags over a rendered template.
+func mergeTemplateOptions(tmpl *promptTemplate, flags promptOptions) promptOptions {
+	opts := flags
+	opts.model = firstNonEmpty(flags.model, tmpl.Model)
+	opts.system = firstNonEmpty(flags.system, tmpl.System)
+	opts.attachments = append(append([]string(nil), tmpl.Attachments...), flags.attachments...)
+	opts.attachmentType = tmpl.AttachmentType
+	opts.schemaPath = tmpl.Schema
+	opts.schemaRetries = tmpl.SchemaRetries
+	opts.stdinSlot = tmpl.usesStdin()
+	return opts
+}
+
+// unescapeTemplateFlag turns literal "\n" and "\t" typed on the command line
+// into real newlines and tabs.
+func unescapeTemplateFlag(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(value)
+}
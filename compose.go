@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// editorScissors separates the prompt from the help text in the editor
+// buffer; it and everything after it are dropped, so markdown headings in the
+// prompt survive.
+const editorScissors = "# ------------------------ >8 ------------------------"
+
+const editorHelp = editorScissors + `
+# Write your prompt above the line. This line and everything below it are
+# ignored. Save and quit to send; an empty prompt cancels.
+`
+
+// clipboardTool is a command that reads or writes the system clipboard.
+type clipboardTool struct {
+	name string
+	args []string
+}
+
+// addEditorFlag registers --editor on commands whose prompt can be composed
+// in $EDITOR.
+func addEditorFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("editor", false, "Compose the prompt in $VISUAL/$EDITOR (arguments seed the buffer)")
+}
+
+// editorRequested reports whether --editor is set on cmd.
+func editorRequested(cmd *cobra.Command) bool {
+	flag := cmd.Flags().Lookup("editor")
+	return flag != nil && flag.Value.String() == "true"
+}
+
+// argsOrEditor wraps an argument check so it is skipped when the prompt
+// comes from the editor instead.
+func argsOrEditor(check cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if editorRequested(cmd) {
+			return nil
+		}
+		return check(cmd, args)
+	}
+}
+
+// composeArgs replaces args with the prompt written in the editor when
+// --editor is set.
+func composeArgs(cmd *cobra.Command, args []string) ([]string, error) {
+	if !editorRequested(cmd) {
+		return args, nil
+	}
+	text, err := composeInEditor(cmd.Context(), strings.Join(args, " "))
+	if err != nil {
+		return nil, err
+	}
+	return []string{text}, nil
+}
+
+// composeInEditor opens the user's editor on a temp file seeded with seed and
+// returns what was written above the scissors line. The editor talks to the
+// controlling terminal so it works while stdin or stdout are redirected.
+func composeInEditor(ctx context.Context, seed string) (string, error) {
+	var (
+		in  io.Reader = os.Stdin
+		out io.Writer = os.Stdout
+	)
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		defer func() { _ = tty.Close() }()
+		in, out = tty, tty
+	} else if stdinIsTTY, ttyErr := isTerminal(os.Stdin); ttyErr != nil || !stdinIsTTY {
+		return "", errors.New("--editor needs an interactive terminal")
+	}
+	return editText(ctx, editorCommand(), seed, in, out)
+}
+
+// editorCommand returns the editor to run: $VISUAL, then $EDITOR, then vi
+// (notepad on Windows).
+func editorCommand() string {
+	if editor := firstNonEmpty(os.Getenv("VISUAL"), os.Getenv("EDITOR")); strings.TrimSpace(editor) != "" {
+		return strings.TrimSpace(editor)
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// editText runs editor on a temp file holding seed and the help text, then
+// returns the trimmed text above the scissors line.
+func editText(ctx context.Context, editor, seed string, stdin io.Reader, stdout io.Writer) (string, error) {
+	file, err := os.CreateTemp("", "mrl-prompt-*.md")
+	if err != nil {
+		return "", err
+	}
+	path := file.Name()
+	defer func() { _ = os.Remove(path) }()
+	buffer := strings.TrimSpace(seed)
+	if buffer != "" {
+		buffer += "\n"
+	}
+	if _, err := file.WriteString(buffer + "\n" + editorHelp); err != nil {
+		_ = file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	// Run through the shell like git does, so EDITOR="code --wait" works.
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		fields := strings.Fields(editor)
+		cmd = exec.CommandContext(ctx, fields[0], append(fields[1:], path)...) //nolint:gosec // the editor is the user's own $EDITOR
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", editor+` "$@"`, editor, path) //nolint:gosec // the editor is the user's own $EDITOR
+	}
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %q: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // temp file created above
+	if err != nil {
+		return "", err
+	}
+	text, offset := string(data), 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if strings.TrimRight(line, "\r\n") == editorScissors {
+			text = text[:offset]
+			break
+		}
+		offset += len(line)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", errors.New("empty prompt; nothing sent")
+	}
+	return text, nil
+}
+
+// clipboardTools lists the clipboard commands to try in order. Wayland tools
+// come first when a Wayland session is running.
+func clipboardTools(goos string, wayland, write bool) []clipboardTool {
+	if goos == "darwin" {
+		if write {
+			return []clipboardTool{{name: "pbcopy"}}
+		}
+		return []clipboardTool{{name: "pbpaste"}}
+	}
+	var tools []clipboardTool
+	if write {
+		if wayland {
+			tools = append(tools, clipboardTool{name: "wl-copy"})
+		}
+		return append(tools,
+			clipboardTool{name: "xclip", args: []string{"-selection", "clipboard", "-in"}},
+			clipboardTool{name: "xsel", args: []string{"--clipboard", "--input"}})
+	}
+	if wayland {
+		tools = append(tools, clipboardTool{name: "wl-paste", args: []string{"--no-newline"}})
+	}
+	return append(tools,
+		clipboardTool{name: "xclip", args: []string{"-selection", "clipboard", "-out"}},
+		clipboardTool{name: "xsel", args: []string{"--clipboard", "--output"}})
+}
+
+// findClipboardTool returns the first installed clipboard command.
+func findClipboardTool(write bool) (clipboardTool, string, error) {
+	tools := clipboardTools(runtime.GOOS, os.Getenv("WAYLAND_DISPLAY") != "", write)
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		if path, err := exec.LookPath(tool.name); err == nil {
+			return tool, path, nil
+		}
+		names = append(names, tool.name)
+	}
+	return clipboardTool{}, "", fmt.Errorf("no clipboard tool found (install one of: %s)", strings.Join(names, ", "))
+}
+
+// readClipboard returns the clipboard text.
+func readClipboard(ctx context.Context) (string, error) {
+	tool, path, err := findClipboardTool(false)
+	if err != nil {
+		return "", err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, tool.args...) //nolint:gosec // clipboard tool resolved from a fixed list
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %s", tool.name, firstNonEmpty(strings.TrimSpace(stderr.String()), err.Error()))
+	}
+	return stdout.String(), nil
+}
+
+// writeClipboard copies text to the clipboard.
+func writeClipboard(ctx context.Context, text string) error {
+	tool, path, err := findClipboardTool(true)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, path, tool.args...) //nolint:gosec // clipboard tool resolved from a fixed list
+	cmd.Stdin = strings.NewReader(text)
+	// wl-copy and xclip fork a background process that serves the
+	// selection. It inherits stderr, so stderr must not be a pipe or Run
+	// would wait for that process to exit.
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", tool.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o700); err != nil { //nolint:gosec // test script must be executable
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEditTextKeepsTextAboveScissors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script editor")
+	}
+	dir := t.TempDir()
+	// The fake editor takes the file as its last argument, checks the seed,
+	// then prepends a markdown heading and adds a line below the scissors,
+	// which must be dropped.
+	editor := writeScript(t, dir, "edit", `for f; do :; done
+grep -q "^draft$" "$f" || exit 3
+{ echo "# Plan"; cat "$f"; echo "ignored below"; } > "$f.new" && mv "$f.new" "$f"
+`)
+	text, err := editText(context.Background(), editor+" --wait", "  draft  ", strings.NewReader(""), os.Stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "# Plan\ndraft" {
+		t.Fatalf("text = %q", text)
+	}
+
+	empty := writeScript(t, dir, "empty", `: > "$1"`)
+	if _, err := editText(context.Background(), empty, "", strings.NewReader(""), os.Stdout); err == nil || !strings.Contains(err.Error(), "empty prompt") {
+		t.Fatalf("err = %v", err)
+	}
+	failing := writeScript(t, dir, "fail", "exit 1\n")
+	if _, err := editText(context.Background(), failing, "x", strings.NewReader(""), os.Stdout); err == nil {
+		t.Fatal("expected an error when the editor fails")
+	}
+}
+
+func TestClipboardTools(t *testing.T) {
+	names := func(tools []clipboardTool) string {
+		var out []string
+		for _, tool := range tools {
+			out = append(out, tool.name)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := names(clipboardTools("linux", true, true)); got != "wl-copy,xclip,xsel" {
+		t.Fatalf("wayland write = %s", got)
+	}
+	if got := names(clipboardTools("linux", false, false)); got != "xclip,xsel" {
+		t.Fatalf("x11 read = %s", got)
+	}
+	if got := names(clipboardTools("darwin", false, false)); got != "pbpaste" {
+		t.Fatalf("darwin read = %s", got)
+	}
+}
+
+func TestClipboardRoundTrip(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses a fake xclip")
+	}
+	dir := t.TempDir()
+	store := filepath.Join(dir, "clipboard")
+	writeScript(t, dir, "xclip", `case "$3" in
+-in) cat > "`+store+`" ;;
+-out) cat "`+store+`" ;;
+*) exit 2 ;;
+esac
+`)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	if err := writeClipboard(context.Background(), "copied text"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readClipboard(context.Background())
+	if err != nil || got != "copied text" {
+		t.Fatalf("readClipboard = %q, %v", got, err)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if _, err := readClipboard(context.Background()); err == nil || !strings.Contains(err.Error(), "xclip, xsel") {
+		t.Fatalf("err = %v", err)
+	}
+}
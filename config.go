@@ -21,9 +21,17 @@ type cliProfile struct {
 	ProjectID    string   `toml:"project_id,omitempty"`
 	Output       string   `toml:"output,omitempty"`
 	Model        string   `toml:"model,omitempty"`
+	Fallbacks    []string `toml:"fallbacks,omitempty"`
 	AllowAll     bool     `toml:"allow_all,omitempty"`
 	Allow        []string `toml:"allow,omitempty"`
 	Trace        bool     `toml:"trace,omitempty"`
+	// Approve = "ask" prompts before bash commands no allow rule covers
+	// (see bashApprover).
+	Approve string `toml:"approve,omitempty"`
+	// AllowURLAttachments lets -a take http(s) URLs (see fetchURLAttachments).
+	AllowURLAttachments bool `toml:"allow_url_attachments,omitempty"`
+	// IgnoreDirs are skipped when directory and glob attachments are expanded.
+	IgnoreDirs []string `toml:"ignore_dirs,omitempty"`
 }
 
 func loadCLIConfig() (cliConfig, error) {
@@ -47,6 +55,21 @@ func defaultConfigPath() (string, error) {
 	return filepath.Join(dir, "mrl", "config.toml"), nil
 }
 
+// defaultDataDir is where mrl keeps local state (sessions and similar
+// records) that is not configuration.
+func defaultDataDir() (string, error) {
+	// Respect XDG_DATA_HOME if set, otherwise use ~/.local/share
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "mrl"), nil
+}
+
 func readCLIConfig(path string) (cliConfig, error) {
 	if strings.TrimSpace(path) == "" {
 		return cliConfig{}, nil
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/generated"
+)
+
+// errCostBudgetExceeded is returned when a run would exceed --max-cost.
+var errCostBudgetExceeded = errors.New("cost budget exceeded")
+
+// modelPricing is the per-token pricing of a model as reported by
+// /responses/resolve. Known is false when the catalog has no prices for it.
+type modelPricing struct {
+	Model                     string  `json:"model"`
+	InputCostPerMillionCents  float64 `json:"input_cost_per_million_cents"`
+	OutputCostPerMillionCents float64 `json:"output_cost_per_million_cents"`
+	PlatformFeePercent        float64 `json:"platform_fee_percent"`
+	Known                     bool    `json:"known"`
+}
+
+// pricingCacheTTL bounds how long resolved pricing is reused from disk, so
+// routine prompts do not pay an extra /responses/resolve round trip.
+const pricingCacheTTL = 24 * time.Hour
+
+type cachedPricing struct {
+	Pricing   modelPricing `json:"pricing"`
+	FetchedAt time.Time    `json:"fetched_at"`
+}
+
+func pricingFromResolution(resolved generated.ResponseResolveResponse) modelPricing {
+	pricing := resolved.Pricing
+	out := modelPricing{Model: resolved.ResolvedModel}
+	if pricing.InputCostPerMillionCents == nil || pricing.OutputCostPerMillionCents == nil {
+		return out
+	}
+	out.InputCostPerMillionCents = float64(*pricing.InputCostPerMillionCents)
+	out.OutputCostPerMillionCents = float64(*pricing.OutputCostPerMillionCents)
+	if pricing.PlatformFeePercent != nil {
+		out.PlatformFeePercent = float64(*pricing.PlatformFeePercent)
+	}
+	out.Known = true
+	return out
+}
+
+// costCents estimates the cost of one call, including the platform fee.
+func (p modelPricing) costCents(inputTokens, outputTokens int64) float64 {
+	base := (float64(inputTokens)*p.InputCostPerMillionCents + float64(outputTokens)*p.OutputCostPerMillionCents) / 1_000_000
+	return base * (1 + p.PlatformFeePercent/100)
+}
+
+// costTracker accumulates the estimated spend of one invocation and enforces
+// an optional budget. Pricing is resolved once per model and cached; a model
+// whose pricing cannot be resolved makes the total incomplete rather than
+// failing the run, unless a budget has to be enforced.
+//
+// Every call is priced, since each usage ledger record carries its cost;
+// pricing comes from the disk cache for pricingCacheTTL, so this rarely costs
+// a /responses/resolve round trip. Whether the spend is shown is up to the
+// command.
+type costTracker struct {
+	cfg      runtimeConfig
+	maxCents float64
+
+	mu       sync.Mutex
+	pricing  map[string]modelPricing
+	spent    float64
+	lastCall float64
+	unpriced []string
+}
+
+// newCostTracker returns a tracker for a budget of maxCents (0 for none).
+func newCostTracker(cfg runtimeConfig, maxCents float64) (*costTracker, error) {
+	if maxCents < 0 {
+		return nil, errors.New("max-cost must be >= 0")
+	}
+	return &costTracker{cfg: cfg, maxCents: maxCents, pricing: map[string]modelPricing{}}, nil
+}
+
+func (t *costTracker) pricingFor(ctx context.Context, model string) modelPricing {
+	model = strings.TrimSpace(model)
+	t.mu.Lock()
+	cached, ok := t.pricing[model]
+	t.mu.Unlock()
+	if ok {
+		return cached
+	}
+	pricing := modelPricing{Model: model}
+	if model != "" {
+		cacheKey := t.cfg.BaseURL + " " + model
+		if cached, ok := loadCachedPricing(cacheKey, time.Now()); ok {
+			pricing = cached
+		} else if resolved, err := requestResponseResolution(ctx, t.cfg, model, ""); err == nil {
+			pricing = pricingFromResolution(resolved)
+			saveCachedPricing(cacheKey, pricing)
+		}
+	}
+	t.mu.Lock()
+	t.pricing[model] = pricing
+	t.mu.Unlock()
+	return pricing
+}
+
+// record adds the cost of one call and returns it. ok is false when the
+// model's pricing is unknown.
+func (t *costTracker) record(ctx context.Context, model string, usage sdk.Usage) (float64, bool) {
+	if t == nil {
+		return 0, false
+	}
+	pricing := t.pricingFor(ctx, model)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !pricing.Known {
+		if !containsString(t.unpriced, model) {
+			t.unpriced = append(t.unpriced, model)
+		}
+		return 0, false
+	}
+	cost := pricing.costCents(usage.InputTokens, usage.OutputTokens)
+	t.spent += cost
+	t.lastCall = cost
+	return cost, true
+}
+
+// total returns the estimated spend so far; complete is false when some
+// calls used a model without known pricing.
+func (t *costTracker) total() (cents float64, complete bool) {
+	if t == nil {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.spent, len(t.unpriced) == 0
+}
+
+// checkInput refuses a call whose estimated input alone would push the spend
+// over the budget. It is a pre-flight guard; output cost is unknown up front.
+func (t *costTracker) checkInput(ctx context.Context, model string, estimatedInputTokens int64) error {
+	if t == nil || t.maxCents <= 0 {
+		return nil
+	}
+	pricing := t.pricingFor(ctx, model)
+	if !pricing.Known {
+		return fmt.Errorf("cannot enforce --max-cost: no pricing available for model %s", model)
+	}
+	projected := pricing.costCents(estimatedInputTokens, 0)
+	t.mu.Lock()
+	spent := t.spent
+	t.mu.Unlock()
+	if spent+projected > t.maxCents {
+		return fmt.Errorf("%w: projected %s (about %d input tokens) exceeds --max-cost %s",
+			errCostBudgetExceeded, formatCents(spent+projected), estimatedInputTokens, formatCents(t.maxCents))
+	}
+	return nil
+}
+
+// checkNext stops a multi-call run before the next call when the spend so far
+// plus the cost of the previous call would exceed the budget.
+func (t *costTracker) checkNext() error {
+	if t == nil || t.maxCents <= 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.unpriced) > 0 {
+		return fmt.Errorf("cannot enforce --max-cost: no pricing available for model %s", strings.Join(t.unpriced, ", "))
+	}
+	if projected := t.spent + t.lastCall; projected > t.maxCents {
+		return fmt.Errorf("%w: spent %s, next call projected to reach %s (--max-cost %s)",
+			errCostBudgetExceeded, formatCents(t.spent), formatCents(projected), formatCents(t.maxCents))
+	}
+	return nil
+}
+
+// summary renders the spend for usage lines, e.g. "0.0421¢".
+func (t *costTracker) summary() string {
+	cents, complete := t.total()
+	if t == nil || (!complete && cents == 0) {
+		return "unavailable"
+	}
+	if !complete {
+		return formatCents(cents) + " (partial)"
+	}
+	return formatCents(cents)
+}
+
+// jsonCents returns the spend for JSON output, or nil when it is unknown.
+func (t *costTracker) jsonCents() *float64 {
+	cents, complete := t.total()
+	if !complete {
+		return nil
+	}
+	return &cents
+}
+
+// warnIfOverBudget reports a finished run whose actual spend ended up above
+// the budget (a single call cannot be stopped midway).
+func (t *costTracker) warnIfOverBudget() {
+	if t == nil || t.maxCents <= 0 {
+		return
+	}
+	if cents, _ := t.total(); cents > t.maxCents {
+		fmt.Fprintf(os.Stderr, "warning: estimated cost %s exceeded --max-cost %s\n", formatCents(cents), formatCents(t.maxCents))
+	}
+}
+
+func pricingCachePath() (string, error) {
+	dir, err := defaultDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pricing.json"), nil
+}
+
+func readPricingCache() map[string]cachedPricing {
+	path, err := pricingCachePath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // cache lives under the mrl data dir
+	if err != nil {
+		return nil
+	}
+	var cache map[string]cachedPricing
+	if json.Unmarshal(data, &cache) != nil {
+		return nil
+	}
+	return cache
+}
+
+func loadCachedPricing(key string, now time.Time) (modelPricing, bool) {
+	entry, ok := readPricingCache()[key]
+	if !ok || now.Sub(entry.FetchedAt) > pricingCacheTTL {
+		return modelPricing{}, false
+	}
+	return entry.Pricing, true
+}
+
+// saveCachedPricing stores resolved pricing on a best-effort basis; a cache
+// write failure only costs a resolve call next time.
+func saveCachedPricing(key string, pricing modelPricing) {
+	path, err := pricingCachePath()
+	if err != nil {
+		return
+	}
+	cache := readPricingCache()
+	if cache == nil {
+		cache = map[string]cachedPricing{}
+	}
+	cache[key] = cachedPricing{Pricing: pricing, FetchedAt: time.Now().UTC()}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0o700) != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pricing-*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+	if os.Rename(tmp.Name(), path) != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+func formatCents(cents float64) string {
+	return fmt.Sprintf("%.4f¢", cents)
+}
+
+// estimateInputTokens is a rough upper-bound token count for pre-flight
+// budget checks (about four bytes per token of the encoded request).
+func estimateInputTokens(input any) int64 {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data)+3) / 4
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+)
+
+func TestModelPricingCostCents(t *testing.T) {
+	pricing := modelPricing{InputCostPerMillionCents: 300, OutputCostPerMillionCents: 1500, PlatformFeePercent: 10, Known: true}
+	got := pricing.costCents(1_000_000, 100_000)
+	want := (300.0 + 150.0) * 1.1
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("costCents = %v, want %v", got, want)
+	}
+}
+
+func newTestCostTracker(t *testing.T, maxCents float64, pricing ...modelPricing) *costTracker {
+	t.Helper()
+	tracker, err := newCostTracker(runtimeConfig{}, maxCents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pricing {
+		tracker.pricing[p.Model] = p
+	}
+	return tracker
+}
+
+func TestCostTrackerBudget(t *testing.T) {
+	ctx := context.Background()
+	tracker := newTestCostTracker(t, 1, modelPricing{Model: "m", InputCostPerMillionCents: 1_000_000, OutputCostPerMillionCents: 1_000_000, Known: true})
+
+	if err := tracker.checkInput(ctx, "m", 2); !errors.Is(err, errCostBudgetExceeded) {
+		t.Fatalf("expected pre-flight refusal, got %v", err)
+	}
+	if err := tracker.checkInput(ctx, "m", 1); err != nil {
+		t.Fatalf("unexpected pre-flight error: %v", err)
+	}
+
+	if cost, ok := tracker.record(ctx, "m", sdk.Usage{InputTokens: 0, OutputTokens: 0}); !ok || cost != 0 {
+		t.Fatalf("unexpected record result %v %v", cost, ok)
+	}
+	if err := tracker.checkNext(); err != nil {
+		t.Fatalf("unexpected budget error: %v", err)
+	}
+	tracker.record(ctx, "m", sdk.Usage{InputTokens: 0, OutputTokens: 1})
+	if err := tracker.checkNext(); !errors.Is(err, errCostBudgetExceeded) {
+		t.Fatalf("expected next call to exceed budget, got %v", err)
+	}
+	if got := tracker.summary(); got != "1.0000¢" {
+		t.Fatalf("unexpected summary %q", got)
+	}
+}
+
+func TestCostTrackerUnpricedModel(t *testing.T) {
+	ctx := context.Background()
+	tracker := newTestCostTracker(t, 0, modelPricing{Model: "priced", InputCostPerMillionCents: 100, OutputCostPerMillionCents: 100, Known: true}, modelPricing{Model: "unknown"})
+
+	tracker.record(ctx, "unknown", sdk.Usage{InputTokens: 10})
+	if got := tracker.summary(); got != "unavailable" {
+		t.Fatalf("unexpected summary %q", got)
+	}
+	if tracker.jsonCents() != nil {
+		t.Fatal("expected no JSON cost when pricing is unknown")
+	}
+	if err := tracker.checkNext(); err != nil {
+		t.Fatalf("unbudgeted tracker must not fail: %v", err)
+	}
+
+	tracker.record(ctx, "priced", sdk.Usage{InputTokens: 1_000_000})
+	if got := tracker.summary(); got != "100.0000¢ (partial)" {
+		t.Fatalf("unexpected summary %q", got)
+	}
+
+	budgeted := newTestCostTracker(t, 5, modelPricing{Model: "unknown"})
+	budgeted.record(ctx, "unknown", sdk.Usage{InputTokens: 10})
+	if err := budgeted.checkNext(); err == nil {
+		t.Fatal("expected error when budget cannot be enforced")
+	}
+}
+
+func TestCostTrackerPricesWithoutBudgetFromCache(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	saveCachedPricing(" m", modelPricing{Model: "m", InputCostPerMillionCents: 100, OutputCostPerMillionCents: 100, Known: true})
+
+	// No budget and nothing shown: the ledger still needs the cost, and the
+	// cached pricing means no resolve call is made.
+	tracker, err := newCostTracker(runtimeConfig{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost, ok := tracker.record(context.Background(), "m", sdk.Usage{InputTokens: 1_000_000})
+	if !ok || math.Abs(cost-100) > 1e-9 {
+		t.Fatalf("record = %v, %v; want 100, true", cost, ok)
+	}
+	if cents := tracker.jsonCents(); cents == nil || math.Abs(*cents-100) > 1e-9 {
+		t.Fatalf("jsonCents = %v, want 100", cents)
+	}
+}
+
+func TestNilCostTracker(t *testing.T) {
+	var tracker *costTracker
+	if err := tracker.checkInput(context.Background(), "m", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.checkNext(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tracker.record(context.Background(), "m", sdk.Usage{}); ok {
+		t.Fatal("nil tracker must not record")
+	}
+	if tracker.summary() != "unavailable" || tracker.jsonCents() != nil {
+		t.Fatal("nil tracker must report unavailable cost")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+// dryRunResult is the bash result the model sees for every command under
+// --dry-run.
+const dryRunResult = "command not executed (dry run); assume it succeeded and continue"
+
+// dryRunInstructions tell the model its commands are only being recorded.
+const dryRunInstructions = `This is a dry run. Your bash commands are recorded for review and are not executed, so their results carry no output. Propose the complete sequence of commands that would finish the task, without commands that only inspect state, then summarize the plan in one or two sentences.`
+
+// doPlan is what `mrl do --dry-run` proposes and `mrl do --apply` runs.
+type doPlan struct {
+	Task      string       `json:"task"`
+	Model     string       `json:"model,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	Summary   string       `json:"summary,omitempty"`
+	Steps     []doPlanStep `json:"steps"`
+}
+
+// doPlanStep is one proposed command. Call is the model's original tool
+// call, which --apply replays with Command, so editing Command in the saved
+// plan changes what runs.
+type doPlanStep struct {
+	Command string       `json:"command"`
+	Call    llm.ToolCall `json:"call"`
+}
+
+// doPlanner is the bash tool handler under --dry-run.
+type doPlanner struct {
+	mu      sync.Mutex
+	steps   []doPlanStep
+	summary string
+}
+
+func (p *doPlanner) registry() *sdk.ToolRegistry {
+	registry := sdk.NewToolRegistry()
+	registry.Register(sdk.ToolNameBash, p.handle)
+	return registry
+}
+
+func (p *doPlanner) handle(args map[string]any, call llm.ToolCall) (any, error) {
+	command, _ := args["command"].(string)
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil, errors.New("command is required")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, doPlanStep{Command: command, Call: call})
+	return sdk.BashResult{Output: dryRunResult}, nil
+}
+
+// setSummary keeps the model's closing text as the plan summary.
+func (p *doPlanner) setSummary(text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.summary = strings.TrimSpace(text)
+}
+
+func (p *doPlanner) plan(task, model string, now time.Time) doPlan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return doPlan{Task: task, Model: model, CreatedAt: now.UTC(), Summary: p.summary, Steps: append([]doPlanStep(nil), p.steps...)}
+}
+
+// printDoPlan lists the proposed commands.
+func printDoPlan(w io.Writer, plan doPlan, savedTo string) {
+	if len(plan.Steps) == 0 {
+		_, _ = fmt.Fprintln(w, "\nNo commands proposed.")
+		return
+	}
+	_, _ = fmt.Fprintf(w, "\nProposed commands (not executed):\n")
+	for i, step := range plan.Steps {
+		_, _ = fmt.Fprintf(w, "%3d. %s\n", i+1, step.Command)
+	}
+	if savedTo != "" {
+		_, _ = fmt.Fprintf(w, "\nPlan saved to %s; run it with: mrl do --apply %s\n", savedTo, savedTo)
+	} else {
+		_, _ = fmt.Fprintln(w, "\nSave it with --save-plan <file> and run it later with mrl do --apply <file>.")
+	}
+}
+
+func saveDoPlan(path string, plan doPlan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+func loadDoPlan(path string) (doPlan, error) {
+	raw, err := os.ReadFile(path) //nolint:gosec // plan path is explicitly selected by the CLI user
+	if err != nil {
+		return doPlan{}, err
+	}
+	var plan doPlan
+	if err := json.Unmarshal(raw, &plan); err != nil {
+		return doPlan{}, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if len(plan.Steps) == 0 {
+		return doPlan{}, fmt.Errorf("plan %s has no steps", path)
+	}
+	for i, step := range plan.Steps {
+		if strings.TrimSpace(step.Command) == "" {
+			return doPlan{}, fmt.Errorf("plan step %d has no command", i+1)
+		}
+		if step.Call.Function == nil {
+			return doPlan{}, fmt.Errorf("plan step %d has no tool call; create plans with mrl do --dry-run --save-plan", i+1)
+		}
+	}
+	return plan, nil
+}
+
+// applyDoPlan runs the plan's commands in order through registry and stops
+// at the first one that fails.
+func applyDoPlan(registry *sdk.ToolRegistry, plan doPlan) error {
+	if task := strings.TrimSpace(plan.Task); task != "" {
+		fmt.Printf("Applying plan for %q (%d commands)\n", task, len(plan.Steps))
+	}
+	for i, step := range plan.Steps {
+		fmt.Printf("\033[1;36m→ %s\033[0m\n", step.Command)
+		results := registry.ExecuteAll([]llm.ToolCall{withBashCommand(step.Call, step.Command)})
+		if len(results) == 0 {
+			return fmt.Errorf("step %d returned no result", i+1)
+		}
+		failure := ""
+		switch r := results[0].Result.(type) {
+		case sdk.BashResult:
+			printBashResult(r)
+			failure = r.Error
+		case bashApprovalResult:
+			printBashResult(r.BashResult)
+			failure = r.BashResult.Error
+		}
+		if results[0].Error != nil {
+			fmt.Printf("\033[31merror: %s\033[0m\n", results[0].Error)
+			failure = results[0].Error.Error()
+		}
+		if failure != "" {
+			return fmt.Errorf("step %d of %d failed (%s); later steps were not run", i+1, len(plan.Steps), failure)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+func TestDoPlannerRecordsCommands(t *testing.T) {
+	planner := &doPlanner{}
+	for _, command := range []string{"  go get -u ./...  ", "go mod tidy"} {
+		result, err := planner.handle(map[string]any{"command": command}, llm.ToolCall{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r := result.(sdk.BashResult); r.Output != dryRunResult || r.Error != "" {
+			t.Fatalf("result = %+v", r)
+		}
+	}
+	if _, err := planner.handle(map[string]any{}, llm.ToolCall{}); err == nil {
+		t.Fatal("expected an error for a missing command")
+	}
+	planner.setSummary(" Upgrade and tidy.\n")
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	plan := planner.plan("upgrade deps", "claude-sonnet-5", now)
+	if len(plan.Steps) != 2 || plan.Steps[0].Command != "go get -u ./..." || plan.Summary != "Upgrade and tidy." || !plan.CreatedAt.Equal(now) {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	var out strings.Builder
+	printDoPlan(&out, plan, "plan.json")
+	for _, want := range []string{"  1. go get -u ./...\n", "  2. go mod tidy\n", "mrl do --apply plan.json"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("printed plan lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestLoadDoPlanRejectsIncompletePlans(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"no steps":   `{"task":"x","steps":[]}`,
+		"no command": `{"task":"x","steps":[{"command":" "}]}`,
+		"no call":    `{"task":"x","steps":[{"command":"make"}]}`,
+		"not json":   `steps:`,
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadDoPlan(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := loadDoPlan(filepath.Join(dir, "missing.json")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
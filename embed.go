@@ -0,0 +1,566 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// embedCapability selects embedding models from the catalog when --model
+	// is not set, as in `mrl model list --capability embedding`.
+	embedCapability = "embedding"
+	// embedBatchSize is how many inputs are sent per /embeddings request.
+	embedBatchSize = 64
+	// defaultEmbedChunkTokens is the default chunk size for file inputs.
+	defaultEmbedChunkTokens = 512
+)
+
+type embedFlags struct {
+	model       string
+	files       []string
+	output      string
+	format      string
+	table       string
+	chunkTokens int64
+	dimensions  int
+	pythonPath  string
+}
+
+type embedSearchFlags struct {
+	index      string
+	format     string
+	table      string
+	model      string
+	dimensions int
+	top        int
+	minScore   float64
+	context    bool
+	pythonPath string
+}
+
+type embeddingsRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type embeddingsResponse struct {
+	Model string `json:"model"`
+	Data  []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		InputTokens int64 `json:"input_tokens"`
+		TotalTokens int64 `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// embeddingRecord is one embedded input: a line, a text argument or a chunk
+// of a file. It is the row format of every index.
+type embeddingRecord struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source,omitempty"`
+	Text      string    `json:"text"`
+	Model     string    `json:"model,omitempty"`
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// embedSearchResult is one hit of mrl embed search.
+type embedSearchResult struct {
+	Rank   int     `json:"rank"`
+	Score  float64 `json:"score"`
+	ID     string  `json:"id"`
+	Source string  `json:"source,omitempty"`
+	Text   string  `json:"text"`
+}
+
+func newEmbedCmd() *cobra.Command {
+	flags := &embedFlags{}
+	cmd := &cobra.Command{
+		Use:   "embed [text]",
+		Short: "Generate embeddings for text, stdin lines or files",
+		Long: `Generate embeddings and write them as JSONL, a NumPy .npy matrix or a
+SQLite table.
+
+Inputs are the text argument, each non-empty line of piped stdin, or the files
+given with -f (directories and globs expand like -a attachments; documents are
+converted to text as with --extract). Files are split into chunks of about
+--chunk-tokens tokens, with ids like path#L1-40.
+
+The output format follows the -o extension (.npy, .db/.sqlite/.sqlite3,
+otherwise JSONL) unless --format is set. Without -o, JSONL goes to stdout.
+A .npy matrix gets a .meta.jsonl sidecar with the id and text of each row.
+SQLite output upserts rows by id and needs python3.
+
+Examples:
+  mrl embed "How do I rotate an API key?"
+  cat faq.txt | mrl embed -o faq.jsonl
+  mrl embed -f ./docs/ -o docs.db
+  mrl embed search "rotate keys" --index docs.db`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEmbed(cmd, args, flags)
+		},
+	}
+	cmd.Flags().StringVar(&flags.model, "model", "", "Embedding model (default: first embedding model in the catalog)")
+	cmd.Flags().StringArrayVarP(&flags.files, "file", "f", nil, "Embed a file, directory or glob (repeatable; - reads stdin as one document)")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output file (default: JSONL on stdout)")
+	cmd.Flags().StringVar(&flags.format, "format", "", "Output format: jsonl, npy or sqlite (default: from the -o extension)")
+	cmd.Flags().StringVar(&flags.table, "table", "embeddings", "SQLite table name")
+	cmd.Flags().Int64Var(&flags.chunkTokens, "chunk-tokens", defaultEmbedChunkTokens, "Approximate tokens per file chunk (0 embeds whole files)")
+	cmd.Flags().IntVar(&flags.dimensions, "dimensions", 0, "Requested embedding dimensions, for models that support it")
+	cmd.Flags().StringVar(&flags.pythonPath, "python", "", "Python executable for SQLite output (default: python3)")
+	cmd.AddCommand(newEmbedSearchCmd())
+	return cmd
+}
+
+func newEmbedSearchCmd() *cobra.Command {
+	flags := &embedSearchFlags{}
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Find the stored embeddings closest to a query",
+		Long: `Embed a query and rank the records of an index by cosine similarity.
+
+The query is embedded with the model stored in the index unless --model is
+set. --context prints the matching texts as <file> blocks, ready to attach to
+mrl or mrl rlm.
+
+Examples:
+  mrl embed search "rotate keys" --index docs.db
+  mrl embed search "billing limits" --index faq.jsonl --top 3 --json
+  mrl embed search "retry policy" --index docs.db --context > context.md
+  mrl rlm "Summarize our retry policy" -a context.md`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEmbedSearch(cmd, strings.Join(args, " "), flags)
+		},
+	}
+	cmd.Flags().StringVar(&flags.index, "index", "", "Index file written by mrl embed (required)")
+	cmd.Flags().StringVar(&flags.format, "format", "", "Index format: jsonl, npy or sqlite (default: from the extension)")
+	cmd.Flags().StringVar(&flags.table, "table", "embeddings", "SQLite table name")
+	cmd.Flags().StringVar(&flags.model, "model", "", "Embedding model for the query (default: the index model)")
+	cmd.Flags().IntVar(&flags.dimensions, "dimensions", 0, "Query embedding dimensions; match the --dimensions the index was built with")
+	cmd.Flags().IntVar(&flags.top, "top", 5, "Number of results")
+	cmd.Flags().Float64Var(&flags.minScore, "min-score", 0, "Drop results below this cosine similarity")
+	cmd.Flags().BoolVar(&flags.context, "context", false, "Print matching texts as <file> blocks")
+	cmd.Flags().StringVar(&flags.pythonPath, "python", "", "Python executable for SQLite indexes (default: python3)")
+	_ = cmd.MarkFlagRequired("index")
+	return cmd
+}
+
+func runEmbed(cmd *cobra.Command, args []string, flags *embedFlags) error {
+	cfg, err := runtimeConfigFrom(cmd)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(cfg.APIKey) == "" {
+		return errors.New("api key required")
+	}
+	format, err := embedFormatFor(flags.format, flags.output)
+	if err != nil {
+		return err
+	}
+	if format != embedFormatJSONL && strings.TrimSpace(flags.output) == "" {
+		return fmt.Errorf("%s output requires -o", format)
+	}
+	if flags.chunkTokens < 0 {
+		return errors.New("--chunk-tokens must not be negative")
+	}
+
+	stdinIsTTY, err := isTerminal(os.Stdin)
+	if err != nil {
+		return err
+	}
+	var stdin io.Reader
+	if !stdinIsTTY {
+		stdin = os.Stdin
+	}
+	records, err := collectEmbedInputs(args, flags.files, stdin, flags.chunkTokens, cfg.IgnoreDirs)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errors.New("nothing to embed: pass text, pipe lines on stdin or use -f")
+	}
+
+	ctx, cancel := contextWithTimeout(cfg.Timeout)
+	defer cancel()
+	model := strings.TrimSpace(flags.model)
+	if model == "" {
+		if model, err = defaultCapabilityModel(ctx, cfg, embedCapability); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	texts := make([]string, len(records))
+	for i := range records {
+		texts[i] = records[i].Text
+	}
+	vectors, servedModel, usage, calls, err := createEmbeddings(ctx, cfg, model, texts, flags.dimensions)
+	recordUsage(ledgerCommandName(cmd), cfg, firstNonEmpty(servedModel, model), calls, usage, time.Since(start), nil)
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		records[i].Model = firstNonEmpty(servedModel, model)
+		records[i].Embedding = vectors[i]
+	}
+
+	if err := writeEmbeddings(format, flags.output, flags.table, flags.pythonPath, records); err != nil {
+		return err
+	}
+	dims := len(records[0].Embedding)
+	if strings.TrimSpace(flags.output) == "" {
+		fmt.Fprintf(os.Stderr, "Model: %s | Inputs: %d | Dimensions: %d | Tokens: %d\n", records[0].Model, len(records), dims, usage.InputTokens)
+		return nil
+	}
+	if cfg.Output == outputFormatJSON {
+		printJSON(map[string]any{
+			"model":      records[0].Model,
+			"output":     flags.output,
+			"format":     format,
+			"records":    len(records),
+			"dimensions": dims,
+			"usage":      map[string]int64{"input_tokens": usage.InputTokens},
+		})
+		return nil
+	}
+	fmt.Printf("Wrote %d embeddings (%d dimensions) to %s\n", len(records), dims, flags.output)
+	fmt.Fprintf(os.Stderr, "Model: %s | Tokens: %d\n", records[0].Model, usage.InputTokens)
+	return nil
+}
+
+func runEmbedSearch(cmd *cobra.Command, query string, flags *embedSearchFlags) error {
+	cfg, err := runtimeConfigFrom(cmd)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(cfg.APIKey) == "" {
+		return errors.New("api key required")
+	}
+	if strings.TrimSpace(query) == "" {
+		return errors.New("query is required")
+	}
+	if flags.top < 1 {
+		return errors.New("--top must be at least 1")
+	}
+	format, err := embedFormatFor(flags.format, flags.index)
+	if err != nil {
+		return err
+	}
+	records, err := readEmbeddings(format, flags.index, flags.table, flags.pythonPath)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%s has no embeddings", flags.index)
+	}
+
+	ctx, cancel := contextWithTimeout(cfg.Timeout)
+	defer cancel()
+	model := firstNonEmpty(strings.TrimSpace(flags.model), records[0].Model)
+	if model == "" {
+		if model, err = defaultCapabilityModel(ctx, cfg, embedCapability); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	vectors, servedModel, usage, calls, err := createEmbeddings(ctx, cfg, model, []string{query}, flags.dimensions)
+	recordUsage(ledgerCommandName(cmd), cfg, firstNonEmpty(servedModel, model), calls, usage, time.Since(start), nil)
+	if err != nil {
+		return err
+	}
+	results, err := searchEmbeddings(records, vectors[0], flags.top, flags.minScore)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cfg.Output == outputFormatJSON:
+		printJSON(map[string]any{"query": query, "model": firstNonEmpty(servedModel, model), "results": results})
+	case flags.context:
+		for _, result := range results {
+			fmt.Println(formatContextFile(result.ID, result.Text, ""))
+		}
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "RANK\tSCORE\tID\tTEXT")
+		for _, result := range results {
+			_, _ = fmt.Fprintf(w, "%d\t%.4f\t%s\t%s\n", result.Rank, result.Score, result.ID, snippet(result.Text, 60))
+		}
+		_ = w.Flush()
+	}
+	return nil
+}
+
+// createEmbeddings embeds texts in batches, returning one vector per text in
+// order. dimensions is passed through when positive.
+func createEmbeddings(ctx context.Context, cfg runtimeConfig, model string, texts []string, dimensions int) ([][]float32, string, sdk.Usage, int, error) {
+	vectors := make([][]float32, 0, len(texts))
+	var (
+		usage       sdk.Usage
+		servedModel string
+		calls       int
+	)
+	for start := 0; start < len(texts); start += embedBatchSize {
+		batch := texts[start:min(start+embedBatchSize, len(texts))]
+		var resp embeddingsResponse
+		err := postModelRoute(ctx, cfg, "/embeddings", embeddingsRequest{
+			Model:      model,
+			Input:      batch,
+			Dimensions: dimensions,
+		}, &resp)
+		if err != nil {
+			return nil, servedModel, usage, calls, err
+		}
+		calls++
+		servedModel = firstNonEmpty(resp.Model, servedModel)
+		usage.InputTokens += resp.Usage.InputTokens
+		usage.TotalTokens += firstNonZero(resp.Usage.TotalTokens, resp.Usage.InputTokens)
+		if len(resp.Data) != len(batch) {
+			return nil, servedModel, usage, calls, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(resp.Data))
+		}
+		ordered := make([][]float32, len(batch))
+		for _, item := range resp.Data {
+			if item.Index < 0 || item.Index >= len(batch) || len(item.Embedding) == 0 {
+				return nil, servedModel, usage, calls, fmt.Errorf("invalid embedding at index %d", item.Index)
+			}
+			ordered[item.Index] = item.Embedding
+		}
+		vectors = append(vectors, ordered...)
+	}
+	return vectors, servedModel, usage, calls, nil
+}
+
+func firstNonZero(values ...int64) int64 {
+	for _, value := range values {
+		if value != 0 {
+			return value
+		}
+	}
+	return 0
+}
+
+// collectEmbedInputs turns the text argument, piped stdin lines and -f files
+// into records without embeddings. Piped stdin is only read as lines when no
+// text and no -f - were given.
+func collectEmbedInputs(args, files []string, stdin io.Reader, chunkTokens int64, ignoreDirs []string) ([]embeddingRecord, error) {
+	var records []embeddingRecord
+	if text := strings.TrimSpace(strings.Join(args, " ")); text != "" {
+		records = append(records, embeddingRecord{ID: textID(text), Text: text})
+	}
+
+	expanded, err := expandAttachmentPaths(files, ignoreDirs)
+	if err != nil {
+		return nil, err
+	}
+	for _, skipped := range expanded.Skipped {
+		fmt.Fprintf(os.Stderr, "warning: skipped %s\n", skipped)
+	}
+	var documents []contextFile
+	explicit := map[string]bool{}
+	for _, raw := range expanded.Paths {
+		path := strings.TrimSpace(raw)
+		if path == "" {
+			continue
+		}
+		if isURLAttachment(path) {
+			return nil, fmt.Errorf("%s: URLs are not supported by mrl embed", path)
+		}
+		var (
+			data []byte
+			name = path
+		)
+		if path == "-" {
+			if stdin == nil {
+				return nil, errors.New("-f - needs piped stdin")
+			}
+			name = "stdin"
+			data, err = io.ReadAll(stdin)
+			stdin = nil
+		} else {
+			data, err = readExtractSource(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		mimeType := llm.NormalizeMimeType(detectMimeType(path, data))
+		text, ok, err := extractText(name, mimeType, data)
+		switch {
+		case err != nil:
+			return nil, err
+		case !ok:
+			return nil, fmt.Errorf("%s has no extractable text (%s)", path, mimeType)
+		}
+		documents = append(documents, contextFile{Path: name, Data: []byte(text)})
+		explicit[filepath.Clean(path)] = true
+	}
+	for _, file := range expanded.Files {
+		if !explicit[filepath.Clean(file.Path)] {
+			documents = append(documents, file)
+		}
+	}
+	for _, doc := range documents {
+		name := filepath.ToSlash(doc.Path)
+		chunks := chunkText(string(doc.Data), chunkTokens)
+		for _, chunk := range chunks {
+			id := name
+			if len(chunks) > 1 {
+				id = fmt.Sprintf("%s#L%d-%d", name, chunk.StartLine, chunk.EndLine)
+			}
+			records = append(records, embeddingRecord{ID: id, Source: name, Text: chunk.Text})
+		}
+	}
+
+	if stdin != nil && len(records) == 0 {
+		scanner := bufio.NewScanner(stdin)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxContextFileBytes)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			records = append(records, embeddingRecord{ID: textID(line), Source: "stdin", Text: line})
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read stdin: %w", err)
+		}
+	}
+	return records, nil
+}
+
+// textID derives a stable id for an input without a path, so re-embedding
+// the same text upserts the same SQLite row.
+func textID(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:6])
+}
+
+type textChunk struct {
+	Text      string
+	StartLine int
+	EndLine   int
+}
+
+// chunkText splits text on line boundaries into chunks of at most maxTokens
+// estimated tokens. Longer lines are split on rune boundaries. maxTokens <= 0
+// keeps the text whole. Whitespace-only chunks are dropped.
+func chunkText(text string, maxTokens int64) []textChunk {
+	if maxTokens <= 0 || estimateTextTokens(text) <= maxTokens {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return []textChunk{{Text: text, StartLine: 1, EndLine: max(strings.Count(strings.TrimRight(text, "\n"), "\n")+1, 1)}}
+	}
+	limit := int(maxTokens * 4)
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var (
+		chunks  []textChunk
+		current strings.Builder
+		first   = 1
+	)
+	flush := func(last int) {
+		if strings.TrimSpace(current.String()) != "" {
+			chunks = append(chunks, textChunk{Text: current.String(), StartLine: first, EndLine: last})
+		}
+		current.Reset()
+		first = last + 1
+	}
+	for i, line := range lines {
+		number := i + 1
+		if current.Len() > 0 && current.Len()+len(line) > limit {
+			flush(number - 1)
+		}
+		// A line longer than a chunk is split into chunks of its own.
+		for len(line) > limit {
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = limit
+			}
+			if piece := line[:cut]; strings.TrimSpace(piece) != "" {
+				chunks = append(chunks, textChunk{Text: piece, StartLine: number, EndLine: number})
+			}
+			line = line[cut:]
+		}
+		current.WriteString(line)
+	}
+	flush(len(lines))
+	return chunks
+}
+
+// searchEmbeddings ranks records by cosine similarity to query.
+func searchEmbeddings(records []embeddingRecord, query []float32, top int, minScore float64) ([]embedSearchResult, error) {
+	queryNorm := vectorNorm(query)
+	if queryNorm == 0 {
+		return nil, errors.New("query embedding is empty")
+	}
+	results := make([]embedSearchResult, 0, len(records))
+	for _, record := range records {
+		if len(record.Embedding) != len(query) {
+			return nil, fmt.Errorf("%s has %d dimensions, the query has %d; use the index model and --dimensions", record.ID, len(record.Embedding), len(query))
+		}
+		norm := vectorNorm(record.Embedding)
+		if norm == 0 {
+			continue
+		}
+		var dot float64
+		for i, value := range record.Embedding {
+			dot += float64(value) * float64(query[i])
+		}
+		score := dot / (norm * queryNorm)
+		if score < minScore {
+			continue
+		}
+		results = append(results, embedSearchResult{Score: score, ID: record.ID, Source: record.Source, Text: record.Text})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > top {
+		results = results[:top]
+	}
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+	return results, nil
+}
+
+func vectorNorm(vector []float32) float64 {
+	var sum float64
+	for _, value := range vector {
+		sum += float64(value) * float64(value)
+	}
+	return math.Sqrt(sum)
+}
+
+// snippet returns the first line of text, shortened to width runes.
+func snippet(text string, width int) string {
+	line := strings.TrimSpace(text)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = strings.TrimSpace(line[:i]) + " …"
+	}
+	return truncateRunes(line, width)
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Embedding indexes are stored as JSONL (one embeddingRecord per line), as a
+// NumPy .npy float32 matrix with a .meta.jsonl sidecar holding the id, source,
+// text and model of each row, or as a SQLite table with little-endian float32
+// blobs. mrl has no SQLite driver, so SQLite tables are read and written
+// through python3's sqlite3 module, as the local RLM sandbox does.
+
+const (
+	embedFormatJSONL  = "jsonl"
+	embedFormatNPY    = "npy"
+	embedFormatSQLite = "sqlite"
+)
+
+var sqliteTablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// embedFormatFor resolves --format, falling back to the file extension.
+func embedFormatFor(format, path string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case embedFormatJSONL:
+		return embedFormatJSONL, nil
+	case embedFormatNPY:
+		return embedFormatNPY, nil
+	case embedFormatSQLite:
+		return embedFormatSQLite, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown format %q (want jsonl, npy or sqlite)", format)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".npy":
+		return embedFormatNPY, nil
+	case ".db", ".sqlite", ".sqlite3":
+		return embedFormatSQLite, nil
+	default:
+		return embedFormatJSONL, nil
+	}
+}
+
+func writeEmbeddings(format, path, table, pythonPath string, records []embeddingRecord) error {
+	switch format {
+	case embedFormatNPY:
+		return writeEmbeddingsNPY(path, records)
+	case embedFormatSQLite:
+		return writeEmbeddingsSQLite(path, table, pythonPath, records)
+	}
+	if strings.TrimSpace(path) == "" {
+		return writeEmbeddingsJSONL(os.Stdout, records)
+	}
+	file, err := os.Create(path) //nolint:gosec // output path is chosen by the CLI user
+	if err != nil {
+		return err
+	}
+	if err := writeEmbeddingsJSONL(file, records); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func readEmbeddings(format, path, table, pythonPath string) ([]embeddingRecord, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	switch format {
+	case embedFormatNPY:
+		return readEmbeddingsNPY(path)
+	case embedFormatSQLite:
+		return readEmbeddingsSQLite(path, table, pythonPath)
+	}
+	file, err := os.Open(path) //nolint:gosec // index path is chosen by the CLI user
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return readEmbeddingsJSONL(file, path)
+}
+
+func writeEmbeddingsJSONL(w io.Writer, records []embeddingRecord) error {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	encoder.SetEscapeHTML(false)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+func readEmbeddingsJSONL(r io.Reader, name string) ([]embeddingRecord, error) {
+	var records []embeddingRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var record embeddingRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	return records, nil
+}
+
+// npyMetaPath is the sidecar holding the row metadata of a .npy matrix.
+func npyMetaPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".meta.jsonl"
+}
+
+// writeEmbeddingsNPY writes an (n, dims) little-endian float32 matrix in NumPy
+// format 1.0, loadable with numpy.load, plus the metadata sidecar.
+func writeEmbeddingsNPY(path string, records []embeddingRecord) error {
+	dims := len(records[0].Embedding)
+	for _, record := range records {
+		if len(record.Embedding) != dims {
+			return fmt.Errorf("%s has %d dimensions, expected %d", record.ID, len(record.Embedding), dims)
+		}
+	}
+	var buf bytes.Buffer
+	header := fmt.Sprintf("{'descr': '<f4', 'fortran_order': False, 'shape': (%d, %d), }", len(records), dims)
+	// Magic (6) + version (2) + header length (2) + header, padded to 64 bytes
+	// and terminated by a newline.
+	padding := 64 - (10+len(header)+1)%64
+	if padding == 64 {
+		padding = 0
+	}
+	header += strings.Repeat(" ", padding) + "\n"
+	buf.WriteString("\x93NUMPY\x01\x00")
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	for _, record := range records {
+		_ = binary.Write(&buf, binary.LittleEndian, record.Embedding)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil { //nolint:gosec // output path is chosen by the CLI user
+		return err
+	}
+
+	meta := make([]embeddingRecord, len(records))
+	for i, record := range records {
+		record.Embedding = nil
+		meta[i] = record
+	}
+	file, err := os.Create(npyMetaPath(path)) //nolint:gosec // sidecar of a user-chosen output path
+	if err != nil {
+		return err
+	}
+	if err := writeEmbeddingsJSONL(file, meta); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+var npyShapePattern = regexp.MustCompile(`'shape':\s*\((\d+),\s*(\d+),?\)`)
+
+// readEmbeddingsNPY reads a 2-D little-endian float32 or float64 matrix. Rows
+// are named row-N when the metadata sidecar is missing.
+func readEmbeddingsNPY(path string) ([]embeddingRecord, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // index path is chosen by the CLI user
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 10 || !bytes.HasPrefix(data, []byte("\x93NUMPY")) {
+		return nil, fmt.Errorf("%s is not a .npy file", path)
+	}
+	headerStart, headerLen := 10, int(binary.LittleEndian.Uint16(data[8:10]))
+	if data[6] >= 2 {
+		if len(data) < 12 {
+			return nil, fmt.Errorf("%s is truncated", path)
+		}
+		headerStart, headerLen = 12, int(binary.LittleEndian.Uint32(data[8:12]))
+	}
+	if len(data) < headerStart+headerLen {
+		return nil, fmt.Errorf("%s is truncated", path)
+	}
+	header := string(data[headerStart : headerStart+headerLen])
+	body := data[headerStart+headerLen:]
+	if strings.Contains(header, "'fortran_order': True") {
+		return nil, fmt.Errorf("%s: Fortran-ordered arrays are not supported", path)
+	}
+	width := 0
+	switch {
+	case strings.Contains(header, "'<f4'"):
+		width = 4
+	case strings.Contains(header, "'<f8'"):
+		width = 8
+	default:
+		return nil, fmt.Errorf("%s: only little-endian float32 and float64 matrices are supported", path)
+	}
+	match := npyShapePattern.FindStringSubmatch(header)
+	if match == nil {
+		return nil, fmt.Errorf("%s: expected a 2-D matrix", path)
+	}
+	rows, _ := strconv.Atoi(match[1])
+	dims, _ := strconv.Atoi(match[2])
+	if len(body) < rows*dims*width {
+		return nil, fmt.Errorf("%s is truncated", path)
+	}
+
+	var meta []embeddingRecord
+	if file, err := os.Open(npyMetaPath(path)); err == nil { //nolint:gosec // sidecar of a user-chosen index path
+		meta, err = readEmbeddingsJSONL(file, npyMetaPath(path))
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(meta) != rows {
+			return nil, fmt.Errorf("%s has %d rows, %s has %d", path, rows, npyMetaPath(path), len(meta))
+		}
+	}
+
+	records := make([]embeddingRecord, rows)
+	for row := range rows {
+		if meta != nil {
+			records[row] = meta[row]
+		} else {
+			records[row] = embeddingRecord{ID: fmt.Sprintf("row-%d", row)}
+		}
+		vector := make([]float32, dims)
+		for col := range dims {
+			offset := (row*dims + col) * width
+			if width == 4 {
+				vector[col] = math.Float32frombits(binary.LittleEndian.Uint32(body[offset:]))
+			} else {
+				vector[col] = float32(math.Float64frombits(binary.LittleEndian.Uint64(body[offset:])))
+			}
+		}
+		records[row].Embedding = vector
+	}
+	return records, nil
+}
+
+const sqliteWriteScript = `import json, sqlite3, struct, sys
+conn = sqlite3.connect(sys.argv[1])
+table = sys.argv[2]
+conn.execute('CREATE TABLE IF NOT EXISTS "%s" (id TEXT PRIMARY KEY, source TEXT, text TEXT NOT NULL, model TEXT, dims INTEGER NOT NULL, embedding BLOB NOT NULL)' % table)
+rows = []
+for line in sys.stdin:
+    r = json.loads(line)
+    v = r["embedding"]
+    rows.append((r["id"], r.get("source"), r["text"], r.get("model"), len(v), struct.pack("<%df" % len(v), *v)))
+conn.executemany('INSERT OR REPLACE INTO "%s" (id, source, text, model, dims, embedding) VALUES (?, ?, ?, ?, ?, ?)' % table, rows)
+conn.commit()
+`
+
+const sqliteReadScript = `import json, sqlite3, struct, sys
+conn = sqlite3.connect(sys.argv[1])
+for id, source, text, model, dims, blob in conn.execute('SELECT id, source, text, model, dims, embedding FROM "%s" ORDER BY rowid' % sys.argv[2]):
+    record = {"id": id, "text": text, "embedding": list(struct.unpack("<%df" % dims, blob))}
+    if source:
+        record["source"] = source
+    if model:
+        record["model"] = model
+    sys.stdout.write(json.dumps(record) + "\n")
+`
+
+func writeEmbeddingsSQLite(path, table, pythonPath string, records []embeddingRecord) error {
+	if !sqliteTablePattern.MatchString(table) {
+		return fmt.Errorf("invalid table name %q", table)
+	}
+	var input bytes.Buffer
+	if err := writeEmbeddingsJSONL(&input, records); err != nil {
+		return err
+	}
+	_, err := runSQLiteScript(pythonPath, sqliteWriteScript, path, table, &input)
+	return err
+}
+
+func readEmbeddingsSQLite(path, table, pythonPath string) ([]embeddingRecord, error) {
+	if !sqliteTablePattern.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	out, err := runSQLiteScript(pythonPath, sqliteReadScript, path, table, nil)
+	if err != nil {
+		return nil, err
+	}
+	return readEmbeddingsJSONL(bytes.NewReader(out), path)
+}
+
+func runSQLiteScript(pythonPath, script, path, table string, stdin io.Reader) ([]byte, error) {
+	python := firstNonEmpty(strings.TrimSpace(pythonPath), "python3")
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, path, table) //nolint:gosec // python path is chosen by the CLI user
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			return nil, fmt.Errorf("SQLite indexes need %s: %w", python, err)
+		}
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		return nil, fmt.Errorf("sqlite %s: %s", path, lines[len(lines)-1])
+	}
+	return out, nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func testEmbeddingRecords() []embeddingRecord {
+	return []embeddingRecord{
+		{ID: "a", Source: "docs/a.md", Text: "alpha", Model: "embed-small", Embedding: []float32{1, 0, 0}},
+		{ID: "b", Text: "beta", Model: "embed-small", Embedding: []float32{0.6, 0.8, 0}},
+		{ID: "c", Text: "gamma", Model: "embed-small", Embedding: []float32{0, 0, -2.5}},
+	}
+}
+
+func TestEmbeddingStoresRoundTrip(t *testing.T) {
+	formats := []string{"index.jsonl", "index.npy"}
+	if _, err := exec.LookPath("python3"); err == nil {
+		formats = append(formats, "index.db")
+	}
+	for _, name := range formats {
+		path := filepath.Join(t.TempDir(), name)
+		format, err := embedFormatFor("", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeEmbeddings(format, path, "embeddings", "", testEmbeddingRecords()); err != nil {
+			t.Fatalf("%s: write: %v", name, err)
+		}
+		got, err := readEmbeddings(format, path, "embeddings", "")
+		if err != nil {
+			t.Fatalf("%s: read: %v", name, err)
+		}
+		want := testEmbeddingRecords()
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d records", name, len(got))
+		}
+		for i := range want {
+			if got[i].ID != want[i].ID || got[i].Source != want[i].Source || got[i].Text != want[i].Text || got[i].Model != want[i].Model || !slices.Equal(got[i].Embedding, want[i].Embedding) {
+				t.Fatalf("%s: record %d = %+v, want %+v", name, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestEmbedFormatFor(t *testing.T) {
+	cases := map[[2]string]string{
+		{"", ""}:              embedFormatJSONL,
+		{"", "out.npy"}:       embedFormatNPY,
+		{"", "out.sqlite3"}:   embedFormatSQLite,
+		{"jsonl", "out.db"}:   embedFormatJSONL,
+		{"SQLite", "out.txt"}: embedFormatSQLite,
+	}
+	for in, want := range cases {
+		if got, err := embedFormatFor(in[0], in[1]); err != nil || got != want {
+			t.Fatalf("embedFormatFor(%q, %q) = %q, %v; want %q", in[0], in[1], got, err, want)
+		}
+	}
+	if _, err := embedFormatFor("parquet", ""); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestSearchEmbeddingsRanksByCosine(t *testing.T) {
+	results, err := searchEmbeddings(testEmbeddingRecords(), []float32{2, 0, 0}, 2, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ID != "a" || results[1].ID != "b" || results[1].Rank != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+	if results[0].Score < 0.999 || results[1].Score < 0.599 || results[1].Score > 0.601 {
+		t.Fatalf("scores = %v, %v", results[0].Score, results[1].Score)
+	}
+	if _, err := searchEmbeddings(testEmbeddingRecords(), []float32{1, 0}, 2, 0); err == nil {
+		t.Fatal("expected a dimension mismatch error")
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	text := strings.Repeat("short line\n", 10) + strings.Repeat("x", 50) + "\n"
+	chunks := chunkText(text, 8)
+	if len(chunks) < 3 {
+		t.Fatalf("expected several chunks, got %+v", chunks)
+	}
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != 2 {
+		t.Fatalf("first chunk = %+v", chunks[0])
+	}
+	var joined strings.Builder
+	for _, chunk := range chunks {
+		if len(chunk.Text) > 32 {
+			t.Fatalf("chunk over budget: %+v", chunk)
+		}
+		joined.WriteString(chunk.Text)
+	}
+	if joined.String() != text {
+		t.Fatalf("chunks do not cover the text:\n%s", joined.String())
+	}
+	if last := chunks[len(chunks)-1]; last.StartLine != 11 || last.EndLine != 11 {
+		t.Fatalf("last chunk = %+v", last)
+	}
+
+	if whole := chunkText("one\ntwo\n", 0); len(whole) != 1 || whole[0].EndLine != 2 {
+		t.Fatalf("unchunked = %+v", whole)
+	}
+}
+
+func TestCollectEmbedInputs(t *testing.T) {
+	records, err := collectEmbedInputs(nil, nil, strings.NewReader("first\n\n  second  \n"), 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Text != "first" || records[1].Text != "second" || records[1].Source != "stdin" {
+		t.Fatalf("stdin records = %+v", records)
+	}
+	if records[0].ID != textID("first") {
+		t.Fatalf("expected a content id, got %q", records[0].ID)
+	}
+
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"notes.md": "# Notes\n", "data.csv": "a,b\n1,2\n"})
+	records, err = collectEmbedInputs([]string{"a", "question"}, []string{filepath.Join(root, "data.csv"), root}, strings.NewReader("ignored\n"), 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, record := range records {
+		ids = append(ids, strings.TrimPrefix(record.ID, filepath.ToSlash(root)+"/"))
+	}
+	if !slices.Equal(ids, []string{textID("a question"), "data.csv", "notes.md"}) {
+		t.Fatalf("ids = %v", ids)
+	}
+	if !strings.Contains(records[1].Text, "| a | b |") {
+		t.Fatalf("expected the CSV converted to a table, got %q", records[1].Text)
+	}
+}
+
+func TestCreateEmbeddingsBatchesInOrder(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req embeddingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/embeddings" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var resp embeddingsResponse
+		resp.Model = req.Model
+		resp.Usage.InputTokens = int64(len(req.Input))
+		// Answer in reverse order to check the index mapping.
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{Index: i, Embedding: []float32{float32(len(req.Input[i]))}})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	texts := make([]string, embedBatchSize+2)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i+1)
+	}
+	cfg := runtimeConfig{BaseURL: server.URL, APIKey: "mr_sk_test"}
+	vectors, model, usage, n, err := createEmbeddings(context.Background(), cfg, "embed-small", texts, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || n != 2 || model != "embed-small" || usage.InputTokens != int64(len(texts)) {
+		t.Fatalf("calls=%d n=%d model=%q usage=%+v", calls, n, model, usage)
+	}
+	for i, vector := range vectors {
+		if vector[0] != float32(i+1) {
+			t.Fatalf("vector %d = %v", i, vector)
+		}
+	}
+}
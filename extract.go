@@ -0,0 +1,617 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+// The extraction layer turns documents into text locally, for --extract and
+// for inlining rlm attachments. Extractors are stdlib-only and aim for
+// readable text, not layout fidelity: PDFs yield their text runs, DOCX its
+// paragraphs and tables, XLSX/CSV markdown tables, and HTML markdown.
+
+const (
+	mimeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	mimeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	// maxExtractSourceBytes bounds the documents read for extraction.
+	maxExtractSourceBytes = 50 << 20
+)
+
+var errNoExtractableText = errors.New("no extractable text")
+
+type textExtractor func(data []byte) (string, error)
+
+// extractorFor picks an extractor by file extension, then by MIME type. Text
+// types (source files, JSON, markdown) pass through unchanged. It returns nil
+// for types without an extractor, such as images.
+func extractorFor(name string, mimeType llm.MimeType) textExtractor {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".pdf":
+		return extractPDFText
+	case ".docx":
+		return extractDOCXText
+	case ".xlsx":
+		return extractXLSXText
+	case ".csv":
+		return delimitedExtractor(',')
+	case ".tsv":
+		return delimitedExtractor('\t')
+	case ".html", ".htm", ".xhtml":
+		return extractHTMLText
+	}
+	normalized := strings.ToLower(string(mimeType))
+	if base, _, ok := strings.Cut(normalized, ";"); ok {
+		normalized = strings.TrimSpace(base)
+	}
+	switch normalized {
+	case "application/pdf":
+		return extractPDFText
+	case mimeDOCX:
+		return extractDOCXText
+	case mimeXLSX:
+		return extractXLSXText
+	case "text/csv", "application/csv":
+		return delimitedExtractor(',')
+	case "text/tab-separated-values":
+		return delimitedExtractor('\t')
+	case "text/html", "application/xhtml+xml":
+		return extractHTMLText
+	}
+	if isTextMime(mimeType) {
+		return extractPlainText
+	}
+	return nil
+}
+
+// extractText converts data to text. ok is false when there is no extractor
+// for the type.
+func extractText(name string, mimeType llm.MimeType, data []byte) (text string, ok bool, err error) {
+	extract := extractorFor(name, mimeType)
+	if extract == nil {
+		return "", false, nil
+	}
+	text, err = extract(data)
+	if err != nil {
+		return "", true, fmt.Errorf("extract %s: %w", filepath.Base(name), err)
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", true, fmt.Errorf("extract %s: %w", filepath.Base(name), errNoExtractableText)
+	}
+	return text, true, nil
+}
+
+func extractPlainText(data []byte) (string, error) {
+	if !utf8.Valid(data) {
+		return "", errors.New("not valid UTF-8 text")
+	}
+	return string(data), nil
+}
+
+func delimitedExtractor(comma rune) textExtractor {
+	return func(data []byte) (string, error) {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comma = comma
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return "", err
+		}
+		return markdownTable(rows), nil
+	}
+}
+
+// markdownTable renders rows as a markdown table, using the first row as the
+// header and padding short rows.
+func markdownTable(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if width == 0 {
+		return ""
+	}
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i := range width {
+			cell := ""
+			if i < len(row) {
+				cell = strings.Join(strings.Fields(row[i]), " ")
+			}
+			b.WriteString(" " + strings.ReplaceAll(cell, "|", `\|`) + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(rows[0])
+	b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return b.String()
+}
+
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	f, err := archive.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return io.ReadAll(io.LimitReader(f, maxExtractSourceBytes))
+}
+
+// extractDOCXText reads word/document.xml: paragraphs become lines, heading
+// styles become markdown headings, list items bullets and tables markdown
+// tables.
+func extractDOCXText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("not a DOCX file: %w", err)
+	}
+	doc, err := readZipFile(archive, "word/document.xml")
+	if err != nil {
+		return "", fmt.Errorf("not a DOCX file: %w", err)
+	}
+
+	var (
+		out       strings.Builder
+		para      strings.Builder
+		prefix    string
+		tableRows [][]string
+		row       []string
+		cell      []string
+		tableDeep int
+	)
+	decoder := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tbl":
+				tableDeep++
+				if tableDeep == 1 {
+					tableRows = nil
+				}
+			case "tr":
+				if tableDeep == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDeep == 1 {
+					cell = nil
+				}
+			case "p":
+				para.Reset()
+				prefix = ""
+			case "pStyle":
+				prefix = docxHeadingPrefix(xmlAttr(t, "val"))
+			case "numPr":
+				if prefix == "" {
+					prefix = "- "
+				}
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return "", err
+				}
+				para.WriteString(text)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				text := strings.TrimSpace(para.String())
+				switch {
+				case tableDeep > 0:
+					if text != "" {
+						cell = append(cell, text)
+					}
+				case text != "":
+					out.WriteString(prefix + text + "\n")
+					if prefix != "- " {
+						out.WriteString("\n")
+					}
+				}
+			case "tc":
+				if tableDeep == 1 {
+					row = append(row, strings.Join(cell, " "))
+				}
+			case "tr":
+				if tableDeep == 1 {
+					tableRows = append(tableRows, row)
+				}
+			case "tbl":
+				tableDeep--
+				if tableDeep == 0 {
+					out.WriteString(markdownTable(tableRows) + "\n")
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(out.String()) + "\n", nil
+}
+
+func docxHeadingPrefix(style string) string {
+	style = strings.ToLower(style)
+	if style == "title" {
+		return "# "
+	}
+	if level, err := strconv.Atoi(strings.TrimPrefix(style, "heading")); err == nil && strings.HasPrefix(style, "heading") && level >= 1 && level <= 6 {
+		return strings.Repeat("#", level) + " "
+	}
+	return ""
+}
+
+func xmlAttr(el xml.StartElement, local string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r xlsxRichText) text() string {
+	if len(r.Runs) == 0 {
+		return r.T
+	}
+	var b strings.Builder
+	for _, run := range r.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// extractXLSXText renders every worksheet as a markdown table under a
+// heading with the sheet name.
+func extractXLSXText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("not an XLSX file: %w", err)
+	}
+	var workbook xlsxWorkbook
+	if err := decodeZipXML(archive, "xl/workbook.xml", &workbook); err != nil {
+		return "", fmt.Errorf("not an XLSX file: %w", err)
+	}
+	var rels xlsxRelationships
+	if err := decodeZipXML(archive, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	var shared xlsxSharedStrings
+	if err := decodeZipXML(archive, "xl/sharedStrings.xml", &shared); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	targets := map[string]string{}
+	for _, rel := range rels.Items {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	var out strings.Builder
+	for _, sheetRef := range workbook.Sheets {
+		var sheet xlsxSheet
+		if err := decodeZipXML(archive, targets[sheetRef.RID], &sheet); err != nil {
+			return "", fmt.Errorf("sheet %s: %w", sheetRef.Name, err)
+		}
+		var rows [][]string
+		for _, r := range sheet.Rows {
+			var row []string
+			for i, c := range r.Cells {
+				col := xlsxColumn(c.Ref)
+				if col < 0 {
+					col = i
+				}
+				for len(row) < col {
+					row = append(row, "")
+				}
+				value := c.Value
+				switch c.Type {
+				case "s":
+					if idx, err := strconv.Atoi(c.Value); err == nil && idx >= 0 && idx < len(shared.Items) {
+						value = shared.Items[idx].text()
+					}
+				case "inlineStr":
+					value = c.Inline.text()
+				case "b":
+					value = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+				}
+				row = append(row, value)
+			}
+			rows = append(rows, row)
+		}
+		fmt.Fprintf(&out, "## %s\n\n%s\n", sheetRef.Name, markdownTable(rows))
+	}
+	return strings.TrimSpace(out.String()) + "\n", nil
+}
+
+func decodeZipXML(archive *zip.Reader, name string, v any) error {
+	data, err := readZipFile(archive, name)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// xlsxColumn converts the letters of a cell reference such as "BC12" to a
+// zero-based column index.
+func xlsxColumn(ref string) int {
+	col := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		letters++
+	}
+	if letters == 0 {
+		return -1
+	}
+	return col - 1
+}
+
+var (
+	htmlHrefPattern = regexp.MustCompile(`(?is)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	htmlSrcPattern  = regexp.MustCompile(`(?is)\bsrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	htmlAltPattern  = regexp.MustCompile(`(?is)\balt\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+)
+
+func htmlAttr(pattern *regexp.Regexp, tag string) string {
+	m := pattern.FindStringSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	return html.UnescapeString(m[1] + m[2] + m[3])
+}
+
+// extractHTMLText converts HTML to markdown: headings, paragraphs, lists,
+// links, emphasis, code and tables are kept; scripts, styles and the document
+// head are dropped.
+func extractHTMLText(data []byte) (string, error) {
+	src := string(data)
+	var (
+		out      strings.Builder
+		lists    []int // item counter per open list; -1 for unordered
+		inPre    bool
+		href     []string
+		rowCells int
+		headRow  bool
+	)
+	newline := func(n int) {
+		current := out.String()
+		trailing := len(current) - len(strings.TrimRight(current, "\n"))
+		if len(current) == 0 {
+			return
+		}
+		for range n - trailing {
+			out.WriteString("\n")
+		}
+	}
+	for i := 0; i < len(src); {
+		lt := strings.IndexByte(src[i:], '<')
+		if lt < 0 {
+			lt = len(src) - i
+		}
+		if text := src[i : i+lt]; text != "" {
+			text = html.UnescapeString(text)
+			if !inPre {
+				lead, trail := isSpaceByte(text[0]), isSpaceByte(text[len(text)-1])
+				text = strings.Join(strings.Fields(text), " ")
+				current := out.String()
+				if lead && current != "" && !strings.HasSuffix(current, " ") && !strings.HasSuffix(current, "\n") {
+					text = " " + text
+				}
+				if trail && text != "" && !strings.HasSuffix(text, " ") {
+					text += " "
+				}
+			}
+			out.WriteString(text)
+		}
+		i += lt
+		if i >= len(src) {
+			break
+		}
+		if strings.HasPrefix(src[i:], "<!--") {
+			end := strings.Index(src[i:], "-->")
+			if end < 0 {
+				break
+			}
+			i += end + 3
+			continue
+		}
+		gt := strings.IndexByte(src[i:], '>')
+		if gt < 0 {
+			break
+		}
+		tag := src[i+1 : i+gt]
+		i += gt + 1
+		closing := strings.HasPrefix(tag, "/")
+		name := strings.ToLower(strings.TrimLeft(tag, "/"))
+		if end := strings.IndexAny(name, " \t\r\n/"); end >= 0 {
+			name = name[:end]
+		}
+		switch name {
+		case "script", "style", "head", "noscript", "svg", "template":
+			if !closing {
+				end := strings.Index(strings.ToLower(src[i:]), "</"+name)
+				if end < 0 {
+					i = len(src)
+				} else {
+					i += end
+				}
+			}
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			newline(2)
+			if !closing {
+				out.WriteString(strings.Repeat("#", int(name[1]-'0')) + " ")
+			}
+		case "p", "div", "section", "article", "header", "footer", "main", "nav", "blockquote", "figure", "table":
+			newline(2)
+		case "br":
+			out.WriteString("\n")
+		case "hr":
+			newline(2)
+			out.WriteString("---")
+			newline(2)
+		case "ul", "ol":
+			if closing {
+				if len(lists) > 0 {
+					lists = lists[:len(lists)-1]
+				}
+				newline(2)
+			} else {
+				counter := -1
+				if name == "ol" {
+					counter = 0
+				}
+				lists = append(lists, counter)
+				newline(1)
+			}
+		case "li":
+			if closing {
+				continue
+			}
+			newline(1)
+			marker := "- "
+			if depth := len(lists); depth > 0 {
+				out.WriteString(strings.Repeat("  ", depth-1))
+				if lists[depth-1] >= 0 {
+					lists[depth-1]++
+					marker = strconv.Itoa(lists[depth-1]) + ". "
+				}
+			}
+			out.WriteString(marker)
+		case "pre":
+			if closing {
+				newline(1)
+				out.WriteString("```")
+				newline(2)
+			} else {
+				newline(2)
+				out.WriteString("```\n")
+			}
+			inPre = !closing
+		case "code":
+			if !inPre {
+				out.WriteString("`")
+			}
+		case "strong", "b":
+			out.WriteString("**")
+		case "em", "i":
+			out.WriteString("*")
+		case "a":
+			if closing {
+				if n := len(href); n > 0 {
+					if href[n-1] != "" {
+						out.WriteString("](" + href[n-1] + ")")
+					}
+					href = href[:n-1]
+				}
+			} else {
+				link := htmlAttr(htmlHrefPattern, tag)
+				href = append(href, link)
+				if link != "" {
+					out.WriteString("[")
+				}
+			}
+		case "img":
+			if alt, src := htmlAttr(htmlAltPattern, tag), htmlAttr(htmlSrcPattern, tag); src != "" {
+				out.WriteString("![" + alt + "](" + src + ")")
+			}
+		case "tr":
+			if closing {
+				out.WriteString("\n")
+				if headRow {
+					out.WriteString("|" + strings.Repeat(" --- |", rowCells) + "\n")
+				}
+			} else {
+				newline(1)
+				out.WriteString("|")
+				rowCells, headRow = 0, false
+			}
+		case "td", "th":
+			if closing {
+				out.WriteString(" |")
+			} else {
+				out.WriteString(" ")
+				rowCells++
+				headRow = headRow || name == "th"
+			}
+		}
+	}
+	text := blankLines.ReplaceAllString(out.String(), "\n\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n", nil
+}
+
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// PDF text extraction reads the text-showing operators (Tj, TJ, ', ") of
+// every content stream, inflating FlateDecode streams. It handles the common
+// case of simple-font PDFs; scanned documents and fonts with custom glyph
+// encodings yield little or unreadable text and are reported as having no
+// extractable text.
+
+var pdfStreamPattern = regexp.MustCompile(`>>\s*stream\r?\n`)
+
+// minPDFPrintableRatio is the share of printable runes below which extracted
+// text is treated as undecodable glyph ids.
+const minPDFPrintableRatio = 0.85
+
+func extractPDFText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\r "), []byte("%PDF-")) {
+		return "", errors.New("not a PDF file")
+	}
+	var out strings.Builder
+	for _, loc := range pdfStreamPattern.FindAllIndex(data, -1) {
+		// The stream dictionary runs from the object header to "stream".
+		dict := data[:loc[0]]
+		if obj := bytes.LastIndex(dict, []byte(" obj")); obj >= 0 {
+			dict = dict[obj:]
+		}
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		stream := data[start : start+end]
+		switch {
+		case bytes.Contains(dict, []byte("/FlateDecode")):
+			inflated, err := inflatePDFStream(stream)
+			if err != nil {
+				continue
+			}
+			stream = inflated
+		case bytes.Contains(dict, []byte("/Filter")):
+			// Images and other encodings carry no text.
+			continue
+		}
+		if bytes.Contains(dict, []byte("/Image")) || !bytes.Contains(stream, []byte("BT")) {
+			continue
+		}
+		pdfContentText(stream, &out)
+	}
+	text := strings.TrimSpace(out.String())
+	if text == "" {
+		return "", errNoExtractableText
+	}
+	printable := 0
+	total := 0
+	for _, r := range text {
+		total++
+		if unicode.IsPrint(r) || unicode.IsSpace(r) {
+			printable++
+		}
+	}
+	if float64(printable)/float64(total) < minPDFPrintableRatio {
+		return "", errors.New("no extractable text (the PDF uses embedded font encodings or is scanned)")
+	}
+	return text + "\n", nil
+}
+
+func inflatePDFStream(stream []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+	data, err := io.ReadAll(io.LimitReader(reader, maxExtractSourceBytes))
+	// Streams are often padded after the compressed data; keep what inflated.
+	if err != nil && len(data) == 0 {
+		return nil, err
+	}
+	return data, nil
+}
+
+// pdfContentText interprets a content stream just enough to collect the
+// strings passed to text operators, breaking lines on text positioning.
+func pdfContentText(content []byte, out *strings.Builder) {
+	var operands []pdfToken
+	lineBreak := func() {
+		current := out.String()
+		if current != "" && !strings.HasSuffix(current, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	lexer := pdfLexer{data: content}
+	for {
+		token, ok := lexer.next()
+		if !ok {
+			break
+		}
+		if token.kind != pdfOperator {
+			operands = append(operands, token)
+			continue
+		}
+		switch token.text {
+		case "Tj":
+			if n := len(operands); n > 0 {
+				out.WriteString(operands[n-1].text)
+			}
+		case "'", "\"":
+			lineBreak()
+			if n := len(operands); n > 0 {
+				out.WriteString(operands[n-1].text)
+			}
+		case "TJ":
+			if n := len(operands); n > 0 && operands[n-1].kind == pdfArray {
+				for _, item := range operands[n-1].items {
+					switch item.kind {
+					case pdfString:
+						out.WriteString(item.text)
+					case pdfNumber:
+						// Large negative kerning separates words.
+						if value, err := strconv.ParseFloat(item.text, 64); err == nil && value < -200 {
+							out.WriteString(" ")
+						}
+					}
+				}
+			}
+		case "T*", "ET":
+			lineBreak()
+		case "Td", "TD":
+			if n := len(operands); n >= 2 {
+				if dy, err := strconv.ParseFloat(operands[n-1].text, 64); err == nil && dy != 0 {
+					lineBreak()
+				}
+			}
+		case "Tm":
+			lineBreak()
+		}
+		operands = operands[:0]
+	}
+	lineBreak()
+}
+
+type pdfTokenKind int
+
+const (
+	pdfOperator pdfTokenKind = iota
+	pdfNumber
+	pdfString
+	pdfName
+	pdfArray
+	pdfOther
+)
+
+type pdfToken struct {
+	kind  pdfTokenKind
+	text  string
+	items []pdfToken
+}
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFDelimiter(b byte) bool {
+	return strings.IndexByte("()<>[]{}/%", b) >= 0
+}
+
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		switch {
+		case isSpaceByte(b) || b == 0 || b == '\f':
+			l.pos++
+		case b == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case b == '(':
+			l.pos++
+			return pdfToken{kind: pdfString, text: decodePDFString(l.literalString())}, true
+		case b == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.pos += 2
+			return pdfToken{kind: pdfOther, text: "<<"}, true
+		case b == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			l.pos += 2
+			return pdfToken{kind: pdfOther, text: ">>"}, true
+		case b == '<':
+			l.pos++
+			return pdfToken{kind: pdfString, text: decodePDFString(l.hexString())}, true
+		case b == '[':
+			l.pos++
+			var items []pdfToken
+			for {
+				item, ok := l.next()
+				if !ok || (item.kind == pdfOther && item.text == "]") {
+					break
+				}
+				items = append(items, item)
+			}
+			return pdfToken{kind: pdfArray, items: items}, true
+		case b == ']' || b == '{' || b == '}' || b == '>' || b == ')':
+			l.pos++
+			return pdfToken{kind: pdfOther, text: string(b)}, true
+		case b == '/':
+			start := l.pos
+			l.pos++
+			l.skipRegular()
+			return pdfToken{kind: pdfName, text: string(l.data[start:l.pos])}, true
+		default:
+			start := l.pos
+			l.skipRegular()
+			word := string(l.data[start:l.pos])
+			if _, err := strconv.ParseFloat(word, 64); err == nil {
+				return pdfToken{kind: pdfNumber, text: word}, true
+			}
+			return pdfToken{kind: pdfOperator, text: word}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+func (l *pdfLexer) skipRegular() {
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		if isSpaceByte(b) || b == 0 || b == '\f' || isPDFDelimiter(b) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// literalString reads a (...) string after the opening parenthesis, handling
+// nesting and escapes.
+func (l *pdfLexer) literalString() []byte {
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		b := l.data[l.pos]
+		l.pos++
+		switch b {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			esc := l.data[l.pos]
+			l.pos++
+			switch esc {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if esc >= '0' && esc <= '7' {
+					value := int(esc - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						value = value*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(value))
+				} else {
+					out = append(out, esc)
+				}
+			}
+			continue
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func (l *pdfLexer) hexString() []byte {
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if b := l.data[l.pos]; !isSpaceByte(b) {
+			digits = append(digits, b)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i+1 < len(digits); i += 2 {
+		value, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			return nil
+		}
+		out = append(out, byte(value))
+	}
+	return out
+}
+
+// decodePDFString decodes UTF-16BE strings (with a byte order mark) and
+// treats everything else as Latin-1, close enough to PDFDocEncoding and
+// WinAnsiEncoding for text.
+func decodePDFString(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractDOCXText(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Quarterly report</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Revenue grew </w:t></w:r><w:r><w:t>12%.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>First point</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Region</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Sales</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>EMEA</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>40</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+</w:body></w:document>`
+	text, err := extractDOCXText(zipArchive(t, map[string]string{"word/document.xml": doc}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Quarterly report\n", "Revenue grew 12%.\n", "- First point\n", "| Region | Sales |\n| --- | --- |\n| EMEA | 40 |"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestExtractXLSXText(t *testing.T) {
+	archive := zipArchive(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Budget" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Item</t></si><si><r><t>Co</t></r><r><t>st</t></r></si><si><t>Rent</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2"><v>1200</v></c></row>
+<row r="3"><c r="A3" t="inlineStr"><is><t>Total</t></is></c><c r="B3" t="b"><v>1</v></c></row>
+</sheetData></worksheet>`,
+	})
+	text, err := extractXLSXText(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## Budget\n\n| Item |  | Cost |\n| --- | --- | --- |\n| Rent |  | 1200 |\n| Total | TRUE |  |\n"
+	if text != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", text, want)
+	}
+}
+
+func TestExtractCSVText(t *testing.T) {
+	text, err := delimitedExtractor(',')([]byte("name,note\nada,\"a | b\"\nbob\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "| name | note |\n| --- | --- |\n| ada | a \\| b |\n| bob |  |\n"
+	if text != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", text, want)
+	}
+}
+
+func TestExtractHTMLText(t *testing.T) {
+	page := `<html><head><title>x</title><style>p{}</style></head><body>
+<h1>Release &amp; notes</h1>
+<p>Read the <a href="https://example.com/docs">docs</a> and <strong>upgrade</strong>.</p>
+<script>alert(1)</script>
+<ul><li>one</li><li>two</li></ul>
+<ol><li>first</li></ol>
+<pre>go test ./...
+</pre>
+<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>
+</body></html>`
+	text, err := extractHTMLText([]byte(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Release & notes\n\n",
+		"Read the [docs](https://example.com/docs) and **upgrade**.",
+		"- one\n- two",
+		"1. first",
+		"```\ngo test ./...\n```",
+		"| A | B |\n| --- | --- |\n| 1 | 2 |",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "alert") || strings.Contains(text, "p{}") {
+		t.Fatalf("script or style leaked into:\n%s", text)
+	}
+}
+
+func buildPDF(t *testing.T, content string, compress bool) []byte {
+	t.Helper()
+	stream := []byte(content)
+	filter := ""
+	if compress {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		_, _ = w.Write(stream)
+		_ = w.Close()
+		stream = buf.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d%s >>\nstream\n", len(stream), filter)
+	pdf.Write(stream)
+	pdf.WriteString("\nendstream\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestExtractPDFText(t *testing.T) {
+	content := "BT /F1 12 Tf 72 720 Td (Invoice \\(draft\\)) Tj 0 -14 Td [(Total:) -250 (42 EUR)] TJ T* <FEFF00C9007400E9> Tj ET"
+	for _, compress := range []bool{false, true} {
+		text, err := extractPDFText(buildPDF(t, content, compress))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "Invoice (draft)\nTotal: 42 EUR\nÉté\n"; text != want {
+			t.Fatalf("compress=%v: got %q, want %q", compress, text, want)
+		}
+	}
+
+	if _, err := extractPDFText(buildPDF(t, "0 0 m 10 10 l S", false)); err == nil {
+		t.Fatal("expected an error for a PDF without text")
+	}
+	if _, err := extractPDFText([]byte("not a pdf")); err == nil {
+		t.Fatal("expected an error for non-PDF data")
+	}
+}
+
+func TestExtractorForFallsBackToMime(t *testing.T) {
+	if extractorFor("report", "application/pdf") == nil {
+		t.Fatal("expected a PDF extractor from the MIME type")
+	}
+	if extractorFor("main.go", "text/plain") == nil {
+		t.Fatal("expected source files to pass through")
+	}
+	if extractorFor("photo.png", "image/png") != nil {
+		t.Fatal("images have no text extractor")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// finalError marks a failure that another model would not fix or that must
+// not be repeated, such as a schema mismatch after the model answered or a
+// stream that broke after output was printed.
+type finalError struct {
+	err error
+}
+
+func (e finalError) Error() string { return e.err.Error() }
+func (e finalError) Unwrap() error { return e.err }
+
+func noFallback(err error) error {
+	if err == nil {
+		return nil
+	}
+	return finalError{err: err}
+}
+
+// modelChain returns the primary model followed by its fallbacks, without
+// blanks or duplicates. Fallbacks given on the command line replace the
+// profile's fallbacks list.
+func modelChain(primary string, flagFallbacks []string, cfg runtimeConfig) []string {
+	fallbacks := splitCSVValues(flagFallbacks)
+	if len(fallbacks) == 0 {
+		fallbacks = cfg.Fallbacks
+	}
+	chain := []string{}
+	for _, model := range append([]string{primary}, fallbacks...) {
+		model = strings.TrimSpace(model)
+		if model != "" && !containsString(chain, model) {
+			chain = append(chain, model)
+		}
+	}
+	return chain
+}
+
+// fallbackTimeout is the deadline of a command that calls chain: each model
+// may use the full timeout, so one that times out leaves time for the next.
+func fallbackTimeout(timeout time.Duration, chain []string) time.Duration {
+	return timeout * time.Duration(max(len(chain), 1))
+}
+
+// shouldFallback reports whether a failed model call may be retried on the
+// next model of the chain: server errors, rate limits, transport errors and
+// an attempt that ran out of its own deadline qualify. Other 4xx responses,
+// such as an invalid request or a rejected API key, would fail the same way
+// on any model, and a cancelled or expired run, an exhausted budget or an
+// error marked with noFallback must not be repeated.
+func shouldFallback(ctx context.Context, err error, outcome *callOutcome) bool {
+	return ctx.Err() == nil && transientFailure(err, outcome)
+}
+
+// callWithFallback runs call on each model of the chain in turn until one
+// succeeds, and returns the index of the model that served the result. With
+// more than one model, each attempt gets its own attemptTimeout, so a model
+// that times out falls back too. Each fallback is announced on stderr so
+// scripted output stays clean.
+func callWithFallback[T any](ctx context.Context, chain []string, attemptTimeout time.Duration, call func(ctx context.Context, model string) (T, error)) (T, int, error) {
+	var zero T
+	for i, model := range chain {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if len(chain) > 1 && attemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, attemptTimeout)
+		}
+		attemptCtx, outcome := withCallOutcome(attemptCtx)
+		out, err := call(attemptCtx, model)
+		cancel()
+		if err == nil {
+			return out, i, nil
+		}
+		if i == len(chain)-1 || !shouldFallback(ctx, err, outcome) {
+			if len(chain) > 1 {
+				err = fmt.Errorf("model %s: %w", model, err)
+			}
+			return zero, i, err
+		}
+		fmt.Fprintf(os.Stderr, "warning: model %s failed (%v); falling back to %s\n", model, err, chain[i+1])
+	}
+	return zero, 0, errors.New("model is required")
+}
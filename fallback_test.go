@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+func TestModelChain(t *testing.T) {
+	cfg := runtimeConfig{Fallbacks: []string{"profile-b", "profile-c"}}
+	if got := modelChain("a", nil, cfg); !reflect.DeepEqual(got, []string{"a", "profile-b", "profile-c"}) {
+		t.Fatalf("profile fallbacks: %v", got)
+	}
+	if got := modelChain("a", []string{"b, a", "c", " "}, cfg); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Fatalf("flag fallbacks: %v", got)
+	}
+	if got := modelChain("a", nil, runtimeConfig{}); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("no fallbacks: %v", got)
+	}
+}
+
+func TestCallWithFallback(t *testing.T) {
+	ctx := context.Background()
+	cases := map[string]error{
+		"server error": &apiError{StatusCode: http.StatusServiceUnavailable, Message: "provider unavailable"},
+		"rate limit":   &apiError{StatusCode: http.StatusTooManyRequests, Message: "slow down"},
+		"transport":    &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+	}
+	for name, failure := range cases {
+		var tried []string
+		out, served, err := callWithFallback(ctx, []string{"a", "b", "c"}, time.Minute, func(_ context.Context, model string) (string, error) {
+			tried = append(tried, model)
+			if model == "a" {
+				return "", fmt.Errorf("create response: %w", failure)
+			}
+			return "answer from " + model, nil
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if out != "answer from b" || served != 1 || !reflect.DeepEqual(tried, []string{"a", "b"}) {
+			t.Fatalf("%s: out=%q served=%d tried=%v", name, out, served, tried)
+		}
+	}
+}
+
+func TestCallWithFallbackOnAttemptTimeout(t *testing.T) {
+	var tried []string
+	out, served, err := callWithFallback(context.Background(), []string{"a", "b"}, 10*time.Millisecond, func(ctx context.Context, model string) (string, error) {
+		tried = append(tried, model)
+		if model == "a" {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return "answer from " + model, nil
+	})
+	if err != nil || out != "answer from b" || served != 1 || !reflect.DeepEqual(tried, []string{"a", "b"}) {
+		t.Fatalf("out=%q served=%d tried=%v err=%v", out, served, tried, err)
+	}
+}
+
+func TestCallWithFallbackStopsOnFinalErrors(t *testing.T) {
+	ctx := context.Background()
+	cases := map[string]error{
+		"bad request":   &apiError{StatusCode: http.StatusBadRequest, Message: "unknown parameter"},
+		"unauthorized":  &apiError{StatusCode: http.StatusUnauthorized, Message: "invalid api key"},
+		"unprocessable": &apiError{StatusCode: http.StatusUnprocessableEntity, Message: "invalid schema"},
+		"no fallback":   noFallback(&apiError{StatusCode: http.StatusBadGateway}),
+		"budget":        fmt.Errorf("%w: too expensive", errCostBudgetExceeded),
+		"canceled":      context.Canceled,
+	}
+	for name, failure := range cases {
+		calls := 0
+		_, _, err := callWithFallback(ctx, []string{"a", "b"}, time.Minute, func(context.Context, string) (int, error) {
+			calls++
+			return 0, failure
+		})
+		if calls != 1 || !errors.Is(err, failure) {
+			t.Fatalf("%s: calls=%d err=%v", name, calls, err)
+		}
+	}
+
+	expired, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	<-expired.Done()
+	calls := 0
+	_, _, err := callWithFallback(expired, []string{"a", "b"}, time.Minute, func(ctx context.Context, _ string) (int, error) {
+		calls++
+		return 0, ctx.Err()
+	})
+	if calls != 1 || err == nil {
+		t.Fatalf("expired run must not fall back: calls=%d err=%v", calls, err)
+	}
+}
+
+func TestCallWithFallbackClassifiesByResponseStatus(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &outcomeTransport{next: http.DefaultTransport}}
+	// The SDK's errors don't carry a type mrl knows, so the status seen by
+	// the transport decides.
+	call := func(ctx context.Context, _ string) (int, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, nil)
+		if err != nil {
+			return 0, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		_ = resp.Body.Close()
+		return 0, errors.New("request failed")
+	}
+	for want, code := range map[int]int{2: http.StatusServiceUnavailable, 1: http.StatusUnauthorized} {
+		status = code
+		calls := 0
+		_, _, _ = callWithFallback(context.Background(), []string{"a", "b"}, time.Minute, func(ctx context.Context, model string) (int, error) {
+			calls++
+			return call(ctx, model)
+		})
+		if calls != want {
+			t.Fatalf("status %d: calls=%d, want %d", code, calls, want)
+		}
+	}
+}
+
+func TestCallWithFallbackThroughPromptClient(t *testing.T) {
+	previous := http.DefaultTransport
+	t.Cleanup(func() { http.DefaultTransport = previous })
+	installOutcomeTransport()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Model == "a" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":{"message":"provider unavailable"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(llm.Response{
+			ID:    "resp_test",
+			Model: req.Model,
+			Output: []llm.OutputItem{{
+				Type:    llm.OutputItemTypeMessage,
+				Role:    llm.RoleAssistant,
+				Content: []llm.ContentPart{llm.TextPart("from " + req.Model)},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	client, err := newPromptClient(runtimeConfig{BaseURL: server.URL, APIKey: "mr_sk_test", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := []llm.InputItem{llm.NewUserText("ping")}
+	result, served, err := callWithFallback(context.Background(), []string{"a", "b"}, time.Minute, func(ctx context.Context, model string) (promptResult, error) {
+		return runCompletion(ctx, client, model, "", input, nil)
+	})
+	// The SDK's 503 error is only recognised through the status the outcome
+	// transport saw on http.DefaultTransport.
+	if err != nil || served != 1 || result.Text != "from b" {
+		t.Fatalf("served=%d text=%q err=%v", served, result.Text, err)
+	}
+}
+
+func TestCallWithFallbackReportsLastModel(t *testing.T) {
+	_, served, err := callWithFallback(context.Background(), []string{"a", "b"}, time.Minute, func(_ context.Context, model string) (int, error) {
+		return 0, &apiError{StatusCode: http.StatusServiceUnavailable, Message: model + " is down"}
+	})
+	if served != 1 || err == nil || err.Error() != "model b: request failed: status=503: b is down" {
+		t.Fatalf("served=%d err=%v", served, err)
+	}
+}
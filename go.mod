@@ -8,6 +8,7 @@ require (
 	github.com/modelrelay/modelrelay/platform v0.0.0-00010101000000-000000000000
 	github.com/modelrelay/modelrelay/providers v0.0.0-20251119210239-1133abe831c1
 	github.com/modelrelay/modelrelay/sdk/go v0.0.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	github.com/spf13/cobra v1.10.2
 	github.com/spf13/pflag v1.0.10
 )
@@ -19,7 +20,6 @@ require (
 	github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/oapi-codegen/runtime v1.1.2 // indirect
-	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1 // indirect
 	go.opentelemetry.io/otel v1.39.0 // indirect
 	go.opentelemetry.io/otel/trace v1.39.0 // indirect
 )
@@ -7,9 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type authMode int
@@ -36,48 +41,52 @@ func doJSON(ctx context.Context, cfg runtimeConfig, mode authMode, method, path
 	return nil
 }
 
+// postModelRoute posts to a model route the SDK client has no call for
+// (image generation, embeddings, speech). A 404 or 405 there means the API
+// behind --base-url doesn't serve the route, which is said plainly instead
+// of surfacing as a bare request failure.
+func postModelRoute(ctx context.Context, cfg runtimeConfig, path string, payload any, out any) error {
+	err := doJSON(ctx, cfg, authModeAPIKey, http.MethodPost, path, payload, out)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusMethodNotAllowed) {
+		return fmt.Errorf("POST %s is not available on this API: %w", path, err)
+	}
+	return err
+}
+
 func doJSONRaw(ctx context.Context, cfg runtimeConfig, mode authMode, method, path string, payload any) ([]byte, error) {
 	fullURL, err := joinBaseURL(cfg.BaseURL, path)
 	if err != nil {
 		return nil, err
 	}
 
-	var body io.Reader
+	var encoded []byte
 	if payload != nil {
-		encoded, marshalErr := json.Marshal(payload)
-		if marshalErr != nil {
-			return nil, marshalErr
+		encoded, err = json.Marshal(payload)
+		if err != nil {
+			return nil, err
 		}
-		body = bytes.NewReader(encoded)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
-	if err != nil {
-		return nil, err
-	}
-	if payload != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
-	if authErr := applyAuth(req, cfg, mode); authErr != nil {
-		return nil, authErr
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
 
-	data, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		msg := strings.TrimSpace(string(data))
-		if msg == "" {
-			msg = resp.Status
+	newRequest := func() (*http.Request, error) {
+		var body io.Reader
+		if payload != nil {
+			body = bytes.NewReader(encoded)
+		}
+		req, reqErr := http.NewRequestWithContext(ctx, method, fullURL, body)
+		if reqErr != nil {
+			return nil, reqErr
 		}
-		return nil, fmt.Errorf("request failed: status=%d body=%s", resp.StatusCode, msg)
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if authErr := applyAuth(req, cfg, mode); authErr != nil {
+			return nil, authErr
+		}
+		return req, nil
 	}
 
-	return data, nil
+	return doAPIRequest(ctx, apiHTTPClient, cfg.retryPolicy(), newRequest, isIdempotentRequest(method, path))
 }
 
 func applyAuth(req *http.Request, cfg runtimeConfig, mode authMode) error {
@@ -119,3 +128,277 @@ func joinBaseURL(baseURL, path string) (string, error) {
 	}
 	return u.String(), nil
 }
+
+// apiHTTPClient is shared by all control-plane calls so connections are
+// reused across requests and retries. Deadlines come from the request context.
+// A nil Transport resolves http.DefaultTransport per request, so --record and
+// --replay apply.
+var apiHTTPClient = &http.Client{}
+
+// retryPolicy controls how control-plane calls are retried. Rate limits (429)
+// are always retried; 5xx responses and network errors only when the request
+// is idempotent, since the server may already have applied it.
+type retryPolicy struct {
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+const (
+	defaultAPIMaxRetries = 3
+	// maxRetryAfter caps how long a Retry-After header can stall the CLI.
+	maxRetryAfter = time.Minute
+)
+
+func (cfg runtimeConfig) retryPolicy() retryPolicy {
+	return retryPolicy{maxRetries: cfg.MaxRetries, baseDelay: 500 * time.Millisecond, maxDelay: 8 * time.Second}
+}
+
+// backoff returns a jittered exponential delay for the given retry (1-based):
+// half the exponential step plus a random share of the other half.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	delay := p.baseDelay << min(retry-1, 10)
+	if delay <= 0 || delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half+1) //nolint:gosec // jitter does not need a CSPRNG
+}
+
+// apiError is a non-2xx response from the ModelRelay API, decoded from the
+// standard error envelope when the body has one.
+type apiError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+	RetryAfter time.Duration
+}
+
+func (e *apiError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "request failed: status=%d", e.StatusCode)
+	if e.Code != "" {
+		fmt.Fprintf(&b, " code=%s", e.Code)
+	}
+	fmt.Fprintf(&b, ": %s", e.Message)
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request_id=%s)", e.RequestID)
+	}
+	return b.String()
+}
+
+// retryable reports whether the request may be sent again.
+func (e *apiError) retryable(idempotent bool) bool {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return true
+	case e.StatusCode >= http.StatusInternalServerError:
+		return idempotent && e.StatusCode != http.StatusNotImplemented
+	default:
+		return false
+	}
+}
+
+func newAPIError(resp *http.Response, body []byte) *apiError {
+	apiErr := &apiError{StatusCode: resp.StatusCode}
+	apiErr.Code, apiErr.Message, apiErr.RequestID = parseAPIErrorBody(body)
+	if apiErr.Message == "" {
+		apiErr.Message = firstNonEmpty(strings.TrimSpace(string(body)), resp.Status)
+	}
+	apiErr.RequestID = firstNonEmpty(apiErr.RequestID, resp.Header.Get("X-Request-Id"))
+	apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return apiErr
+}
+
+// parseAPIErrorBody reads the ModelRelay error envelope: {"error": {...}},
+// {"error": "message"} or the same fields at the top level. Fields the body
+// lacks are empty.
+func parseAPIErrorBody(raw []byte) (code, message, requestID string) {
+	var envelope struct {
+		Error     json.RawMessage `json:"error"`
+		Code      string          `json:"code"`
+		Message   string          `json:"message"`
+		RequestID string          `json:"request_id"`
+	}
+	if json.Unmarshal(raw, &envelope) != nil {
+		return "", "", ""
+	}
+	var nested struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id"`
+	}
+	if len(envelope.Error) > 0 && json.Unmarshal(envelope.Error, &nested) != nil {
+		_ = json.Unmarshal(envelope.Error, &nested.Message)
+	}
+	return firstNonEmpty(nested.Code, envelope.Code),
+		strings.TrimSpace(firstNonEmpty(nested.Message, envelope.Message)),
+		firstNonEmpty(nested.RequestID, envelope.RequestID)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return min(time.Duration(seconds)*time.Second, maxRetryAfter)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return min(max(at.Sub(now), 0), maxRetryAfter)
+	}
+	return 0
+}
+
+// isIdempotentRequest reports whether a control-plane request is safe to
+// repeat. POST is idempotent only for resolve endpoints, which do not write,
+// and for RLM lease finalization, which settles a lease at most once.
+func isIdempotentRequest(method, path string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		pathPart, _, _ := strings.Cut(path, "?")
+		return strings.HasSuffix(pathPart, "/resolve") || strings.HasSuffix(pathPart, "/finalize")
+	default:
+		return false
+	}
+}
+
+// doAPIRequest sends a request built by newRequest, retrying per policy, and
+// returns the body of the first 2xx response. newRequest is called for each
+// attempt so the body can be replayed.
+func doAPIRequest(ctx context.Context, client *http.Client, policy retryPolicy, newRequest func() (*http.Request, error), idempotent bool) ([]byte, error) {
+	if client == nil {
+		client = apiHTTPClient
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || !idempotent || attempt >= policy.maxRetries || errors.Is(err, errCassetteMiss) {
+				return nil, err
+			}
+			if sleepErr := sleepContext(ctx, policy.backoff(attempt+1)); sleepErr != nil {
+				return nil, err
+			}
+			continue
+		}
+		data, readErr := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+			if readErr != nil {
+				return nil, readErr
+			}
+			return data, nil
+		}
+		apiErr := newAPIError(resp, data)
+		if !apiErr.retryable(idempotent) || attempt >= policy.maxRetries {
+			return nil, apiErr
+		}
+		delay := max(apiErr.RetryAfter, policy.backoff(attempt+1))
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return nil, apiErr
+		}
+	}
+}
+
+// callOutcome is what the transport saw of the last HTTP exchange of a call
+// whose context carries it. SDK errors are opaque to mrl, so model calls are
+// classified by the response status instead of the error type.
+type callOutcome struct {
+	mu           sync.Mutex
+	status       int
+	transportErr bool
+}
+
+type callOutcomeKey struct{}
+
+func withCallOutcome(ctx context.Context) (context.Context, *callOutcome) {
+	outcome := &callOutcome{}
+	return context.WithValue(ctx, callOutcomeKey{}, outcome), outcome
+}
+
+func (o *callOutcome) seen() (status int, transportErr bool) {
+	if o == nil {
+		return 0, false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.status, o.transportErr
+}
+
+// outcomeTransport records each exchange in the callOutcome of its request
+// context, if any.
+type outcomeTransport struct {
+	next http.RoundTripper
+}
+
+func (t *outcomeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if outcome, ok := req.Context().Value(callOutcomeKey{}).(*callOutcome); ok {
+		outcome.mu.Lock()
+		outcome.status, outcome.transportErr = 0, err != nil
+		if resp != nil {
+			outcome.status = resp.StatusCode
+		}
+		outcome.mu.Unlock()
+	}
+	return resp, err
+}
+
+// installOutcomeTransport wraps http.DefaultTransport, after any cassette, so
+// replayed responses are classified too.
+func installOutcomeTransport() {
+	if _, ok := http.DefaultTransport.(*outcomeTransport); !ok {
+		http.DefaultTransport = &outcomeTransport{next: http.DefaultTransport}
+	}
+}
+
+// transientFailure reports whether a failed call is worth sending again,
+// possibly to another model: 5xx and 429 responses, transport errors and
+// request timeouts. Other 4xx responses, including auth and invalid-request
+// errors, and anything cancelled, budgeted or marked noFallback are not.
+// outcome is the call's callOutcome and may be nil.
+func transientFailure(err error, outcome *callOutcome) bool {
+	var final finalError
+	switch {
+	case err == nil,
+		errors.As(err, &final),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, errCostBudgetExceeded),
+		errors.Is(err, errCassetteMiss):
+		return false
+	}
+	status, transportErr := outcome.seen()
+	if apiErr := (*apiError)(nil); errors.As(err, &apiErr) {
+		status = apiErr.StatusCode
+	}
+	if status >= http.StatusBadRequest {
+		return status == http.StatusTooManyRequests || (status >= http.StatusInternalServerError && status != http.StatusNotImplemented)
+	}
+	var netErr net.Error
+	return transportErr || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
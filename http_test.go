@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testRetryConfig(serverURL string, retries int) runtimeConfig {
+	return runtimeConfig{BaseURL: serverURL, APIKey: "mr_sk_test", MaxRetries: retries}
+}
+
+func TestDoJSONRaw_RetriesIdempotentServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	cfg := testRetryConfig(server.URL, 3)
+	body, err := doJSONRaw(t.Context(), cfg, authModeAPIKey, http.MethodGet, "/customers", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"ok":true}` || calls.Load() != 3 {
+		t.Fatalf("body=%s calls=%d", body, calls.Load())
+	}
+}
+
+func TestDoJSONRaw_DoesNotRetryNonIdempotentServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("X-Request-Id", "req_123")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":{"code":"internal","message":"boom"}}`))
+	}))
+	defer server.Close()
+
+	_, err := doJSONRaw(t.Context(), testRetryConfig(server.URL, 3), authModeAPIKey, http.MethodPost, "/customers", map[string]string{"external_id": "c1"})
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected apiError, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("POST must not be retried on 5xx, calls=%d", calls.Load())
+	}
+	if apiErr.StatusCode != http.StatusInternalServerError || apiErr.Code != "internal" || apiErr.Message != "boom" || apiErr.RequestID != "req_123" {
+		t.Fatalf("unexpected error: %+v", apiErr)
+	}
+	if got := apiErr.Error(); got != "request failed: status=500 code=internal: boom (request_id=req_123)" {
+		t.Fatalf("unexpected message %q", got)
+	}
+}
+
+func TestDoJSONRaw_RetriesRateLimitsWithRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	if _, err := doJSONRaw(t.Context(), testRetryConfig(server.URL, 1), authModeAPIKey, http.MethodPost, "/customers", struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected one retry, calls=%d", calls.Load())
+	}
+}
+
+func TestDoJSONRaw_StopsAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"message":"down"}`))
+	}))
+	defer server.Close()
+
+	_, err := doJSONRaw(t.Context(), testRetryConfig(server.URL, 0), authModeAPIKey, http.MethodGet, "/tiers", nil)
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || apiErr.Message != "down" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected no retries, calls=%d", calls.Load())
+	}
+}
+
+func TestPostModelRouteNamesUnservedRoute(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	err := postModelRoute(t.Context(), testRetryConfig(server.URL, 0), "/embeddings", map[string]any{"input": []string{"x"}}, nil)
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || !strings.Contains(err.Error(), "POST /embeddings is not available") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"5":                             5 * time.Second,
+		"-1":                            0,
+		"3600":                          maxRetryAfter,
+		"Tue, 10 Mar 2026 12:00:30 GMT": 30 * time.Second,
+		"Tue, 10 Mar 2026 11:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for raw, want := range cases {
+		if got := parseRetryAfter(raw, now); got != want {
+			t.Fatalf("%q: got %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffStaysWithinBounds(t *testing.T) {
+	policy := retryPolicy{baseDelay: 100 * time.Millisecond, maxDelay: time.Second}
+	for retry := 1; retry <= 20; retry++ {
+		delay := policy.backoff(retry)
+		step := min(policy.baseDelay<<min(retry-1, 10), policy.maxDelay)
+		if delay < step/2 || delay > step {
+			t.Fatalf("retry %d: delay %v outside [%v, %v]", retry, delay, step/2, step)
+		}
+	}
+}
+
+func TestIsIdempotentRequest(t *testing.T) {
+	cases := []struct {
+		method, path string
+		want         bool
+	}{
+		{http.MethodGet, "/customers", true},
+		{http.MethodDelete, "/customers/1", true},
+		{http.MethodPost, "/customers", false},
+		{http.MethodPost, "/responses/resolve", true},
+		{http.MethodPost, "/rlm/executions", false},
+		{http.MethodPost, "/rlm/executions/e1/finalize", true},
+		{http.MethodPatch, "/tiers/1", false},
+	}
+	for _, tc := range cases {
+		if got := isIdempotentRequest(tc.method, tc.path); got != tc.want {
+			t.Fatalf("%s %s: got %v", tc.method, tc.path, got)
+		}
+	}
+}
+
+func TestParseAPIErrorBody(t *testing.T) {
+	cases := []struct {
+		body                     string
+		code, message, requestID string
+	}{
+		{`{"error":{"code":"invalid_request","message":" bad model ","request_id":"req_1"}}`, "invalid_request", "bad model", "req_1"},
+		{`{"error":"denied"}`, "", "denied", ""},
+		{`{"code":"rate_limited","message":"slow down"}`, "rate_limited", "slow down", ""},
+		{`not json`, "", "", ""},
+	}
+	for _, tc := range cases {
+		code, message, requestID := parseAPIErrorBody([]byte(tc.body))
+		if code != tc.code || message != tc.message || requestID != tc.requestID {
+			t.Fatalf("%s: got %q %q %q", tc.body, code, message, requestID)
+		}
+	}
+	if got := extractAPIErrorMessage([]byte(`{"error":"denied"}`), "403 Forbidden"); got != "denied" {
+		t.Fatalf("extractAPIErrorMessage = %q", got)
+	}
+	if got := extractAPIErrorMessage([]byte(`<html>`), "403 Forbidden"); got != "403 Forbidden" {
+		t.Fatalf("extractAPIErrorMessage = %q", got)
+	}
+}
@@ -0,0 +1,584 @@
+package main
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// markdownMode selects how response text is written to the terminal.
+type markdownMode int
+
+const (
+	// markdownRaw writes the text unchanged.
+	markdownRaw markdownMode = iota
+	// markdownRender styles headings, lists, quotes, tables and code with
+	// ANSI escapes.
+	markdownRender
+	// markdownExtractCode writes only the contents of fenced code blocks.
+	markdownExtractCode
+)
+
+const (
+	ansiReset     = "\033[0m"
+	ansiBold      = "\033[1m"
+	ansiDim       = "\033[2m"
+	ansiItalic    = "\033[3m"
+	ansiUnderline = "\033[4m"
+	ansiGreen     = "\033[32m"
+	ansiYellow    = "\033[33m"
+	ansiBlue      = "\033[34m"
+	ansiMagenta   = "\033[35m"
+	ansiCyan      = "\033[36m"
+	ansiGray      = "\033[90m"
+)
+
+var (
+	mdHeading     = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	mdRule        = regexp.MustCompile(`^\s*([-*_])(?:\s*[-*_]){2,}\s*$`)
+	mdQuote       = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdBullet      = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdOrdered     = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	mdTask        = regexp.MustCompile(`^\[([ xX])\]\s+`)
+	mdTableRow    = regexp.MustCompile(`^\s*\|.*\|\s*$`)
+	mdTableDelim  = regexp.MustCompile(`^\s*:?-+:?\s*$`)
+	mdBold        = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdItalic      = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	mdLink        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdStrike      = regexp.MustCompile(`~~([^~]+)~~`)
+	ansiEscapeSeq = regexp.MustCompile("\033\\[[0-9;]*m")
+)
+
+// markdownWriter renders response text as it arrives. Text is handled a line
+// at a time, so streamed deltas show up as soon as their line is complete;
+// tables are held back until their last row. Flush must be called once the
+// response is done.
+type markdownWriter struct {
+	out  io.Writer
+	mode markdownMode
+	err  error
+
+	pending string
+	table   []string
+
+	// Open fence: marker character, length and indent, plus the language
+	// and whether a block comment is still open for highlighting.
+	fenceChar    byte
+	fenceLen     int
+	fenceIndent  int
+	fenceSyntax  *codeSyntax
+	blockComment bool
+
+	codeBlocks int
+}
+
+func newMarkdownWriter(out io.Writer, mode markdownMode) *markdownWriter {
+	return &markdownWriter{out: out, mode: mode}
+}
+
+// responseMarkdownMode picks the output mode for a response: --extract-code
+// and --raw win, otherwise text is rendered only when stdout is a terminal
+// that accepts colors.
+func responseMarkdownMode(raw, extractCode bool) markdownMode {
+	switch {
+	case extractCode:
+		return markdownExtractCode
+	case raw:
+		return markdownRaw
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return markdownRaw
+	}
+	if stdoutIsTTY, err := isTerminal(os.Stdout); err != nil || !stdoutIsTTY {
+		return markdownRaw
+	}
+	return markdownRender
+}
+
+func (m *markdownWriter) Write(p []byte) (int, error) {
+	if m.mode == markdownRaw {
+		m.emit(string(p))
+		return len(p), m.err
+	}
+	m.pending += string(p)
+	for {
+		i := strings.IndexByte(m.pending, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSuffix(m.pending[:i], "\r")
+		m.pending = m.pending[i+1:]
+		m.line(line)
+	}
+	return len(p), m.err
+}
+
+// WriteString writes a text delta.
+func (m *markdownWriter) WriteString(s string) (int, error) {
+	return m.Write([]byte(s))
+}
+
+// Flush writes what is still buffered and ends the output with a newline.
+func (m *markdownWriter) Flush() error {
+	switch m.mode {
+	case markdownRaw:
+		m.emit("\n")
+	default:
+		if m.pending != "" {
+			line := m.pending
+			m.pending = ""
+			m.line(line)
+		}
+		m.flushTable()
+	}
+	return m.err
+}
+
+// foundCode reports whether any fenced code block was seen.
+func (m *markdownWriter) foundCode() bool {
+	return m.codeBlocks > 0
+}
+
+func (m *markdownWriter) emit(s string) {
+	if m.err != nil {
+		return
+	}
+	_, m.err = io.WriteString(m.out, s)
+}
+
+func (m *markdownWriter) line(line string) {
+	if m.fenceLen > 0 {
+		if m.closesFence(line) {
+			m.fenceLen = 0
+			m.blockComment = false
+			return
+		}
+		code := trimIndent(line, m.fenceIndent)
+		if m.mode == markdownExtractCode {
+			m.emit(code + "\n")
+			return
+		}
+		m.emit("  " + highlightCode(code, m.fenceSyntax, &m.blockComment) + "\n")
+		return
+	}
+	if m.opensFence(line) {
+		m.flushTable()
+		return
+	}
+	if m.mode == markdownExtractCode {
+		return
+	}
+	if mdTableRow.MatchString(line) {
+		m.table = append(m.table, line)
+		return
+	}
+	m.flushTable()
+	m.emit(renderMarkdownLine(line) + "\n")
+}
+
+// opensFence starts a code block on ``` or ~~~ lines.
+func (m *markdownWriter) opensFence(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	indent := len(line) - len(trimmed)
+	if !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") {
+		return false
+	}
+	char := trimmed[0]
+	n := len(trimmed) - len(strings.TrimLeft(trimmed, string(char)))
+	info := strings.TrimSpace(trimmed[n:])
+	if char == '`' && strings.Contains(info, "`") {
+		return false
+	}
+	lang, _, _ := strings.Cut(info, " ")
+	lang = strings.ToLower(strings.Trim(lang, "{}."))
+
+	if m.mode == markdownExtractCode && m.codeBlocks > 0 {
+		m.emit("\n")
+	}
+	m.codeBlocks++
+	m.fenceChar, m.fenceLen, m.fenceIndent = char, n, indent
+	m.fenceSyntax = codeSyntaxFor(lang)
+	m.blockComment = false
+	if m.mode == markdownRender && lang != "" {
+		m.emit(ansiGray + "  " + lang + ansiReset + "\n")
+	}
+	return true
+}
+
+func (m *markdownWriter) closesFence(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return len(trimmed) >= m.fenceLen && strings.Trim(trimmed, string(m.fenceChar)) == ""
+}
+
+// trimIndent removes up to n leading spaces, the indent of the opening fence.
+func trimIndent(line string, n int) string {
+	for n > 0 && strings.HasPrefix(line, " ") {
+		line = line[1:]
+		n--
+	}
+	return line
+}
+
+// renderMarkdownLine styles one line outside code blocks and tables.
+func renderMarkdownLine(line string) string {
+	if match := mdHeading.FindStringSubmatch(line); match != nil {
+		text := renderInline(match[2])
+		switch len(match[1]) {
+		case 1:
+			return ansiBold + ansiUnderline + ansiMagenta + text + ansiReset
+		case 2:
+			return ansiBold + ansiMagenta + text + ansiReset
+		default:
+			return ansiBold + text + ansiReset
+		}
+	}
+	if mdRule.MatchString(line) {
+		return ansiGray + strings.Repeat("─", 40) + ansiReset
+	}
+	if match := mdQuote.FindStringSubmatch(line); match != nil {
+		return ansiGray + "│ " + ansiReset + ansiItalic + renderInline(match[1]) + ansiReset
+	}
+	if match := mdBullet.FindStringSubmatch(line); match != nil {
+		marker := ansiCyan + "•" + ansiReset
+		text := match[2]
+		if task := mdTask.FindStringSubmatch(text); task != nil {
+			marker = ansiCyan + "☐" + ansiReset
+			if task[1] != " " {
+				marker = ansiGreen + "☑" + ansiReset
+			}
+			text = text[len(task[0]):]
+		}
+		return match[1] + marker + " " + renderInline(text)
+	}
+	if match := mdOrdered.FindStringSubmatch(line); match != nil {
+		return match[1] + ansiCyan + match[2] + "." + ansiReset + " " + renderInline(match[3])
+	}
+	return renderInline(line)
+}
+
+// renderInline styles code spans, bold, italic, strikethrough and links.
+// Text inside backticks is left alone.
+func renderInline(text string) string {
+	parts := strings.Split(text, "`")
+	if len(parts)%2 == 0 {
+		// An unmatched backtick is literal text.
+		parts[len(parts)-2] += "`" + parts[len(parts)-1]
+		parts = parts[:len(parts)-1]
+	}
+	var out strings.Builder
+	for i, part := range parts {
+		if i%2 == 1 {
+			out.WriteString(ansiYellow + part + ansiReset)
+			continue
+		}
+		part = mdLink.ReplaceAllString(part, ansiUnderline+ansiBlue+"$1"+ansiReset+ansiGray+" ($2)"+ansiReset)
+		part = mdBold.ReplaceAllString(part, ansiBold+"$1"+ansiReset)
+		part = mdItalic.ReplaceAllString(part, ansiItalic+"$1"+ansiReset)
+		part = mdStrike.ReplaceAllString(part, ansiDim+"$1"+ansiReset)
+		out.WriteString(part)
+	}
+	return out.String()
+}
+
+// flushTable renders buffered table rows with aligned columns.
+func (m *markdownWriter) flushTable() {
+	if len(m.table) == 0 {
+		return
+	}
+	rows := m.table
+	m.table = nil
+	m.emit(renderMarkdownTable(rows))
+}
+
+func renderMarkdownTable(lines []string) string {
+	var (
+		rows   [][]string
+		align  []byte
+		header = -1
+	)
+	for _, line := range lines {
+		cells := splitTableRow(line)
+		if header < 0 && len(rows) == 1 && isTableDelimiter(cells) {
+			header = 0
+			for _, cell := range cells {
+				cell = strings.TrimSpace(cell)
+				switch {
+				case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+					align = append(align, 'c')
+				case strings.HasSuffix(cell, ":"):
+					align = append(align, 'r')
+				default:
+					align = append(align, 'l')
+				}
+			}
+			continue
+		}
+		rendered := make([]string, len(cells))
+		for i, cell := range cells {
+			rendered[i] = renderInline(strings.TrimSpace(cell))
+		}
+		rows = append(rows, rendered)
+	}
+
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], visibleWidth(cell))
+		}
+	}
+	var out strings.Builder
+	for r, row := range rows {
+		cells := make([]string, len(widths))
+		for i := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			a := byte('l')
+			if i < len(align) {
+				a = align[i]
+			}
+			cells[i] = padCell(cell, widths[i], a)
+			if r == header {
+				cells[i] = ansiBold + cells[i] + ansiReset
+			}
+		}
+		out.WriteString(strings.Join(cells, ansiGray+" │ "+ansiReset) + "\n")
+		if r == header {
+			rule := make([]string, len(widths))
+			for i, width := range widths {
+				rule[i] = strings.Repeat("─", width)
+			}
+			out.WriteString(ansiGray + strings.Join(rule, "─┼─") + ansiReset + "\n")
+		}
+	}
+	return out.String()
+}
+
+// splitTableRow splits "| a | b |" into cells, keeping escaped pipes.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(strings.TrimSuffix(line, "|"), "|")
+	var (
+		cells []string
+		cell  strings.Builder
+	)
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, cell.String())
+}
+
+func isTableDelimiter(cells []string) bool {
+	for _, cell := range cells {
+		if !mdTableDelim.MatchString(cell) {
+			return false
+		}
+	}
+	return len(cells) > 0
+}
+
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiEscapeSeq.ReplaceAllString(s, ""))
+}
+
+func padCell(cell string, width int, align byte) string {
+	gap := width - visibleWidth(cell)
+	switch align {
+	case 'r':
+		return strings.Repeat(" ", gap) + cell
+	case 'c':
+		return strings.Repeat(" ", gap/2) + cell + strings.Repeat(" ", gap-gap/2)
+	default:
+		return cell + strings.Repeat(" ", gap)
+	}
+}
+
+// codeSyntax is what the highlighter knows about a language: keywords,
+// comment markers and string quotes.
+type codeSyntax struct {
+	keywords      map[string]bool
+	caseFold      bool
+	lineComments  []string
+	blockComments bool
+	quotes        string
+}
+
+func newCodeSyntax(keywords string, lineComments []string, blockComments bool, quotes string) *codeSyntax {
+	set := make(map[string]bool)
+	for _, keyword := range strings.Fields(keywords) {
+		set[keyword] = true
+	}
+	return &codeSyntax{keywords: set, lineComments: lineComments, blockComments: blockComments, quotes: quotes}
+}
+
+var (
+	goSyntax = newCodeSyntax(`break case chan const continue default defer else fallthrough for func go goto if
+		import interface map package range return select struct switch type var nil true false iota`,
+		[]string{"//"}, true, "\"'`")
+	pythonSyntax = newCodeSyntax(`and as assert async await break class continue def del elif else except False
+		finally for from global if import in is lambda None nonlocal not or pass raise return True try while with
+		yield self match case`, []string{"#"}, false, `"'`)
+	jsSyntax = newCodeSyntax(`break case catch class const continue debugger default delete do else enum export
+		extends false finally for function if implements import in instanceof interface let new null return static
+		super switch this throw true try type typeof undefined var void while with yield async await of readonly`,
+		[]string{"//"}, true, "\"'`")
+	shellSyntax = newCodeSyntax(`if then else elif fi for while until do done case esac function in return local
+		export readonly unset shift exit set source echo cd`, []string{"#"}, false, `"'`)
+	rustSyntax = newCodeSyntax(`as async await break const continue crate dyn else enum extern false fn for if impl
+		in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where
+		while`, []string{"//"}, true, `"`)
+	cSyntax = newCodeSyntax(`abstract auto bool boolean break byte case catch char class const continue default
+		delete do double else enum extends extern false final finally float for fun func if implements import inline
+		int interface let long namespace new null nullptr override package private protected public return short
+		signed sizeof static struct super switch template this throw throws true try typedef typename union unsigned
+		using val var virtual void volatile when while`, []string{"//"}, true, `"'`)
+	sqlSyntax = func() *codeSyntax {
+		syntax := newCodeSyntax(`select from where insert into update delete create table drop alter add column
+			join left right inner outer full cross on group by order having limit offset as and or not null is in
+			values set union all distinct case when then else end primary key foreign references index view with
+			asc desc exists between like ilike returning default begin commit rollback`, []string{"--"}, true, `'"`)
+		syntax.caseFold = true
+		return syntax
+	}()
+	dataSyntax = newCodeSyntax(`true false null yes no on off`, []string{"#"}, false, `"'`)
+	jsonSyntax = newCodeSyntax(`true false null`, nil, false, `"`)
+)
+
+// codeSyntaxFor maps a fence language to its syntax; unknown languages are
+// not highlighted.
+func codeSyntaxFor(lang string) *codeSyntax {
+	switch lang {
+	case "go", "golang":
+		return goSyntax
+	case "python", "py", "python3":
+		return pythonSyntax
+	case "javascript", "js", "jsx", "typescript", "ts", "tsx", "mjs":
+		return jsSyntax
+	case "bash", "sh", "shell", "zsh", "console", "shellsession":
+		return shellSyntax
+	case "rust", "rs":
+		return rustSyntax
+	case "c", "h", "cpp", "c++", "cc", "java", "kotlin", "kt", "csharp", "cs", "swift", "scala":
+		return cSyntax
+	case "sql", "postgres", "postgresql", "mysql", "sqlite":
+		return sqlSyntax
+	case "yaml", "yml", "toml", "ini", "dockerfile", "make", "makefile":
+		return dataSyntax
+	case "json", "jsonc", "jsonl":
+		return jsonSyntax
+	}
+	return nil
+}
+
+// highlightCode colors one line of code. blockComment carries an open /* */
+// comment from line to line.
+func highlightCode(line string, syntax *codeSyntax, blockComment *bool) string {
+	if syntax == nil || line == "" {
+		return line
+	}
+	var out strings.Builder
+	i := 0
+	for i < len(line) {
+		rest := line[i:]
+		if *blockComment || syntax.blockComments && strings.HasPrefix(rest, "/*") {
+			from := i
+			if !*blockComment {
+				from += 2
+			}
+			end := strings.Index(line[from:], "*/")
+			if end < 0 {
+				*blockComment = true
+				out.WriteString(ansiGray + rest + ansiReset)
+				return out.String()
+			}
+			out.WriteString(ansiGray + line[i:from+end+2] + ansiReset)
+			i = from + end + 2
+			*blockComment = false
+			continue
+		}
+		if isLineComment(line, i, syntax) {
+			out.WriteString(ansiGray + rest + ansiReset)
+			return out.String()
+		}
+		c := line[i]
+		switch {
+		case strings.IndexByte(syntax.quotes, c) >= 0:
+			end := i + 1
+			for end < len(line) && line[end] != c {
+				if line[end] == '\\' && c != '`' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(line))
+			out.WriteString(ansiGreen + line[i:end] + ansiReset)
+			i = end
+		case c >= '0' && c <= '9' && (i == 0 || !isWordByte(line[i-1])):
+			end := i
+			for end < len(line) && (isWordByte(line[end]) || line[end] == '.') {
+				end++
+			}
+			out.WriteString(ansiMagenta + line[i:end] + ansiReset)
+			i = end
+		case isWordByte(c):
+			end := i
+			for end < len(line) && isWordByte(line[end]) {
+				end++
+			}
+			word := line[i:end]
+			key := word
+			if syntax.caseFold {
+				key = strings.ToLower(word)
+			}
+			if syntax.keywords[key] {
+				out.WriteString(ansiBlue + word + ansiReset)
+			} else if end < len(line) && line[end] == '(' {
+				out.WriteString(ansiYellow + word + ansiReset)
+			} else {
+				out.WriteString(word)
+			}
+			i = end
+		default:
+			_, size := utf8.DecodeRuneInString(rest)
+			out.WriteString(rest[:size])
+			i += size
+		}
+	}
+	return out.String()
+}
+
+// isLineComment reports whether a line comment starts at i. A # only starts a
+// comment at the start of a word, so ${#list} and URLs with fragments stay
+// code.
+func isLineComment(line string, i int, syntax *codeSyntax) bool {
+	for _, marker := range syntax.lineComments {
+		if !strings.HasPrefix(line[i:], marker) {
+			continue
+		}
+		if marker == "#" && i > 0 && !unicode.IsSpace(rune(line[i-1])) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const markdownSample = "# Rotate logs\n\nRun **this** as `root`:\n\n```bash\n# keep a week\nfind /var/log -mtime +7 -delete\n```\n\n| Flag | Meaning |\n|:-----|--------:|\n| -n | dry run |\n\n- [x] done\n- item\n\n  ~~~python\n  print(\"hi\")\n  ~~~\n"
+
+func renderAll(text string, mode markdownMode, chunk int) (string, *markdownWriter) {
+	var out strings.Builder
+	writer := newMarkdownWriter(&out, mode)
+	for len(text) > 0 {
+		n := min(chunk, len(text))
+		_, _ = writer.WriteString(text[:n])
+		text = text[n:]
+	}
+	_ = writer.Flush()
+	return out.String(), writer
+}
+
+func TestMarkdownExtractCode(t *testing.T) {
+	got, writer := renderAll(markdownSample, markdownExtractCode, 3)
+	want := "# keep a week\nfind /var/log -mtime +7 -delete\n\nprint(\"hi\")\n"
+	if got != want || !writer.foundCode() {
+		t.Fatalf("extracted = %q", got)
+	}
+	if _, writer := renderAll("no code here\n", markdownExtractCode, 100); writer.foundCode() {
+		t.Fatal("expected no code blocks")
+	}
+}
+
+func TestMarkdownRenderStreamsLikeWhole(t *testing.T) {
+	whole, _ := renderAll(markdownSample, markdownRender, len(markdownSample))
+	streamed, _ := renderAll(markdownSample, markdownRender, 1)
+	if whole != streamed {
+		t.Fatalf("streamed output differs:\n%q\n%q", whole, streamed)
+	}
+	plain := ansiEscapeSeq.ReplaceAllString(whole, "")
+	for _, want := range []string{
+		"Rotate logs\n",
+		"Run this as root:\n",
+		"  bash\n  # keep a week\n",
+		"Flag │ Meaning\n─────┼────────\n-n   │ dry run\n",
+		"☑ done\n• item\n",
+		"  print(\"hi\")\n",
+	} {
+		if !strings.Contains(plain, want) {
+			t.Fatalf("rendered output lacks %q:\n%s", want, plain)
+		}
+	}
+	if strings.Contains(plain, "```") || strings.Contains(plain, "**") {
+		t.Fatalf("markdown syntax left in output:\n%s", plain)
+	}
+	if !strings.Contains(whole, ansiGray+"# keep a week"+ansiReset) {
+		t.Fatalf("shell comment not highlighted: %q", whole)
+	}
+}
+
+func TestMarkdownRawPassesThrough(t *testing.T) {
+	got, _ := renderAll("**as is**", markdownRaw, 2)
+	if got != "**as is**\n" {
+		t.Fatalf("raw = %q", got)
+	}
+}
+
+func TestHighlightCode(t *testing.T) {
+	var open bool
+	got := highlightCode(`func main() { x := "a // b" /* start`, goSyntax, &open)
+	if !open {
+		t.Fatal("expected an open block comment")
+	}
+	want := ansiBlue + "func" + ansiReset + " " + ansiYellow + "main" + ansiReset + "() { x := " +
+		ansiGreen + `"a // b"` + ansiReset + " " + ansiGray + "/* start" + ansiReset
+	if got != want {
+		t.Fatalf("highlight = %q", got)
+	}
+	if got := highlightCode("end */ return 1", goSyntax, &open); open || !strings.HasPrefix(got, ansiGray+"end */"+ansiReset) {
+		t.Fatalf("block comment end = %q, open=%v", got, open)
+	}
+	if got := highlightCode("SELECT id FROM t -- all", sqlSyntax, &open); !strings.Contains(got, ansiBlue+"SELECT"+ansiReset) || !strings.HasSuffix(got, ansiGray+"-- all"+ansiReset) {
+		t.Fatalf("sql = %q", got)
+	}
+	if got := highlightCode("plain text", nil, &open); got != "plain text" {
+		t.Fatalf("unknown language = %q", got)
+	}
+}
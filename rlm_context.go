@@ -71,6 +71,12 @@ func buildRLMFileAttachments(paths []string, overrideMime string, stdin io.Reade
 			}
 			return nil, nil, errors.New("attachment path is empty")
 		}
+		if isURLAttachment(path) {
+			if cleanup != nil {
+				cleanup()
+			}
+			return nil, nil, errURLAttachmentsDisabled
+		}
 
 		if path == "-" {
 			if seenStdin {
@@ -98,7 +104,13 @@ func buildRLMFileAttachments(paths []string, overrideMime string, stdin io.Reade
 				mimeType = llm.NormalizeMimeType(detectMimeType(stdinPath, sample))
 			}
 			if !isTextMime(mimeType) {
-				text = ""
+				text, err = maybeExtractInlineText(stdinPath, mimeType, size, textInlineLimit)
+				if err != nil {
+					if cleanup != nil {
+						cleanup()
+					}
+					return nil, nil, err
+				}
 			}
 			files = append(files, rlmFileAttachment{
 				Name: "stdin",
@@ -235,11 +247,17 @@ func writeStdinToTempFile(stdin io.Reader, dir string, textInlineLimit int64) (s
 	return absPath, size, sample, textOut, nil
 }
 
+// maybeReadInlineText returns the text of an attachment to inline into the
+// RLM context: text files as they are, and documents with a text extractor
+// (PDF, DOCX, XLSX, CSV, HTML) as extracted text, both up to textInlineLimit.
 func maybeReadInlineText(path string, mime llm.MimeType, size int64, textInlineLimit int64) (string, error) {
-	if textInlineLimit <= 0 || size <= 0 || size > textInlineLimit {
+	if textInlineLimit <= 0 || size <= 0 {
 		return "", nil
 	}
 	if !isTextMime(mime) {
+		return maybeExtractInlineText(path, mime, size, textInlineLimit)
+	}
+	if size > textInlineLimit {
 		return "", nil
 	}
 	data, err := os.ReadFile(path) //nolint:gosec // context path is the CLI-owned temporary attachment
@@ -252,6 +270,27 @@ func maybeReadInlineText(path string, mime llm.MimeType, size int64, textInlineL
 	return string(data), nil
 }
 
+// maybeExtractInlineText extracts document text for inlining. Documents that
+// fail to extract stay path references.
+func maybeExtractInlineText(path string, mime llm.MimeType, size int64, textInlineLimit int64) (string, error) {
+	if extractorFor(path, mime) == nil || size > maxExtractSourceBytes {
+		return "", nil
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // context path is the CLI-owned temporary attachment
+	if err != nil {
+		return "", err
+	}
+	text, _, err := extractText(path, mime, data)
+	if err != nil {
+		log.Printf("warning: %v", err)
+		return "", nil
+	}
+	if int64(len(text)) > textInlineLimit {
+		return "", nil
+	}
+	return text, nil
+}
+
 func isTextMime(mime llm.MimeType) bool {
 	normalized := strings.ToLower(string(mime))
 	if strings.HasPrefix(normalized, "text/") {
@@ -0,0 +1,713 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/modelrelay/modelrelay/platform/rlmrunner"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+	"github.com/spf13/cobra"
+)
+
+// `mrl rlm --index dir` mounts a chunked full-text index over a document
+// folder as a data source. The index is built on first use and cached under
+// <data>/indexes; later runs re-read only files whose size or modification
+// time changed. Chunks are ranked with BM25, fused with cosine similarity
+// when the index carries embeddings.
+//
+// The runner reaches the index through the loopback server as an mcp_http
+// source with read-only search and read tools, so the sandbox calls
+// docs.search(query=...) like any other MCP binding; the bearer token is
+// resolved through the MCP secret broker and never enters the sandbox.
+
+const (
+	rlmIndexVersion = 1
+	// rlmIndexTenant and rlmIndexTokenRef address the loopback token in the
+	// MCP secret broker.
+	rlmIndexTenant   = "mrl-local"
+	rlmIndexTokenRef = "index-token"
+	// rlmIndexNetwork admits the loopback server to the runner's MCP egress.
+	rlmIndexNetwork = "127.0.0.1/32"
+
+	defaultIndexSearchResults = 8
+	maxIndexSearchResults     = 50
+	maxIndexReadBytes         = 64 << 10
+	// bm25K1 and bm25B are the usual BM25 saturation and length
+	// normalization parameters.
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// rrfK damps reciprocal rank fusion of the BM25 and embedding rankings.
+	rrfK = 60
+)
+
+var rlmIndexTools = []string{"search", "read"}
+
+// rlmIndexFile is the cached form of an index.
+type rlmIndexFile struct {
+	Version     int                       `json:"version"`
+	Dir         string                    `json:"dir"`
+	ChunkTokens int64                     `json:"chunk_tokens"`
+	Model       string                    `json:"model,omitempty"`
+	Files       map[string]rlmIndexedFile `json:"files"`
+}
+
+type rlmIndexedFile struct {
+	Size    int64           `json:"size"`
+	ModTime time.Time       `json:"mod_time"`
+	Chunks  []rlmIndexChunk `json:"chunks"`
+}
+
+type rlmIndexChunk struct {
+	StartLine int       `json:"start_line"`
+	EndLine   int       `json:"end_line"`
+	Text      string    `json:"text"`
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// rlmIndexOptions configures buildRLMIndex. With Embed set, chunks are
+// embedded with Model and searches fuse both rankings. Without it, cached
+// embeddings are kept but not used.
+type rlmIndexOptions struct {
+	ChunkTokens int64
+	IgnoreDirs  []string
+	Model       string
+	Embed       func(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+type rlmIndexStats struct {
+	Files   int
+	Chunks  int
+	Updated int
+	Skipped []string
+}
+
+// rlmIndex is a loaded index with BM25 statistics.
+type rlmIndex struct {
+	files     map[string]rlmIndexedFile
+	paths     []string
+	chunkPath []string
+	chunks    []rlmIndexChunk
+	terms     []map[string]int
+	lengths   []int
+	docFreq   map[string]int
+	avgLength float64
+	embed     func(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+type rlmIndexResult struct {
+	ID        string  `json:"id"`
+	Path      string  `json:"path"`
+	StartLine int     `json:"start_line"`
+	EndLine   int     `json:"end_line"`
+	Score     float64 `json:"score"`
+	Text      string  `json:"text"`
+}
+
+func rlmIndexCachePath(dir string) (string, error) {
+	data, err := defaultDataDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(data, "indexes", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// loadRLMDocumentIndex builds the --index source, or returns nil without
+// --index. Embedding calls are recorded in the usage ledger under the rlm
+// command.
+func loadRLMDocumentIndex(ctx context.Context, cmd *cobra.Command, cfg runtimeConfig, flags *rlmFlags) (*rlmIndex, error) {
+	dir := strings.TrimSpace(flags.index)
+	embeddings := flags.indexEmbeddings || strings.TrimSpace(flags.indexEmbeddingModel) != ""
+	if dir == "" {
+		if embeddings {
+			return nil, errors.New("--index-embeddings requires --index")
+		}
+		return nil, nil
+	}
+	if !validMCPSourceName(strings.TrimSpace(flags.indexName)) {
+		return nil, errors.New("--index-name must be a public ASCII Python identifier")
+	}
+	opts := rlmIndexOptions{ChunkTokens: defaultEmbedChunkTokens, IgnoreDirs: cfg.IgnoreDirs}
+	if embeddings {
+		if strings.TrimSpace(cfg.APIKey) == "" {
+			return nil, errors.New("--index-embeddings requires an API key")
+		}
+		model := strings.TrimSpace(flags.indexEmbeddingModel)
+		if model == "" {
+			var err error
+			if model, err = defaultCapabilityModel(ctx, cfg, embedCapability); err != nil {
+				return nil, err
+			}
+		}
+		command := ledgerCommandName(cmd)
+		opts.Model = model
+		opts.Embed = func(ctx context.Context, texts []string) ([][]float32, error) {
+			start := time.Now()
+			vectors, servedModel, usage, calls, err := createEmbeddings(ctx, cfg, model, texts, 0)
+			recordUsage(command, cfg, firstNonEmpty(servedModel, model), calls, usage, time.Since(start), nil)
+			return vectors, err
+		}
+	}
+	start := time.Now()
+	index, stats, err := buildRLMIndex(ctx, dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	warnContextPack(os.Stderr, stats.Skipped, contextPackReport{})
+	fmt.Fprintf(os.Stderr, "rlm: indexed %s: %d files, %d chunks (%d updated) in %s\n", dir, stats.Files, stats.Chunks, stats.Updated, time.Since(start).Round(time.Millisecond))
+	return index, nil
+}
+
+// buildRLMIndex builds or refreshes the cached index of dir.
+func buildRLMIndex(ctx context.Context, dir string, opts rlmIndexOptions) (*rlmIndex, rlmIndexStats, error) {
+	var stats rlmIndexStats
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, stats, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, stats, err
+	}
+	if !info.IsDir() {
+		return nil, stats, fmt.Errorf("--index %s is not a directory", dir)
+	}
+	cachePath, err := rlmIndexCachePath(abs)
+	if err != nil {
+		return nil, stats, err
+	}
+	cached := loadRLMIndexFile(cachePath, abs, opts.ChunkTokens)
+	model := cached.Model
+	if opts.Embed != nil && cached.Model != opts.Model {
+		// Vectors from another model are not comparable; re-embed.
+		model = opts.Model
+		for path, file := range cached.Files {
+			for i := range file.Chunks {
+				file.Chunks[i].Embedding = nil
+			}
+			cached.Files[path] = file
+		}
+	}
+
+	paths, err := walkAttachmentDir(abs, opts.IgnoreDirs)
+	if err != nil {
+		return nil, stats, err
+	}
+	next := rlmIndexFile{Version: rlmIndexVersion, Dir: abs, ChunkTokens: opts.ChunkTokens, Model: model, Files: map[string]rlmIndexedFile{}}
+	for _, path := range paths {
+		rel, err := filepath.Rel(abs, path)
+		if err != nil {
+			return nil, stats, err
+		}
+		rel = filepath.ToSlash(rel)
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			return nil, stats, err
+		}
+		if old, ok := cached.Files[rel]; ok && old.Size == fileInfo.Size() && old.ModTime.Equal(fileInfo.ModTime()) {
+			next.Files[rel] = old
+			continue
+		}
+		text, reason := readIndexText(path, fileInfo.Size())
+		if reason != "" {
+			stats.Skipped = append(stats.Skipped, rel+" ("+reason+")")
+			continue
+		}
+		var chunks []rlmIndexChunk
+		for _, chunk := range chunkText(text, opts.ChunkTokens) {
+			chunks = append(chunks, rlmIndexChunk{StartLine: chunk.StartLine, EndLine: chunk.EndLine, Text: chunk.Text})
+		}
+		next.Files[rel] = rlmIndexedFile{Size: fileInfo.Size(), ModTime: fileInfo.ModTime(), Chunks: chunks}
+		stats.Updated++
+	}
+
+	if opts.Embed != nil {
+		if err := embedIndexChunks(ctx, next.Files, opts.Embed); err != nil {
+			return nil, stats, fmt.Errorf("embed index: %w", err)
+		}
+	}
+	if data, err := json.Marshal(next); err == nil {
+		if err := writeFileAtomic(cachePath, data); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not cache the index of %s: %v\n", dir, err)
+		}
+	}
+
+	index := newRLMIndex(next.Files)
+	index.embed = opts.Embed
+	stats.Files = len(index.paths)
+	stats.Chunks = len(index.chunks)
+	return index, stats, nil
+}
+
+// loadRLMIndexFile returns the cached index when it matches dir and the chunk
+// size, otherwise an empty one.
+func loadRLMIndexFile(path, dir string, chunkTokens int64) rlmIndexFile {
+	empty := rlmIndexFile{Files: map[string]rlmIndexedFile{}}
+	data, err := os.ReadFile(path) //nolint:gosec // path is derived from the data directory
+	if err != nil {
+		return empty
+	}
+	var file rlmIndexFile
+	if json.Unmarshal(data, &file) != nil || file.Version != rlmIndexVersion || file.Dir != dir || file.ChunkTokens != chunkTokens || file.Files == nil {
+		return empty
+	}
+	return file
+}
+
+// readIndexText returns the text of a corpus file, converting documents that
+// have an extractor. reason is set when the file is skipped.
+func readIndexText(path string, size int64) (string, string) {
+	if size == 0 {
+		return "", ""
+	}
+	if size > maxExtractSourceBytes {
+		return "", fmt.Sprintf("larger than %d MB", maxExtractSourceBytes>>20)
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // corpus files are under the directory selected by the CLI user
+	if err != nil {
+		return "", err.Error()
+	}
+	if !isBinaryData(data) {
+		return string(data), ""
+	}
+	text, ok, err := extractText(path, llm.NormalizeMimeType(detectMimeType(path, data)), data)
+	switch {
+	case !ok:
+		return "", "binary"
+	case err != nil:
+		return "", "no extractable text"
+	}
+	return text, ""
+}
+
+func embedIndexChunks(ctx context.Context, files map[string]rlmIndexedFile, embed func(context.Context, []string) ([][]float32, error)) error {
+	type ref struct {
+		path  string
+		chunk int
+	}
+	var (
+		refs  []ref
+		texts []string
+	)
+	for path, file := range files {
+		for i, chunk := range file.Chunks {
+			if len(chunk.Embedding) == 0 {
+				refs = append(refs, ref{path, i})
+				texts = append(texts, chunk.Text)
+			}
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+	vectors, err := embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+	for i, r := range refs {
+		files[r.path].Chunks[r.chunk].Embedding = vectors[i]
+	}
+	return nil
+}
+
+func newRLMIndex(files map[string]rlmIndexedFile) *rlmIndex {
+	index := &rlmIndex{files: files, docFreq: map[string]int{}}
+	for path := range files {
+		index.paths = append(index.paths, path)
+	}
+	sort.Strings(index.paths)
+	total := 0
+	for _, path := range index.paths {
+		for _, chunk := range files[path].Chunks {
+			terms := map[string]int{}
+			length := 0
+			for _, term := range indexTerms(chunk.Text) {
+				terms[term]++
+				length++
+			}
+			for term := range terms {
+				index.docFreq[term]++
+			}
+			index.chunkPath = append(index.chunkPath, path)
+			index.chunks = append(index.chunks, chunk)
+			index.terms = append(index.terms, terms)
+			index.lengths = append(index.lengths, length)
+			total += length
+		}
+	}
+	if len(index.chunks) > 0 {
+		index.avgLength = float64(total) / float64(len(index.chunks))
+	}
+	return index
+}
+
+// indexTerms lowercases text and splits it into letter and digit runs.
+func indexTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func (x *rlmIndex) hasEmbeddings() bool {
+	return x.embed != nil && len(x.chunks) > 0 && len(x.chunks[0].Embedding) > 0
+}
+
+// search ranks chunks for query, optionally limited to paths starting with
+// prefix.
+func (x *rlmIndex) search(ctx context.Context, query string, k int, prefix string) ([]rlmIndexResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("query is required")
+	}
+	if k <= 0 {
+		k = defaultIndexSearchResults
+	}
+	k = min(k, maxIndexSearchResults)
+	prefix = strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(prefix)), "./")
+
+	candidates := make([]int, 0, len(x.chunks))
+	for i := range x.chunks {
+		if strings.HasPrefix(x.chunkPath[i], prefix) {
+			candidates = append(candidates, i)
+		}
+	}
+	bm25 := x.bm25Scores(indexTerms(query), candidates)
+	scores := bm25
+	if x.hasEmbeddings() {
+		vectors, err := x.embed(ctx, []string{query})
+		if err != nil {
+			return nil, fmt.Errorf("embed query: %w", err)
+		}
+		cosine := map[int]float64{}
+		queryNorm := vectorNorm(vectors[0])
+		for _, i := range candidates {
+			embedding := x.chunks[i].Embedding
+			norm := vectorNorm(embedding)
+			if len(embedding) != len(vectors[0]) || norm == 0 || queryNorm == 0 {
+				continue
+			}
+			var dot float64
+			for d, value := range embedding {
+				dot += float64(value) * float64(vectors[0][d])
+			}
+			cosine[i] = dot / (norm * queryNorm)
+		}
+		scores = fuseRankings(bm25, cosine)
+	}
+
+	ranked := make([]int, 0, len(scores))
+	for i := range scores {
+		ranked = append(ranked, i)
+	}
+	sort.Slice(ranked, func(a, b int) bool {
+		if scores[ranked[a]] != scores[ranked[b]] {
+			return scores[ranked[a]] > scores[ranked[b]]
+		}
+		return ranked[a] < ranked[b]
+	})
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+	results := make([]rlmIndexResult, 0, len(ranked))
+	for _, i := range ranked {
+		chunk := x.chunks[i]
+		results = append(results, rlmIndexResult{
+			ID:        fmt.Sprintf("%s#L%d-%d", x.chunkPath[i], chunk.StartLine, chunk.EndLine),
+			Path:      x.chunkPath[i],
+			StartLine: chunk.StartLine,
+			EndLine:   chunk.EndLine,
+			Score:     math.Round(scores[i]*10000) / 10000,
+			Text:      chunk.Text,
+		})
+	}
+	return results, nil
+}
+
+// bm25Scores scores the candidate chunks containing at least one query term.
+func (x *rlmIndex) bm25Scores(query []string, candidates []int) map[int]float64 {
+	scores := map[int]float64{}
+	n := float64(len(x.chunks))
+	for _, term := range uniqueStrings(query) {
+		df := float64(x.docFreq[term])
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, i := range candidates {
+			tf := float64(x.terms[i][term])
+			if tf == 0 {
+				continue
+			}
+			norm := tf + bm25K1*(1-bm25B+bm25B*float64(x.lengths[i])/x.avgLength)
+			scores[i] += idf * tf * (bm25K1 + 1) / norm
+		}
+	}
+	return scores
+}
+
+// fuseRankings combines two score maps by reciprocal rank fusion.
+func fuseRankings(rankings ...map[int]float64) map[int]float64 {
+	fused := map[int]float64{}
+	for _, scores := range rankings {
+		order := make([]int, 0, len(scores))
+		for i := range scores {
+			order = append(order, i)
+		}
+		sort.Slice(order, func(a, b int) bool {
+			if scores[order[a]] != scores[order[b]] {
+				return scores[order[a]] > scores[order[b]]
+			}
+			return order[a] < order[b]
+		})
+		for rank, i := range order {
+			fused[i] += 1 / float64(rrfK+rank+1)
+		}
+	}
+	return fused
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+// read returns lines start..end (1-based, inclusive; 0 means the file's
+// first or last line) of an indexed file, as indexed, capped at
+// maxIndexReadBytes.
+func (x *rlmIndex) read(path string, start, end int) (string, bool, error) {
+	path = strings.TrimPrefix(filepath.ToSlash(strings.TrimSpace(path)), "./")
+	file, ok := x.files[path]
+	if !ok {
+		return "", false, fmt.Errorf("%s is not in the index", path)
+	}
+	var text strings.Builder
+	for _, chunk := range file.Chunks {
+		text.WriteString(chunk.Text)
+	}
+	lines := strings.SplitAfter(text.String(), "\n")
+	if start <= 0 {
+		start = 1
+	}
+	if end <= 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > end {
+		return "", false, fmt.Errorf("%s has %d lines", path, len(lines))
+	}
+	out := strings.Join(lines[start-1:end], "")
+	if len(out) > maxIndexReadBytes {
+		return out[:maxIndexReadBytes], true, nil
+	}
+	return out, false, nil
+}
+
+// rlmIndexDataSource describes the loopback MCP endpoint as a runner data
+// source named name.
+func rlmIndexDataSource(name, endpoint string) (rlmrunner.DataSourceSpec, error) {
+	bindings := map[string]string{}
+	effects := map[string]string{}
+	budgets := map[string]string{}
+	for _, tool := range rlmIndexTools {
+		bindings[tool] = tool
+		effects[tool] = "read"
+		budgets[tool] = "data.read"
+	}
+	config, err := json.Marshal(map[string]any{
+		"endpoint":          endpoint,
+		"allowed_endpoints": []string{endpoint},
+		"tenant_id":         rlmIndexTenant,
+		"auth":              map[string]string{"type": "bearer", "token_ref": rlmIndexTokenRef},
+		"allowed_tools":     rlmIndexTools,
+		"bindings":          bindings,
+		"effects":           effects,
+		"budget_classes":    budgets,
+	})
+	if err != nil {
+		return rlmrunner.DataSourceSpec{}, err
+	}
+	return rlmrunner.DataSourceSpec{Type: "mcp_http", Name: name, MCPConfig: config}, nil
+}
+
+// rlmIndexMCPHandler serves the index as a minimal MCP server over HTTP:
+// JSON-RPC requests answered with JSON bodies.
+type rlmIndexMCPHandler struct {
+	ctx   context.Context
+	index *rlmIndex
+	token string
+}
+
+type mcpRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type mcpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (h *rlmIndexMCPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !validBearerToken(r, h.token) {
+		writeSQLBrokerError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var req mcpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeMCPResponse(w, nil, nil, &mcpError{Code: -32700, Message: "parse error"})
+		return
+	}
+	if len(req.ID) == 0 {
+		// Notifications such as notifications/initialized need no answer.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	result, rpcErr := h.handle(req)
+	writeMCPResponse(w, req.ID, result, rpcErr)
+}
+
+func (h *rlmIndexMCPHandler) handle(req mcpRequest) (any, *mcpError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		return map[string]any{
+			"protocolVersion": firstNonEmpty(params.ProtocolVersion, "2025-06-18"),
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": "mrl-index", "version": version},
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		return map[string]any{"tools": rlmIndexToolDefinitions()}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &mcpError{Code: -32602, Message: "invalid params"}
+		}
+		structured, err := h.call(params.Name, params.Arguments)
+		if err != nil {
+			return map[string]any{
+				"content": []map[string]string{{"type": "text", "text": err.Error()}},
+				"isError": true,
+			}, nil
+		}
+		text, _ := json.Marshal(structured)
+		return map[string]any{
+			"content":           []map[string]string{{"type": "text", "text": string(text)}},
+			"structuredContent": structured,
+		}, nil
+	default:
+		return nil, &mcpError{Code: -32601, Message: "method not found: " + req.Method}
+	}
+}
+
+func (h *rlmIndexMCPHandler) call(name string, raw json.RawMessage) (map[string]any, error) {
+	if len(raw) == 0 {
+		raw = json.RawMessage("{}")
+	}
+	switch name {
+	case "search":
+		var args struct {
+			Query      string `json:"query"`
+			K          int    `json:"k"`
+			PathPrefix string `json:"path_prefix"`
+		}
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, fmt.Errorf("invalid search arguments: %w", err)
+		}
+		results, err := h.index.search(h.ctx, args.Query, args.K, args.PathPrefix)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"results": results}, nil
+	case "read":
+		var args struct {
+			Path      string `json:"path"`
+			StartLine int    `json:"start_line"`
+			EndLine   int    `json:"end_line"`
+		}
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, fmt.Errorf("invalid read arguments: %w", err)
+		}
+		text, truncated, err := h.index.read(args.Path, args.StartLine, args.EndLine)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"path": args.Path, "text": text, "truncated": truncated}, nil
+	default:
+		return nil, fmt.Errorf("unknown tool %q", name)
+	}
+}
+
+func rlmIndexToolDefinitions() []map[string]any {
+	return []map[string]any{
+		{
+			"name":        "search",
+			"description": "Full-text search over the indexed documents. Returns the best matching chunks with their path, line range, score and text.",
+			"inputSchema": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query":       map[string]any{"type": "string", "description": "Search terms"},
+					"k":           map[string]any{"type": "integer", "description": fmt.Sprintf("Number of results (default %d, max %d)", defaultIndexSearchResults, maxIndexSearchResults)},
+					"path_prefix": map[string]any{"type": "string", "description": "Only search files under this relative path"},
+				},
+				"required": []string{"query"},
+			},
+			"annotations": map[string]any{"readOnlyHint": true},
+		},
+		{
+			"name":        "read",
+			"description": "Read an indexed file, or a line range of it, by its relative path.",
+			"inputSchema": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"path":       map[string]any{"type": "string", "description": "Relative path from a search result"},
+					"start_line": map[string]any{"type": "integer", "description": "First line (1-based, default 1)"},
+					"end_line":   map[string]any{"type": "integer", "description": "Last line (inclusive, default end of file)"},
+				},
+				"required": []string{"path"},
+			},
+			"annotations": map[string]any{"readOnlyHint": true},
+		},
+	}
+}
+
+func writeMCPResponse(w http.ResponseWriter, id json.RawMessage, result any, rpcErr *mcpError) {
+	response := map[string]any{"jsonrpc": "2.0", "id": id}
+	if rpcErr != nil {
+		response["error"] = rpcErr
+	} else {
+		response["result"] = result
+	}
+	writeJSON(w, http.StatusOK, response)
+}
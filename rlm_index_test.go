@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func buildTestIndex(t *testing.T, opts rlmIndexOptions) (string, *rlmIndex, rlmIndexStats) {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"guides/retries.md": "# Retries\n\nThe client retries rate limited requests with exponential backoff.\n",
+		"guides/billing.md": "# Billing\n\nInvoices are issued monthly. Usage is billed per token.\n",
+		"notes/empty.txt":   "",
+		"logo.png":          "\x89PNG\x00\x00",
+		"build/out.md":      "retries retries retries",
+		".gitignore":        "build/\n",
+	})
+	index, stats, err := buildRLMIndex(context.Background(), root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root, index, stats
+}
+
+func TestRLMIndexSearchAndCache(t *testing.T) {
+	root, index, stats := buildTestIndex(t, rlmIndexOptions{ChunkTokens: defaultEmbedChunkTokens})
+	if stats.Updated != 4 || len(stats.Skipped) != 1 || !strings.HasPrefix(stats.Skipped[0], "logo.png") {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	results, err := index.search(context.Background(), "How are rate limited requests retried?", 5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].Path != "guides/retries.md" || results[0].StartLine != 1 {
+		t.Fatalf("results = %+v", results)
+	}
+	for _, result := range results {
+		if strings.HasPrefix(result.Path, "build/") {
+			t.Fatalf("ignored file was indexed: %+v", result)
+		}
+	}
+	if filtered, _ := index.search(context.Background(), "retries", 5, "./notes"); len(filtered) != 0 {
+		t.Fatalf("path_prefix not applied: %+v", filtered)
+	}
+
+	_, again, err := buildRLMIndex(context.Background(), root, rlmIndexOptions{ChunkTokens: defaultEmbedChunkTokens})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Updated != 0 || again.Chunks != stats.Chunks {
+		t.Fatalf("expected the cached index to be reused, got %+v", again)
+	}
+
+	text, truncated, err := index.read("guides/billing.md", 3, 3)
+	if err != nil || truncated || text != "Invoices are issued monthly. Usage is billed per token.\n" {
+		t.Fatalf("read = %q, %v, %v", text, truncated, err)
+	}
+	if _, _, err := index.read("../secret.txt", 0, 0); err == nil {
+		t.Fatal("expected an error for a path outside the index")
+	}
+}
+
+func TestRLMIndexFusesEmbeddings(t *testing.T) {
+	// The fake model puts billing chunks and the query on the same axis, so
+	// the fused ranking lifts billing although the query shares no terms.
+	embed := func(_ context.Context, texts []string) ([][]float32, error) {
+		out := make([][]float32, len(texts))
+		for i, text := range texts {
+			if strings.Contains(text, "Invoices") || strings.Contains(text, "money") {
+				out[i] = []float32{1, 0}
+			} else {
+				out[i] = []float32{0, 1}
+			}
+		}
+		return out, nil
+	}
+	_, index, _ := buildTestIndex(t, rlmIndexOptions{ChunkTokens: defaultEmbedChunkTokens, Model: "embed-small", Embed: embed})
+	if !index.hasEmbeddings() {
+		t.Fatal("expected embedded chunks")
+	}
+	results, err := index.search(context.Background(), "money", 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "guides/billing.md" {
+		t.Fatalf("results = %+v", results)
+	}
+}
+
+func TestRLMIndexMCPHandler(t *testing.T) {
+	_, index, _ := buildTestIndex(t, rlmIndexOptions{ChunkTokens: defaultEmbedChunkTokens})
+	handler := &rlmIndexMCPHandler{ctx: context.Background(), index: index, token: "runner-capability"}
+	call := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/index/mcp", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := call("wrong", `{"jsonrpc":"2.0","id":1,"method":"ping"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token status = %d", rec.Code)
+	}
+	if rec := call("runner-capability", `{"jsonrpc":"2.0","method":"notifications/initialized"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("notification status = %d", rec.Code)
+	}
+	rec := call("runner-capability", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if !strings.Contains(rec.Body.String(), `"name":"search"`) || !strings.Contains(rec.Body.String(), `"name":"read"`) {
+		t.Fatalf("tools/list = %s", rec.Body.String())
+	}
+
+	rec = call("runner-capability", `{"jsonrpc":"2.0","id":"2","method":"tools/call","params":{"name":"search","arguments":{"query":"invoices","k":1}}}`)
+	var response struct {
+		ID     string `json:"id"`
+		Result struct {
+			IsError           bool `json:"isError"`
+			StructuredContent struct {
+				Results []rlmIndexResult `json:"results"`
+			} `json:"structuredContent"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.ID != "2" || response.Result.IsError || len(response.Result.StructuredContent.Results) != 1 || response.Result.StructuredContent.Results[0].Path != "guides/billing.md" {
+		t.Fatalf("tools/call = %s", rec.Body.String())
+	}
+
+	rec = call("runner-capability", `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"read","arguments":{"path":"missing.md"}}}`)
+	if !strings.Contains(rec.Body.String(), `"isError":true`) {
+		t.Fatalf("expected a tool error, got %s", rec.Body.String())
+	}
+}
+
+func TestRLMIndexDataSourceIsValidMCPConfig(t *testing.T) {
+	source, err := rlmIndexDataSource("docs", "http://127.0.0.1:4000/index/mcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.Type != "mcp_http" || source.Name != "docs" {
+		t.Fatalf("source = %+v", source)
+	}
+	_, tenant, refs, err := validateLocalMCPConfig(source.MCPConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant != rlmIndexTenant || len(refs) != 1 || refs[0] != rlmIndexTokenRef {
+		t.Fatalf("tenant=%q refs=%v", tenant, refs)
+	}
+}
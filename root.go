@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -17,6 +19,17 @@ func newRootCmd() *cobra.Command {
 	var attachments []string
 	var attachmentType string
 	var attachStdin bool
+	var session string
+	var schemaPath string
+	var schemaRetries int
+	var maxCost float64
+	var fallbacks []string
+	var maxContextTokens int64
+	var extract bool
+	var fromClipboard bool
+	var toClipboard bool
+	var raw bool
+	var extractCode bool
 
 	root := &cobra.Command{
 		Use:   "mrl [prompt]",
@@ -27,26 +40,82 @@ Examples:
   mrl "What is 2 + 2?"
   mrl "Write a haiku" --stream
   mrl "Explain recursion" --model gpt-5.2 --usage
+  mrl "Summarize this" --model gpt-5.2 --fallback-model claude-sonnet-5
+  mrl --session refactor "next step?"
+  mrl "Extract the invoice fields" -a invoice.pdf --schema invoice.schema.json
+  mrl "Write a bash script that rotates logs" --extract-code > rotate.sh
+  mrl --editor
+  mrl --from-clipboard "Explain this stack trace" --to-clipboard
+  mrl chat
+  git diff | mrl run review --var focus=security
   mrl config set --model claude-sonnet-5`,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		Args:          cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 0 {
+			if len(args) == 0 && !fromClipboard && !editorRequested(cmd) {
 				return cmd.Help()
 			}
-			return runPrompt(cmd, args, model, system, attachments, attachmentType, attachStdin, stream, usage)
+			prompt := strings.Join(args, " ")
+			if fromClipboard {
+				clip, err := readClipboard(cmd.Context())
+				if err != nil {
+					return err
+				}
+				if strings.TrimSpace(clip) == "" {
+					return errors.New("clipboard is empty")
+				}
+				prompt = strings.TrimSpace(clip + "\n\n" + prompt)
+			}
+			if editorRequested(cmd) {
+				composed, err := composeInEditor(cmd.Context(), prompt)
+				if err != nil {
+					return err
+				}
+				prompt = composed
+			}
+			return runPrompt(cmd, prompt, promptOptions{
+				model:            model,
+				system:           system,
+				attachments:      attachments,
+				attachmentType:   attachmentType,
+				attachStdin:      attachStdin,
+				stream:           stream,
+				showUsage:        usage,
+				session:          session,
+				schemaPath:       schemaPath,
+				schemaRetries:    schemaRetries,
+				maxCost:          maxCost,
+				fallbacks:        fallbacks,
+				maxContextTokens: maxContextTokens,
+				extract:          extract,
+				toClipboard:      toClipboard,
+				raw:              raw,
+				extractCode:      extractCode,
+			})
 		},
 	}
 
 	// Prompt flags (on root command)
 	root.Flags().StringVar(&model, "model", "", "Model ID (overrides profile default)")
+	root.Flags().StringSliceVar(&fallbacks, "fallback-model", nil, "Models to try in order when the model fails (comma-separated)")
 	root.Flags().StringVar(&system, "system", "", "System prompt")
 	root.Flags().BoolVar(&stream, "stream", false, "Stream output as it's generated")
 	root.Flags().BoolVar(&usage, "usage", false, "Show token usage after response")
-	root.Flags().StringArrayVarP(&attachments, "attachment", "a", nil, "Attach a local file (repeatable; use '-' for stdin)")
+	root.Flags().BoolVar(&raw, "raw", false, "Print the response as is instead of rendering markdown")
+	root.Flags().BoolVar(&extractCode, "extract-code", false, "Print only the fenced code blocks of the response")
+	root.Flags().StringArrayVarP(&attachments, "attachment", "a", nil, "Attach a file, directory or glob (repeatable; use '-' for stdin)")
 	root.Flags().StringVar(&attachmentType, "attachment-type", "", "Override attachment MIME type (useful for stdin)")
 	root.Flags().BoolVar(&attachStdin, "attach-stdin", false, "Attach stdin as a file (requires piping data)")
+	root.Flags().BoolVar(&extract, "extract", false, "Send text extracted from PDF, DOCX, XLSX, CSV and HTML attachments instead of the files")
+	root.Flags().Int64Var(&maxContextTokens, "max-context-tokens", 0, "Token budget for text packed from directory and glob attachments (0 = no limit)")
+	root.Flags().StringVar(&schemaPath, "schema", "", "JSON Schema file the response must match (use '-' for stdin)")
+	root.Flags().IntVar(&schemaRetries, "schema-retries", 0, "Re-prompt up to N times when the response fails schema validation")
+	root.Flags().Float64Var(&maxCost, "max-cost", 0, "Refuse prompts whose estimated cost exceeds this many cents")
+	root.Flags().StringVar(&session, "session", "", "Continue (or start) a named conversation saved on disk")
+	addEditorFlag(root)
+	root.Flags().BoolVar(&fromClipboard, "from-clipboard", false, "Prepend the clipboard text to the prompt (wl-paste, xclip, xsel or pbpaste)")
+	root.Flags().BoolVar(&toClipboard, "to-clipboard", false, "Copy the response to the clipboard (wl-copy, xclip, xsel or pbcopy)")
 
 	// Global flags
 	root.PersistentFlags().String("profile", "", "Config profile")
@@ -56,6 +125,10 @@ Examples:
 	root.PersistentFlags().String("token", "", "Account bearer token (from 'mrl auth login')")
 	root.PersistentFlags().Bool("json", false, "Output JSON")
 	root.PersistentFlags().Duration("timeout", 30*time.Second, "Request timeout")
+	root.PersistentFlags().Int("max-retries", defaultAPIMaxRetries, "Retries for rate-limited or failed API calls")
+	root.PersistentFlags().Bool("allow-url-attachments", false, "Allow http(s) URLs as attachments (downloaded and cached locally)")
+	root.PersistentFlags().String("record", "", "Record every HTTP exchange to a cassette directory")
+	root.PersistentFlags().String("replay", "", "Replay HTTP exchanges from a cassette directory instead of the network")
 
 	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		cfgFile, err := loadCLIConfig()
@@ -66,18 +139,32 @@ Examples:
 		if err != nil {
 			return err
 		}
+		if err := setupCassette(cmd, &runtime); err != nil {
+			return err
+		}
+		installOutcomeTransport()
 		cmd.SetContext(withRuntimeConfig(cmd.Context(), runtime))
 		return nil
 	}
 
 	root.AddCommand(
 		newConfigCmd(),
+		newChatCmd(),
+		newSessionCmd(),
+		newBatchCmd(),
+		newCompareCmd(),
+		newTemplateCmd(),
+		newRunTemplateCmd(),
 		newAuthCmd(),
 		newCustomerCmd(),
 		newUsageCmd(),
 		newTierCmd(),
 		newAgentCmd(),
 		newModelCmd(),
+		newImageCmd(),
+		newEmbedCmd(),
+		newTranscribeCmd(),
+		newSpeakCmd(),
 		newResponseCmd(),
 		newSchemaCmd(),
 		newVersionCmd(),
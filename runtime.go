@@ -25,11 +25,21 @@ type runtimeConfig struct {
 	APIKey    string
 	Token     string
 	Model     string
+	// Fallbacks are tried in order when Model fails (see callWithFallback).
+	Fallbacks []string
 	Output    outputFormat
 	Timeout   time.Duration
-	AllowAll  bool
-	Allow     []string
-	Trace     bool
+	// MaxRetries bounds retries of control-plane calls (see doAPIRequest).
+	MaxRetries int
+	AllowAll   bool
+	Allow      []string
+	Trace      bool
+	// Approve is the profile's approve setting ("ask" or empty).
+	Approve string
+	// AllowURLAttachments enables downloading http(s) attachments.
+	AllowURLAttachments bool
+	// IgnoreDirs are skipped when expanding directory and glob attachments.
+	IgnoreDirs []string
 }
 
 type runtimeConfigKey struct{}
@@ -61,6 +71,8 @@ func resolveRuntimeConfig(cmd *cobra.Command, cfgFile cliConfig) (runtimeConfig,
 	tokenFlag, _ := cmd.Flags().GetString("token")
 	jsonFlag, _ := cmd.Flags().GetBool("json")
 	timeoutFlag, _ := cmd.Flags().GetDuration("timeout")
+	maxRetriesFlag, _ := cmd.Flags().GetInt("max-retries")
+	allowURLsFlag, _ := cmd.Flags().GetBool("allow-url-attachments")
 
 	baseURL := firstNonEmpty(baseFlag, os.Getenv("MODELRELAY_API_BASE_URL"), profile.BaseURL, defaultAPIBaseURL)
 	if strings.TrimSpace(baseURL) == "" {
@@ -82,18 +94,27 @@ func resolveRuntimeConfig(cmd *cobra.Command, cfgFile cliConfig) (runtimeConfig,
 		timeout = timeoutFlag
 	}
 
+	if maxRetriesFlag < 0 {
+		return runtimeConfig{}, errors.New("max-retries must be >= 0")
+	}
+
 	return runtimeConfig{
-		Profile:   profileName,
-		BaseURL:   strings.TrimSpace(baseURL),
-		ProjectID: strings.TrimSpace(projectID),
-		APIKey:    strings.TrimSpace(apiKey),
-		Token:     strings.TrimSpace(token),
-		Model:     strings.TrimSpace(model),
-		Output:    output,
-		Timeout:   timeout,
-		AllowAll:  profile.AllowAll,
-		Allow:     profile.Allow,
-		Trace:     profile.Trace,
+		Profile:             profileName,
+		BaseURL:             strings.TrimSpace(baseURL),
+		ProjectID:           strings.TrimSpace(projectID),
+		APIKey:              strings.TrimSpace(apiKey),
+		Token:               strings.TrimSpace(token),
+		Model:               strings.TrimSpace(model),
+		Fallbacks:           profile.Fallbacks,
+		Output:              output,
+		Timeout:             timeout,
+		MaxRetries:          maxRetriesFlag,
+		AllowAll:            profile.AllowAll,
+		Allow:               profile.Allow,
+		Trace:               profile.Trace,
+		Approve:             strings.ToLower(strings.TrimSpace(profile.Approve)),
+		AllowURLAttachments: allowURLsFlag || profile.AllowURLAttachments,
+		IgnoreDirs:          profile.IgnoreDirs,
 	}, nil
 }
 
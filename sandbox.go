@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+	"github.com/spf13/cobra"
+)
+
+// sandboxEnvKeys are the host variables a sandboxed command sees. Everything
+// else, API keys included, stays outside.
+var sandboxEnvKeys = []string{"PATH", "HOME", "USER", "LOGNAME", "LANG", "LC_ALL", "TERM", "TZ"}
+
+// sandboxOptions are the --sandbox flags of do and agent loop.
+type sandboxOptions struct {
+	enabled bool
+	network bool
+	// memory is an address-space limit such as 512M or 2G.
+	memory  string
+	cpuTime time.Duration
+	// keep leaves the copy-on-write layer on disk after the run.
+	keep bool
+}
+
+func addSandboxFlags(cmd *cobra.Command, opts *sandboxOptions) {
+	cmd.Flags().BoolVar(&opts.enabled, "sandbox", false, "Run bash and custom tools in a bubblewrap jail with a copy-on-write tool root")
+	cmd.Flags().BoolVar(&opts.network, "sandbox-network", false, "Allow network access inside the sandbox")
+	cmd.Flags().StringVar(&opts.memory, "sandbox-memory", "", "Memory limit per sandboxed command (e.g. 512M, 2G)")
+	cmd.Flags().DurationVar(&opts.cpuTime, "sandbox-cpu-time", 0, "CPU time limit per sandboxed command (e.g. 2m)")
+	cmd.Flags().BoolVar(&opts.keep, "sandbox-keep", false, "Keep the sandbox's file changes on disk instead of discarding them")
+}
+
+// toolSandbox runs commands under bubblewrap. The host filesystem is mounted
+// read-only, the tool root is an overlay whose writes land in dir/upper, and
+// /tmp, /run and the home directory are empty tmpfs mounts, which hides
+// sockets such as the Docker daemon's. Every namespace is unshared, the
+// network too unless allowed.
+type toolSandbox struct {
+	bwrap string
+	root  string
+	dir   string
+	home  string
+	opts  sandboxOptions
+	// memoryKB and cpuSeconds become ulimits inside the jail.
+	memoryKB   uint64
+	cpuSeconds uint64
+}
+
+// newToolSandbox prepares a sandbox over root and starts one empty command
+// in it, so a missing bwrap or disabled user namespaces fail the run up
+// front rather than every tool call.
+func newToolSandbox(ctx context.Context, root string, opts sandboxOptions) (*toolSandbox, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("--sandbox is only supported on Linux")
+	}
+	bwrap, err := exec.LookPath("bwrap")
+	if err != nil {
+		return nil, errors.New("--sandbox needs bubblewrap (bwrap) on PATH")
+	}
+	box, err := newSandboxLayout(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	box.bwrap = bwrap
+	if err := box.probe(ctx); err != nil {
+		_ = os.RemoveAll(box.dir)
+		return nil, err
+	}
+	return box, nil
+}
+
+// newSandboxLayout resolves the limits and creates the overlay directories.
+func newSandboxLayout(root string, opts sandboxOptions) (*toolSandbox, error) {
+	absRoot, err := filepath.Abs(firstNonEmpty(strings.TrimSpace(root), "."))
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(absRoot); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("tool root %s is not a directory", absRoot)
+	}
+	box := &toolSandbox{root: absRoot, opts: opts}
+	if strings.TrimSpace(opts.memory) != "" {
+		size, err := parseMemorySize(opts.memory)
+		if err != nil {
+			return nil, err
+		}
+		box.memoryKB = max(size/1024, 1)
+	}
+	if opts.cpuTime < 0 {
+		return nil, errors.New("--sandbox-cpu-time must not be negative")
+	}
+	if opts.cpuTime > 0 {
+		box.cpuSeconds = uint64(max(opts.cpuTime.Round(time.Second), time.Second) / time.Second)
+	}
+	if home, err := os.UserHomeDir(); err == nil && home != "/" {
+		box.home = home
+	}
+	box.dir, err = os.MkdirTemp("", "mrl-sandbox-")
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range []string{"upper", "work"} {
+		if err := os.Mkdir(filepath.Join(box.dir, sub), 0o700); err != nil {
+			_ = os.RemoveAll(box.dir)
+			return nil, err
+		}
+	}
+	return box, nil
+}
+
+func (s *toolSandbox) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := s.command(ctx, s.root, []string{"true"}, nil)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("bubblewrap cannot start a sandbox here (it needs unprivileged user namespaces and bwrap 0.7 or newer): %s",
+			firstNonEmpty(strings.TrimSpace(stderr.String()), err.Error()))
+	}
+	return nil
+}
+
+// command returns argv run inside the sandbox from workDir, with only the
+// sandbox environment plus extra.
+func (s *toolSandbox) command(ctx context.Context, workDir string, argv []string, extra map[string]string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, s.bwrap, s.args(workDir, argv)...) //nolint:gosec // runs the configured tool command inside the jail
+	cmd.Env = sandboxEnv(extra)
+	// Killing bwrap takes the whole pid namespace down; don't wait on pipes
+	// a stray process might still hold.
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+func (s *toolSandbox) args(workDir string, argv []string) []string {
+	args := []string{
+		"--unshare-all", "--die-with-parent", "--new-session",
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+		"--tmpfs", "/run",
+	}
+	if s.home != "" {
+		args = append(args, "--tmpfs", s.home)
+	}
+	if s.opts.network {
+		args = append(args, "--share-net")
+	}
+	args = append(args,
+		"--overlay-src", s.root,
+		"--overlay", filepath.Join(s.dir, "upper"), filepath.Join(s.dir, "work"), s.root,
+		"--chdir", firstNonEmpty(workDir, s.root),
+		"--",
+	)
+	var limits []string
+	if s.memoryKB > 0 {
+		limits = append(limits, "ulimit -v "+strconv.FormatUint(s.memoryKB, 10))
+	}
+	if s.cpuSeconds > 0 {
+		limits = append(limits, "ulimit -t "+strconv.FormatUint(s.cpuSeconds, 10))
+	}
+	if len(limits) > 0 {
+		args = append(args, "/bin/sh", "-c", strings.Join(limits, " && ")+` && exec "$@"`, "sh")
+	}
+	return append(args, argv...)
+}
+
+// changedPaths lists the files the sandbox wrote or deleted under the tool
+// root, relative to it.
+func (s *toolSandbox) changedPaths() ([]string, error) {
+	upper := filepath.Join(s.dir, "upper")
+	var paths []string
+	err := filepath.WalkDir(upper, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(upper, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	return paths, err
+}
+
+// close reports what the run changed and, unless --sandbox-keep is set,
+// discards it. The host tool root is never modified.
+func (s *toolSandbox) close(w io.Writer) error {
+	if s == nil {
+		return nil
+	}
+	paths, err := s.changedPaths()
+	if err != nil {
+		return err
+	}
+	if s.opts.keep {
+		_, _ = fmt.Fprintf(w, "sandbox: %d changed files under %s kept in %s\n", len(paths), s.root, filepath.Join(s.dir, "upper"))
+		return nil
+	}
+	if len(paths) > 0 {
+		_, _ = fmt.Fprintf(w, "sandbox: discarded %d changed files under %s (use --sandbox-keep to keep them)\n", len(paths), s.root)
+	}
+	return os.RemoveAll(s.dir)
+}
+
+func sandboxEnv(extra map[string]string) []string {
+	var env []string
+	for _, key := range sandboxEnvKeys {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	for key, value := range extra {
+		if strings.TrimSpace(key) != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// parseMemorySize reads sizes such as 2G, 512M, 64k or a plain byte count.
+func parseMemorySize(raw string) (uint64, error) {
+	value := strings.ToUpper(strings.TrimSpace(raw))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "B"), "I")
+	shift := 0
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'K':
+			shift = 10
+		case 'M':
+			shift = 20
+		case 'G':
+			shift = 30
+		case 'T':
+			shift = 40
+		}
+	}
+	if shift > 0 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	if err != nil || n == 0 || n > (1<<63)>>shift {
+		return 0, fmt.Errorf("invalid memory size %q (use e.g. 512M or 2G)", raw)
+	}
+	return n << shift, nil
+}
+
+// sandboxBash is the bash tool handler under --sandbox. Allow rules cover a
+// command as they do under --approve: prefix rules never cover commands with
+// shell control operators. A command matching a deny rule is refused.
+type sandboxBash struct {
+	box       *toolSandbox
+	allowAll  bool
+	allow     []sdk.BashCommandRule
+	deny      []sdk.BashCommandRule
+	timeout   time.Duration
+	maxOutput int
+}
+
+// registry returns a registry holding only this handler, for use as the
+// runner of --approve.
+func (b *sandboxBash) registry() *sdk.ToolRegistry {
+	registry := sdk.NewToolRegistry()
+	registry.Register(sdk.ToolNameBash, b.handle)
+	return registry
+}
+
+func (b *sandboxBash) handle(args map[string]any, _ llm.ToolCall) (any, error) {
+	command, _ := args["command"].(string)
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil, errors.New("command is required")
+	}
+	if bashRulesMatch(b.deny, command) {
+		return nil, fmt.Errorf("command %q matches a deny rule", command)
+	}
+	if !b.allowAll && !bashCommandCovered(b.allow, command) {
+		return nil, fmt.Errorf("command %q is not allowed", command)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+	output := newLimitedBuffer(b.maxOutput, cancel)
+	cmd := b.box.command(ctx, b.box.root, []string{"bash", "-c", command}, nil)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	runErr := cmd.Run()
+	result := sdk.BashResult{Output: output.String()}
+	switch {
+	case output.Truncated():
+		result.Error = fmt.Sprintf("output exceeded %d bytes; the command was stopped", b.maxOutput)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Error = fmt.Sprintf("command timed out after %s", b.timeout)
+	case runErr != nil:
+		result.Error = runErr.Error()
+	}
+	return result, nil
+}
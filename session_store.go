@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+// storedNamePattern restricts names of files mrl keeps on disk (sessions,
+// templates) so they cannot escape their directory.
+var storedNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// promptSession is a named conversation persisted between invocations. Items
+// holds the full input history exactly as sent to the model; Turns keeps the
+// plain text and usage of each exchange for listing and export.
+type promptSession struct {
+	Name      string          `json:"name"`
+	Model     string          `json:"model,omitempty"`
+	System    string          `json:"system,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Items     []llm.InputItem `json:"items"`
+	Turns     []sessionTurn   `json:"turns,omitempty"`
+}
+
+type sessionTurn struct {
+	At        time.Time    `json:"at"`
+	Model     string       `json:"model,omitempty"`
+	Prompt    string       `json:"prompt,omitempty"`
+	Response  string       `json:"response,omitempty"`
+	Usage     sessionUsage `json:"usage"`
+	LatencyMS int64        `json:"latency_ms"`
+}
+
+type sessionUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	TotalTokens  int64 `json:"total_tokens"`
+}
+
+func sessionsDir() (string, error) {
+	dir, err := defaultDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions"), nil
+}
+
+func sessionPath(name string) (string, error) {
+	if !storedNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid session name %q (use letters, digits, '.', '_' or '-')", name)
+	}
+	dir, err := sessionsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// loadPromptSession reads a stored session. The boolean is false when no
+// session with that name exists yet.
+func loadPromptSession(name string) (*promptSession, bool, error) {
+	path, err := sessionPath(name)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path is derived from a validated session name under the mrl data dir
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	var session promptSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, false, fmt.Errorf("session %s is corrupt: %w", name, err)
+	}
+	session.Name = name
+	return &session, true, nil
+}
+
+// openPromptSession loads the named session or starts a new, unsaved one.
+func openPromptSession(name string) (*promptSession, error) {
+	name = strings.TrimSpace(name)
+	session, found, err := loadPromptSession(name)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return session, nil
+	}
+	now := time.Now().UTC()
+	return &promptSession{Name: name, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+func savePromptSession(session *promptSession) error {
+	if session == nil {
+		return errors.New("session is nil")
+	}
+	path, err := sessionPath(session.Name)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+session.Name+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func removePromptSession(name string) error {
+	path, err := sessionPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("session %s not found", name)
+		}
+		return err
+	}
+	return nil
+}
+
+func listPromptSessions() ([]promptSession, error) {
+	dir, err := sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var sessions []promptSession
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok || !storedNamePattern.MatchString(name) {
+			continue
+		}
+		session, found, err := loadPromptSession(name)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			sessions = append(sessions, *session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions, nil
+}
+
+// recordTurn appends a completed exchange to the session. The user item and
+// the assistant reply both become part of the history sent on the next turn.
+func (s *promptSession) recordTurn(userItem llm.InputItem, prompt string, result promptResult) {
+	now := time.Now().UTC()
+	s.Items = append(s.Items, userItem, llm.NewAssistantText(result.Text))
+	s.Turns = append(s.Turns, sessionTurn{
+		At:       now,
+		Model:    result.Model.String(),
+		Prompt:   prompt,
+		Response: result.Text,
+		Usage: sessionUsage{
+			InputTokens:  result.Usage.InputTokens,
+			OutputTokens: result.Usage.OutputTokens,
+			TotalTokens:  result.Usage.TotalTokens,
+		},
+		LatencyMS: result.Latency.Milliseconds(),
+	})
+	s.UpdatedAt = now
+}
+
+func (s *promptSession) totalUsage() sessionUsage {
+	var total sessionUsage
+	for _, turn := range s.Turns {
+		total.InputTokens += turn.Usage.InputTokens
+		total.OutputTokens += turn.Usage.OutputTokens
+		total.TotalTokens += turn.Usage.TotalTokens
+	}
+	return total
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+func TestPromptSession_SaveLoadRoundTrip(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	session, err := openPromptSession("refactor")
+	if err != nil {
+		t.Fatalf("openPromptSession: %v", err)
+	}
+	session.Model = "demo"
+	session.System = "be brief"
+	session.recordTurn(llm.NewUserText("first"), "first", promptResult{
+		Text:    "answer",
+		Model:   sdk.NewModelID("demo"),
+		Usage:   sdk.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15},
+		Latency: 250 * time.Millisecond,
+	})
+	if err := savePromptSession(session); err != nil {
+		t.Fatalf("savePromptSession: %v", err)
+	}
+
+	loaded, found, err := loadPromptSession("refactor")
+	if err != nil || !found {
+		t.Fatalf("loadPromptSession: found=%v err=%v", found, err)
+	}
+	if loaded.Model != "demo" || loaded.System != "be brief" {
+		t.Fatalf("unexpected session settings: %+v", loaded)
+	}
+	if len(loaded.Items) != 2 || loaded.Items[1].Role != llm.RoleAssistant {
+		t.Fatalf("expected user and assistant items, got %+v", loaded.Items)
+	}
+	if len(loaded.Turns) != 1 || loaded.Turns[0].LatencyMS != 250 || loaded.Turns[0].Response != "answer" {
+		t.Fatalf("unexpected turns: %+v", loaded.Turns)
+	}
+	if total := loaded.totalUsage(); total.TotalTokens != 15 {
+		t.Fatalf("expected 15 total tokens, got %d", total.TotalTokens)
+	}
+
+	sessions, err := listPromptSessions()
+	if err != nil {
+		t.Fatalf("listPromptSessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Name != "refactor" {
+		t.Fatalf("unexpected session list: %+v", sessions)
+	}
+
+	if err := removePromptSession("refactor"); err != nil {
+		t.Fatalf("removePromptSession: %v", err)
+	}
+	if _, found, _ := loadPromptSession("refactor"); found {
+		t.Fatal("expected session to be removed")
+	}
+}
+
+func TestSessionPath_RejectsTraversal(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	for _, name := range []string{"", "../escape", ".hidden", "a/b"} {
+		if _, err := sessionPath(name); err == nil {
+			t.Fatalf("expected error for session name %q", name)
+		}
+	}
+}
+
+func TestMarshalSessionExport_IncludesSystem(t *testing.T) {
+	session := &promptSession{
+		Name:   "demo",
+		System: "be brief",
+		Items:  []llm.InputItem{llm.NewUserText("hi"), llm.NewAssistantText("hello")},
+	}
+	data, err := marshalSessionExport(session)
+	if err != nil {
+		t.Fatalf("marshalSessionExport: %v", err)
+	}
+	var payload struct {
+		Input []llm.InputItem `json:"input"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if len(payload.Input) != 3 || payload.Input[0].Role != llm.RoleSystem {
+		t.Fatalf("expected system + 2 items, got %+v", payload.Input)
+	}
+}
+
+func TestRenderSessionMarkdown(t *testing.T) {
+	session := &promptSession{
+		Name:  "demo",
+		Turns: []sessionTurn{{Prompt: "question", Response: "answer"}},
+	}
+	out := renderSessionMarkdown(session)
+	if !strings.Contains(out, "## User\n\nquestion") || !strings.Contains(out, "## Assistant\n\nanswer") {
+		t.Fatalf("unexpected markdown: %s", out)
+	}
+}
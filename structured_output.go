@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	schema "github.com/modelrelay/modelrelay/providers/schema"
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const structuredSchemaResource = "mrl://schema.json"
+
+var structuredSchemaNameSanitizer = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// structuredOutput is a JSON Schema the model's final answer must satisfy. The
+// normalized schema is sent as the response format and the reply is checked
+// locally, since not every provider enforces the schema strictly.
+type structuredOutput struct {
+	name      string
+	schema    json.RawMessage
+	validator *jsonschema.Schema
+	retries   int
+}
+
+func loadStructuredOutput(path string, retries int) (*structuredOutput, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, nil //nolint:nilnil // nil means structured output is disabled
+	}
+	if retries < 0 {
+		return nil, errors.New("schema-retries must be >= 0")
+	}
+	raw, err := readSchemaInput(path)
+	if err != nil {
+		return nil, err
+	}
+	return newStructuredOutput(structuredSchemaName(path), path, raw, retries)
+}
+
+// newStructuredOutput normalizes and compiles a JSON Schema; label names it
+// in errors.
+func newStructuredOutput(name, label string, raw json.RawMessage, retries int) (*structuredOutput, error) {
+	normalized, err := schema.NormalizeJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", label, err)
+	}
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("encode schema %s: %w", label, err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(structuredSchemaResource, bytes.NewReader(encoded)); err != nil {
+		return nil, fmt.Errorf("load schema %s: %w", label, err)
+	}
+	validator, err := compiler.Compile(structuredSchemaResource)
+	if err != nil {
+		return nil, fmt.Errorf("compile schema %s: %w", label, err)
+	}
+
+	return &structuredOutput{
+		name:      name,
+		schema:    encoded,
+		validator: validator,
+		retries:   retries,
+	}, nil
+}
+
+func structuredSchemaName(path string) string {
+	base := "response"
+	if path != "-" {
+		base = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	name := strings.Trim(structuredSchemaNameSanitizer.ReplaceAllString(base, "_"), "_")
+	if name == "" {
+		return "response"
+	}
+	return name
+}
+
+func (s *structuredOutput) format() llm.OutputFormat {
+	strict := true
+	return llm.OutputFormat{
+		Type: llm.OutputFormatTypeJSONSchema,
+		JSONSchema: &llm.JSONSchemaFormat{
+			Name:   s.name,
+			Schema: s.schema,
+			Strict: &strict,
+		},
+	}
+}
+
+// validate extracts the JSON document from a reply and checks it against the
+// schema, returning the compact JSON on success.
+func (s *structuredOutput) validate(text string) (json.RawMessage, error) {
+	trimmed := stripJSONCodeFence(text)
+	if trimmed == "" {
+		return nil, errors.New("response is empty")
+	}
+	var value any
+	if err := json.Unmarshal([]byte(trimmed), &value); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	if err := s.validator.Validate(value); err != nil {
+		return nil, fmt.Errorf("response does not match schema: %w", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(trimmed)); err != nil {
+		return nil, err
+	}
+	return compact.Bytes(), nil
+}
+
+// retryMessage asks the model to correct a reply that failed validation.
+func (s *structuredOutput) retryMessage(validationErr error) llm.InputItem {
+	return llm.NewUserText(fmt.Sprintf(
+		"Your previous response was rejected: %v\nReply again with only a JSON document that matches this JSON Schema, with no surrounding text:\n%s",
+		validationErr, s.schema,
+	))
+}
+
+// stripJSONCodeFence removes a surrounding ```json fence that some models add
+// even when asked for bare JSON.
+func stripJSONCodeFence(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") {
+		return trimmed
+	}
+	body := strings.TrimPrefix(trimmed, "```")
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 {
+		body = body[newline+1:]
+	} else {
+		return trimmed
+	}
+	body = strings.TrimSpace(body)
+	body = strings.TrimSuffix(body, "```")
+	return strings.TrimSpace(body)
+}
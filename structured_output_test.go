@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+func writeTestSchema(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "invoice.schema.json")
+	raw := `{
+		"type": "object",
+		"properties": {"total": {"type": "number"}, "currency": {"type": "string"}},
+		"required": ["total", "currency"],
+		"additionalProperties": false
+	}`
+	if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+		t.Fatalf("write schema: %v", err)
+	}
+	return path
+}
+
+func TestLoadStructuredOutput_Validate(t *testing.T) {
+	structured, err := loadStructuredOutput(writeTestSchema(t), 1)
+	if err != nil {
+		t.Fatalf("loadStructuredOutput: %v", err)
+	}
+	if structured.name != "invoice_schema" {
+		t.Fatalf("unexpected schema name %q", structured.name)
+	}
+	format := structured.format()
+	if format.JSONSchema == nil || format.JSONSchema.Name != "invoice_schema" {
+		t.Fatalf("unexpected output format: %+v", format)
+	}
+
+	got, err := structured.validate("```json\n{ \"total\": 12.5, \"currency\": \"EUR\" }\n```")
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if string(got) != `{"total":12.5,"currency":"EUR"}` {
+		t.Fatalf("unexpected compact JSON: %s", got)
+	}
+
+	if _, err := structured.validate(`{"total": "twelve"}`); err == nil {
+		t.Fatal("expected schema validation error")
+	}
+	if _, err := structured.validate("not json"); err == nil {
+		t.Fatal("expected JSON parse error")
+	}
+}
+
+func TestLoadStructuredOutput_Disabled(t *testing.T) {
+	structured, err := loadStructuredOutput("", 0)
+	if err != nil || structured != nil {
+		t.Fatalf("expected nil structured output, got %v %v", structured, err)
+	}
+	if _, err := loadStructuredOutput(writeTestSchema(t), -1); err == nil {
+		t.Fatal("expected error for negative retries")
+	}
+}
+
+func TestStructuredOutputRetryMessage(t *testing.T) {
+	structured, err := loadStructuredOutput(writeTestSchema(t), 1)
+	if err != nil {
+		t.Fatalf("loadStructuredOutput: %v", err)
+	}
+	_, validationErr := structured.validate(`{}`)
+	item := structured.retryMessage(validationErr)
+	if item.Role != llm.RoleUser {
+		t.Fatalf("expected user retry message, got %s", item.Role)
+	}
+}
+
+func TestStripJSONCodeFence(t *testing.T) {
+	cases := map[string]string{
+		`{"a":1}`:                 `{"a":1}`,
+		"```json\n{\"a\":1}\n```": `{"a":1}`,
+		"```\n[1,2]\n```":         `[1,2]`,
+		"  \n{\"a\":1}\n ":        `{"a":1}`,
+	}
+	for input, want := range cases {
+		if got := stripJSONCodeFence(input); got != want {
+			t.Fatalf("stripJSONCodeFence(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if got := stripJSONCodeFence("```"); !strings.HasPrefix(got, "```") {
+		t.Fatalf("expected unterminated fence to be left alone, got %q", got)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const templateStdinPlaceholder = "{{stdin}}"
+
+var templatePlaceholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// promptTemplate is a reusable prompt stored as TOML next to the CLI config.
+// Prompt, system and attachment paths may contain {{var}} placeholders; Vars
+// holds default values. {{stdin}} in the prompt is filled from piped input.
+type promptTemplate struct {
+	Name           string            `toml:"-" json:"name"`
+	Description    string            `toml:"description,omitempty" json:"description,omitempty"`
+	Model          string            `toml:"model,omitempty" json:"model,omitempty"`
+	System         string            `toml:"system,omitempty" json:"system,omitempty"`
+	Prompt         string            `toml:"prompt,omitempty" json:"prompt,omitempty"`
+	Attachments    []string          `toml:"attachments,omitempty" json:"attachments,omitempty"`
+	AttachmentType string            `toml:"attachment_type,omitempty" json:"attachment_type,omitempty"`
+	Schema         string            `toml:"schema,omitempty" json:"schema,omitempty"`
+	SchemaRetries  int               `toml:"schema_retries,omitempty" json:"schema_retries,omitempty"`
+	Vars           map[string]string `toml:"vars,omitempty" json:"vars,omitempty"`
+}
+
+func templatesDir() (string, error) {
+	path, err := defaultConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "templates"), nil
+}
+
+func templatePath(name string) (string, error) {
+	if !storedNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid template name %q (use letters, digits, '.', '_' or '-')", name)
+	}
+	dir, err := templatesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".toml"), nil
+}
+
+func loadPromptTemplate(name string) (*promptTemplate, error) {
+	name = strings.TrimSpace(name)
+	path, err := templatePath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path is derived from a validated template name under the mrl config dir
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("template %s not found", name)
+		}
+		return nil, err
+	}
+	tmpl, err := decodePromptTemplate(data)
+	if err != nil {
+		return nil, fmt.Errorf("template %s is invalid: %w", name, err)
+	}
+	tmpl.Name = name
+	return tmpl, nil
+}
+
+func decodePromptTemplate(data []byte) (*promptTemplate, error) {
+	var tmpl promptTemplate
+	meta, err := toml.Decode(string(data), &tmpl)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("unknown key %q", undecoded[0].String())
+	}
+	if err := tmpl.validate(); err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+func savePromptTemplate(tmpl *promptTemplate, overwrite bool) error {
+	if err := tmpl.validate(); err != nil {
+		return err
+	}
+	path, err := templatePath(tmpl.Name)
+	if err != nil {
+		return err
+	}
+	if !overwrite {
+		if _, statErr := os.Stat(path); statErr == nil {
+			return fmt.Errorf("template %s already exists (use --force to replace it)", tmpl.Name)
+		}
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(tmpl); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+func removePromptTemplate(name string) error {
+	path, err := templatePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("template %s not found", name)
+		}
+		return err
+	}
+	return nil
+}
+
+func listPromptTemplates() ([]promptTemplate, error) {
+	dir, err := templatesDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var templates []promptTemplate
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".toml")
+		if entry.IsDir() || !ok || !storedNamePattern.MatchString(name) {
+			continue
+		}
+		tmpl, err := loadPromptTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *tmpl)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+func (t *promptTemplate) validate() error {
+	if strings.TrimSpace(t.Prompt) == "" && len(t.Attachments) == 0 {
+		return errors.New("template needs a prompt or attachments")
+	}
+	if t.SchemaRetries < 0 {
+		return errors.New("schema_retries must be >= 0")
+	}
+	if strings.Contains(normalizeTemplatePlaceholders(t.System), templateStdinPlaceholder) {
+		return errors.New("{{stdin}} is only supported in the prompt")
+	}
+	return nil
+}
+
+// variables lists the placeholder names used by the template, excluding stdin.
+func (t *promptTemplate) variables() []string {
+	seen := map[string]struct{}{}
+	sources := append([]string{t.Prompt, t.System}, t.Attachments...)
+	for _, source := range sources {
+		for _, match := range templatePlaceholderPattern.FindAllStringSubmatch(source, -1) {
+			if match[1] != "stdin" {
+				seen[match[1]] = struct{}{}
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// render substitutes vars (falling back to the template defaults) into the
+// prompt, system prompt and attachment paths. {{stdin}} is left in place for
+// runPrompt to fill. Every placeholder must have a value.
+func (t *promptTemplate) render(vars map[string]string) (*promptTemplate, error) {
+	values := make(map[string]string, len(t.Vars)+len(vars))
+	for key, value := range t.Vars {
+		values[key] = value
+	}
+	for key, value := range vars {
+		values[key] = value
+	}
+	var missing []string
+	for _, name := range t.variables() {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing template variables: %s (pass --var name=value)", strings.Join(missing, ", "))
+	}
+
+	replace := func(source string) string {
+		return templatePlaceholderPattern.ReplaceAllStringFunc(source, func(match string) string {
+			name := templatePlaceholderPattern.FindStringSubmatch(match)[1]
+			if name == "stdin" {
+				return templateStdinPlaceholder
+			}
+			return values[name]
+		})
+	}
+	out := *t
+	out.Prompt = replace(t.Prompt)
+	out.System = replace(t.System)
+	out.Attachments = make([]string, 0, len(t.Attachments))
+	for _, path := range t.Attachments {
+		out.Attachments = append(out.Attachments, replace(path))
+	}
+	return &out, nil
+}
+
+func (t *promptTemplate) usesStdin() bool {
+	return strings.Contains(normalizeTemplatePlaceholders(t.Prompt), templateStdinPlaceholder)
+}
+
+func normalizeTemplatePlaceholders(source string) string {
+	return templatePlaceholderPattern.ReplaceAllString(source, "{{$1}}")
+}
+
+// parseTemplateVars parses repeated key=value flags.
+func parseTemplateVars(raw []string) (map[string]string, error) {
+	vars := make(map[string]string, len(raw))
+	for _, entry := range raw {
+		key, value, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --var %q (expected key=value)", entry)
+		}
+		if key == "stdin" {
+			return nil, errors.New("stdin is filled from piped input and cannot be set with --var")
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromptTemplateRender(t *testing.T) {
+	tmpl := &promptTemplate{
+		Name:        "review",
+		System:      "You review {{ lang }} code.",
+		Prompt:      "Focus on {{focus}}.\n\n{{ stdin }}",
+		Attachments: []string{"{{dir}}/CONTRIBUTING.md"},
+		Vars:        map[string]string{"focus": "correctness", "lang": "Go"},
+	}
+	if got := strings.Join(tmpl.variables(), ","); got != "dir,focus,lang" {
+		t.Fatalf("unexpected variables %q", got)
+	}
+	if _, err := tmpl.render(nil); err == nil || !strings.Contains(err.Error(), "dir") {
+		t.Fatalf("expected missing dir error, got %v", err)
+	}
+
+	rendered, err := tmpl.render(map[string]string{"focus": "security", "dir": "docs"})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if rendered.Prompt != "Focus on security.\n\n{{stdin}}" {
+		t.Fatalf("unexpected prompt %q", rendered.Prompt)
+	}
+	if rendered.System != "You review Go code." {
+		t.Fatalf("unexpected system %q", rendered.System)
+	}
+	if rendered.Attachments[0] != "docs/CONTRIBUTING.md" {
+		t.Fatalf("unexpected attachment %q", rendered.Attachments[0])
+	}
+	if !rendered.usesStdin() {
+		t.Fatal("expected rendered template to use stdin")
+	}
+	if tmpl.Prompt != "Focus on {{focus}}.\n\n{{ stdin }}" {
+		t.Fatal("render must not modify the stored template")
+	}
+}
+
+func TestPromptTemplateStoreRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tmpl := &promptTemplate{
+		Name:          "summarize",
+		Description:   "Summarize a file",
+		Model:         "claude-sonnet-5",
+		Prompt:        "Summarize {{file}}",
+		Attachments:   []string{"{{file}}"},
+		SchemaRetries: 1,
+		Vars:          map[string]string{"file": "README.md"},
+	}
+	if err := savePromptTemplate(tmpl, false); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := savePromptTemplate(tmpl, false); err == nil {
+		t.Fatal("expected error when overwriting without force")
+	}
+	loaded, err := loadPromptTemplate("summarize")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.Name != "summarize" || loaded.Model != tmpl.Model || loaded.Vars["file"] != "README.md" || loaded.SchemaRetries != 1 {
+		t.Fatalf("unexpected loaded template: %+v", loaded)
+	}
+	templates, err := listPromptTemplates()
+	if err != nil || len(templates) != 1 {
+		t.Fatalf("list: %v, %v", templates, err)
+	}
+	if err := removePromptTemplate("summarize"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, err := loadPromptTemplate("summarize"); err == nil {
+		t.Fatal("expected not found after remove")
+	}
+	if _, err := templatePath("../escape"); err == nil {
+		t.Fatal("expected invalid name error")
+	}
+}
+
+func TestDecodePromptTemplateRejectsUnknownKeys(t *testing.T) {
+	if _, err := decodePromptTemplate([]byte("prompt = \"hi\"\nsytem = \"typo\"\n")); err == nil {
+		t.Fatal("expected unknown key error")
+	}
+	if _, err := decodePromptTemplate([]byte("system = \"{{stdin}}\"\nprompt = \"hi\"\n")); err == nil {
+		t.Fatal("expected stdin-in-system error")
+	}
+}
+
+func TestParseTemplateVars(t *testing.T) {
+	vars, err := parseTemplateVars([]string{"a=1", "b=x=y", "c="})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if vars["a"] != "1" || vars["b"] != "x=y" || vars["c"] != "" {
+		t.Fatalf("unexpected vars %v", vars)
+	}
+	for _, bad := range []string{"novalue", "=x", "stdin=x"} {
+		if _, err := parseTemplateVars([]string{bad}); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestMergeTemplateOptions(t *testing.T) {
+	tmpl := &promptTemplate{Model: "m1", System: "sys", Attachments: []string{"a.txt"}, Schema: "s.json", Prompt: "{{stdin}}"}
+	opts := mergeTemplateOptions(tmpl, promptOptions{model: "m2", attachments: []string{"b.txt"}})
+	if opts.model != "m2" || opts.system != "sys" || opts.schemaPath != "s.json" || !opts.stdinSlot {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+	if strings.Join(opts.attachments, ",") != "a.txt,b.txt" {
+		t.Fatalf("unexpected attachments %v", opts.attachments)
+	}
+}
@@ -30,6 +30,8 @@ type customExecTool struct {
 	timeout     time.Duration
 	env         map[string]string
 	maxOutput   int
+	// sandbox, when set, runs the command in the --sandbox jail.
+	sandbox *toolSandbox
 }
 
 type execToolResult struct {
@@ -41,7 +43,7 @@ type execToolResult struct {
 	Error           string `json:"error,omitempty"`
 }
 
-func registerCustomTools(registry *sdk.ToolRegistry, toolRoot string, manifest *toolManifest, seen map[sdk.ToolName]struct{}) ([]llm.Tool, error) {
+func registerCustomTools(registry *sdk.ToolRegistry, toolRoot string, manifest *toolManifest, seen map[sdk.ToolName]struct{}, box *toolSandbox) ([]llm.Tool, error) {
 	if manifest == nil || len(manifest.Custom) == 0 {
 		return nil, nil
 	}
@@ -54,6 +56,7 @@ func registerCustomTools(registry *sdk.ToolRegistry, toolRoot string, manifest *
 		if err != nil {
 			return nil, err
 		}
+		tool.sandbox = box
 		var errDef error
 		defs, errDef = appendToolDefs(defs, seen, def)
 		if errDef != nil {
@@ -183,9 +186,14 @@ func (t *customExecTool) handle(args map[string]any, _ llm.ToolCall) (any, error
 		return nil, fmt.Errorf("encode args: %w", err)
 	}
 
-	cmd := exec.CommandContext(ctx, t.command[0], t.command[1:]...) //nolint:gosec // tool execution is explicit and user-configured
-	cmd.Dir = t.workDir
-	cmd.Env = mergeEnv(t.env)
+	var cmd *exec.Cmd
+	if t.sandbox != nil {
+		cmd = t.sandbox.command(ctx, t.workDir, t.command, t.env)
+	} else {
+		cmd = exec.CommandContext(ctx, t.command[0], t.command[1:]...) //nolint:gosec // tool execution is explicit and user-configured
+		cmd.Dir = t.workDir
+		cmd.Env = mergeEnv(t.env)
+	}
 	cmd.Stdin = bytes.NewReader(input)
 	cmd.Stdout = stdoutBuf
 	cmd.Stderr = stderrBuf
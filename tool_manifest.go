@@ -21,6 +21,7 @@ type toolManifest struct {
 	Bash            *toolManifestBash    `json:"bash" toml:"bash"`
 	TasksWrite      *toolManifestTasks   `json:"tasks_write" toml:"tasks_write"`
 	FS              *toolManifestFS      `json:"fs" toml:"fs"`
+	Sandbox         *toolManifestSandbox `json:"sandbox" toml:"sandbox"`
 	Custom          []toolManifestCustom `json:"custom" toml:"custom"`
 
 	sourceDir string `json:"-" toml:"-"`
@@ -48,6 +49,14 @@ type toolManifestFS struct {
 	SearchTimeout    string   `json:"search_timeout" toml:"search_timeout"`
 }
 
+type toolManifestSandbox struct {
+	Enabled *bool  `json:"enabled" toml:"enabled"`
+	Network *bool  `json:"network" toml:"network"`
+	Memory  string `json:"memory" toml:"memory"`
+	CPUTime string `json:"cpu_time" toml:"cpu_time"`
+	Keep    *bool  `json:"keep" toml:"keep"`
+}
+
 type toolManifestCustom struct {
 	Name           string            `json:"name" toml:"name"`
 	Description    string            `json:"description" toml:"description"`
@@ -137,6 +146,28 @@ func applyToolManifest(flags *agentLoopFlags, manifest toolManifest, flagset *pf
 		}
 	}
 
+	if manifest.Sandbox != nil {
+		if !flagset.Changed("sandbox") && manifest.Sandbox.Enabled != nil {
+			flags.sandbox.enabled = *manifest.Sandbox.Enabled
+		}
+		if !flagset.Changed("sandbox-network") && manifest.Sandbox.Network != nil {
+			flags.sandbox.network = *manifest.Sandbox.Network
+		}
+		if !flagset.Changed("sandbox-memory") && strings.TrimSpace(manifest.Sandbox.Memory) != "" {
+			flags.sandbox.memory = strings.TrimSpace(manifest.Sandbox.Memory)
+		}
+		if !flagset.Changed("sandbox-cpu-time") && strings.TrimSpace(manifest.Sandbox.CPUTime) != "" {
+			dur, err := time.ParseDuration(strings.TrimSpace(manifest.Sandbox.CPUTime))
+			if err != nil {
+				return fmt.Errorf("invalid sandbox cpu_time %q: %w", manifest.Sandbox.CPUTime, err)
+			}
+			flags.sandbox.cpuTime = dur
+		}
+		if !flagset.Changed("sandbox-keep") && manifest.Sandbox.Keep != nil {
+			flags.sandbox.keep = *manifest.Sandbox.Keep
+		}
+	}
+
 	if manifest.TasksWrite != nil {
 		if !flagset.Changed("tasks-output") && strings.TrimSpace(manifest.TasksWrite.Output) != "" {
 			flags.tasksOutputPath = strings.TrimSpace(manifest.TasksWrite.Output)
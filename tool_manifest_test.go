@@ -115,6 +115,11 @@ func TestApplyToolManifest(t *testing.T) {
 		TasksWrite: &toolManifestTasks{
 			Output: "tasks.json",
 		},
+		Sandbox: &toolManifestSandbox{
+			Enabled: &allowAll,
+			Memory:  "2G",
+			CPUTime: "1m",
+		},
 	}
 
 	if err := applyToolManifest(flags, manifest, cmd.Flags()); err != nil {
@@ -136,6 +141,9 @@ func TestApplyToolManifest(t *testing.T) {
 	if flags.bashMaxOutBytes != maxBytes {
 		t.Fatalf("expected bash max output bytes")
 	}
+	if !flags.sandbox.enabled || flags.sandbox.memory != "2G" || flags.sandbox.cpuTime != time.Minute || flags.sandbox.network {
+		t.Fatalf("expected sandbox settings from manifest, got %+v", flags.sandbox)
+	}
 	if flags.tasksOutputPath != "tasks.json" {
 		t.Fatalf("expected tasks output path")
 	}
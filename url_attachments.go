@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// urlAttachmentMaxBytes caps a single downloaded attachment.
+	urlAttachmentMaxBytes = 50 << 20
+	urlAttachmentTimeout  = 60 * time.Second
+	// urlAttachmentCacheTTL bounds how long a URL is served from the local
+	// copy before it is downloaded again.
+	urlAttachmentCacheTTL  = 24 * time.Hour
+	urlAttachmentRedirects = 5
+)
+
+var errURLAttachmentsDisabled = errors.New("URL attachments are disabled; pass --allow-url-attachments or set allow_url_attachments in the profile")
+
+func isURLAttachment(path string) bool {
+	path = strings.TrimSpace(path)
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// fetchURLAttachments replaces http(s) attachment paths with downloaded local
+// copies, so the file-based attachment code handles them like any other path.
+// Other paths are returned unchanged.
+func fetchURLAttachments(ctx context.Context, cfg runtimeConfig, paths []string) ([]string, error) {
+	out := make([]string, 0, len(paths))
+	for _, raw := range paths {
+		if !isURLAttachment(raw) {
+			out = append(out, raw)
+			continue
+		}
+		if !cfg.AllowURLAttachments {
+			return nil, errURLAttachmentsDisabled
+		}
+		local, err := fetchURLAttachment(ctx, strings.TrimSpace(raw), time.Now())
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, local)
+	}
+	return out, nil
+}
+
+// urlAttachmentEntry records where the last download of a URL is stored.
+type urlAttachmentEntry struct {
+	SHA256    string    `json:"sha256"`
+	Name      string    `json:"name"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// urlAttachmentMu serializes index updates when batch rows download in parallel.
+var urlAttachmentMu sync.Mutex
+
+func urlAttachmentsDir() (string, error) {
+	dir, err := defaultDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "attachments"), nil
+}
+
+// fetchURLAttachment downloads rawURL into the content-addressed cache
+// (<data>/attachments/<sha256>/<name>) and returns the local path. The file
+// keeps the URL's file name so MIME detection and the attachment name work as
+// for local files.
+func fetchURLAttachment(ctx context.Context, rawURL string, now time.Time) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", fmt.Errorf("invalid attachment URL %q", rawURL)
+	}
+	dir, err := urlAttachmentsDir()
+	if err != nil {
+		return "", err
+	}
+	if entry, ok := lookupURLAttachment(dir, rawURL, now); ok {
+		return filepath.Join(dir, entry.SHA256, entry.Name), nil
+	}
+
+	data, contentType, err := downloadURLAttachment(ctx, parsed)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	entry := urlAttachmentEntry{
+		SHA256:    hex.EncodeToString(sum[:]),
+		Name:      urlAttachmentName(parsed, contentType),
+		FetchedAt: now.UTC(),
+	}
+	local := filepath.Join(dir, entry.SHA256, entry.Name)
+	if _, statErr := os.Stat(local); statErr != nil {
+		if err := writeFileAtomic(local, data); err != nil {
+			return "", fmt.Errorf("cache %s: %w", rawURL, err)
+		}
+	}
+	storeURLAttachment(dir, rawURL, entry)
+	return local, nil
+}
+
+func downloadURLAttachment(ctx context.Context, target *url.URL) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, urlAttachmentTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	client := &http.Client{
+		CheckRedirect: func(next *http.Request, via []*http.Request) error {
+			if len(via) >= urlAttachmentRedirects {
+				return errors.New("too many redirects")
+			}
+			if next.URL.Scheme != "http" && next.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", next.URL.Scheme)
+			}
+			return nil
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("download %s: %w", target.Redacted(), err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, "", fmt.Errorf("download %s: %s", target.Redacted(), resp.Status)
+	}
+	if resp.ContentLength > urlAttachmentMaxBytes {
+		return nil, "", fmt.Errorf("download %s: %d bytes exceeds the %d MB attachment limit", target.Redacted(), resp.ContentLength, urlAttachmentMaxBytes>>20)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, urlAttachmentMaxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("download %s: %w", target.Redacted(), err)
+	}
+	if len(data) > urlAttachmentMaxBytes {
+		return nil, "", fmt.Errorf("download %s: exceeds the %d MB attachment limit", target.Redacted(), urlAttachmentMaxBytes>>20)
+	}
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("download %s: attachment is empty", target.Redacted())
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// urlAttachmentName derives a safe file name from the URL path, adding an
+// extension from the Content-Type when the path has none.
+func urlAttachmentName(target *url.URL, contentType string) string {
+	name := path.Base(target.Path)
+	if name == "." || name == "/" || name == ".." || strings.ContainsAny(name, `\:`) {
+		name = "download"
+	}
+	if path.Ext(name) == "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+				name += exts[0]
+			}
+		}
+	}
+	return name
+}
+
+func urlAttachmentIndexPath(dir string) string {
+	return filepath.Join(dir, "index.json")
+}
+
+func readURLAttachmentIndex(dir string) map[string]urlAttachmentEntry {
+	data, err := os.ReadFile(urlAttachmentIndexPath(dir)) //nolint:gosec // index lives under the mrl data dir
+	if err != nil {
+		return nil
+	}
+	var index map[string]urlAttachmentEntry
+	if json.Unmarshal(data, &index) != nil {
+		return nil
+	}
+	return index
+}
+
+func lookupURLAttachment(dir, rawURL string, now time.Time) (urlAttachmentEntry, bool) {
+	urlAttachmentMu.Lock()
+	defer urlAttachmentMu.Unlock()
+	entry, ok := readURLAttachmentIndex(dir)[rawURL]
+	if !ok || now.Sub(entry.FetchedAt) > urlAttachmentCacheTTL {
+		return urlAttachmentEntry{}, false
+	}
+	if _, err := os.Stat(filepath.Join(dir, entry.SHA256, entry.Name)); err != nil {
+		return urlAttachmentEntry{}, false
+	}
+	return entry, true
+}
+
+// storeURLAttachment updates the index on a best-effort basis; a failure only
+// means the URL is downloaded again next time.
+func storeURLAttachment(dir, rawURL string, entry urlAttachmentEntry) {
+	urlAttachmentMu.Lock()
+	defer urlAttachmentMu.Unlock()
+	index := readURLAttachmentIndex(dir)
+	if index == nil {
+		index = map[string]urlAttachmentEntry{}
+	}
+	index[rawURL] = entry
+	data, err := json.Marshal(index)
+	if err != nil {
+		return
+	}
+	_ = writeFileAtomic(urlAttachmentIndexPath(dir), data)
+}
+
+// writeFileAtomic writes data next to path and renames it into place, so
+// readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Join(writeErr, closeErr)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
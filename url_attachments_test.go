@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelrelay/modelrelay/sdk/go/llm"
+)
+
+func TestFetchURLAttachmentsDownloadsAndCaches(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4 test"))
+	}))
+	defer server.Close()
+
+	cfg := runtimeConfig{AllowURLAttachments: true}
+	local := filepath.Join(t.TempDir(), "notes.txt")
+	paths, err := fetchURLAttachments(t.Context(), cfg, []string{server.URL + "/reports/q3.pdf", local})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[1] != local {
+		t.Fatalf("unexpected paths %v", paths)
+	}
+	if filepath.Base(paths[0]) != "q3.pdf" {
+		t.Fatalf("expected cached copy to keep the URL file name, got %s", paths[0])
+	}
+	data, err := os.ReadFile(paths[0])
+	if err != nil || string(data) != "%PDF-1.4 test" {
+		t.Fatalf("cached content = %q, %v", data, err)
+	}
+
+	again, err := fetchURLAttachments(t.Context(), cfg, []string{server.URL + "/reports/q3.pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0] != paths[0] || hits.Load() != 1 {
+		t.Fatalf("expected cache hit, got %s after %d downloads", again[0], hits.Load())
+	}
+
+	parts, err := buildAttachmentParts(paths[:1], "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts[0].File == nil || parts[0].File.MimeType != llm.MimeTypePDF {
+		t.Fatalf("unexpected attachment part %+v", parts[0])
+	}
+}
+
+func TestFetchURLAttachmentsRequiresOptIn(t *testing.T) {
+	_, err := fetchURLAttachments(t.Context(), runtimeConfig{}, []string{"https://example.com/a.pdf"})
+	if !errors.Is(err, errURLAttachmentsDisabled) {
+		t.Fatalf("expected opt-in error, got %v", err)
+	}
+	if _, err := buildAttachmentParts([]string{"https://example.com/a.pdf"}, "", nil); !errors.Is(err, errURLAttachmentsDisabled) {
+		t.Fatalf("expected buildAttachmentParts to reject URLs, got %v", err)
+	}
+}
+
+func TestFetchURLAttachmentRejectsFailures(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/huge":
+			w.Header().Set("Content-Length", "104857600")
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	cases := map[string]string{
+		"/missing": "404",
+		"/huge":    "attachment limit",
+		"/empty":   "empty",
+	}
+	for path, want := range cases {
+		_, err := fetchURLAttachment(t.Context(), server.URL+path, time.Now())
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: expected error containing %q, got %v", path, want, err)
+		}
+	}
+}
+
+func TestURLAttachmentName(t *testing.T) {
+	cases := []struct {
+		rawURL, contentType, want string
+	}{
+		{"https://example.com/docs/report.pdf?x=1", "application/octet-stream", "report.pdf"},
+		{"https://example.com/", "application/pdf", "download.pdf"},
+		{"https://example.com/export", "text/csv; charset=utf-8", "export.csv"},
+		{"https://example.com/export", "", "export"},
+	}
+	for _, tc := range cases {
+		parsed, err := url.Parse(tc.rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := urlAttachmentName(parsed, tc.contentType); got != tc.want {
+			t.Fatalf("%s: got %q, want %q", tc.rawURL, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/spf13/cobra"
+)
+
+// usageRecord is one line of the local usage ledger. A record is written per
+// prompt, chat turn and batch row, per image, embedding and audio request, and
+// per do, agent loop and rlm run.
+type usageRecord struct {
+	At           time.Time `json:"at"`
+	Command      string    `json:"command"`
+	Model        string    `json:"model,omitempty"`
+	Profile      string    `json:"profile,omitempty"`
+	Calls        int       `json:"calls"`
+	InputTokens  int64     `json:"input_tokens"`
+	OutputTokens int64     `json:"output_tokens"`
+	TotalTokens  int64     `json:"total_tokens"`
+	Images       int64     `json:"images,omitempty"`
+	LatencyMS    int64     `json:"latency_ms"`
+	CostCents    *float64  `json:"cost_cents,omitempty"`
+}
+
+func usageLedgerPath() (string, error) {
+	dir, err := defaultDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "usage.jsonl"), nil
+}
+
+// recordUsage appends a record to the ledger. Ledger failures never fail the
+// command that produced the usage; they are reported on stderr.
+func recordUsage(command string, cfg runtimeConfig, model string, calls int, usage sdk.Usage, latency time.Duration, costCents *float64) {
+	if calls <= 0 {
+		return
+	}
+	record := usageRecord{
+		At:           time.Now().UTC(),
+		Command:      command,
+		Model:        strings.TrimSpace(model),
+		Profile:      cfg.Profile,
+		Calls:        calls,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		TotalTokens:  usage.TotalTokens,
+		LatencyMS:    latency.Milliseconds(),
+		CostCents:    costCents,
+	}
+	if err := appendUsageRecord(record); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record usage: %v\n", err)
+	}
+}
+
+// recordImageUsage appends a ledger record for an image generation or edit.
+// Image calls carry no token counts and are not priced locally.
+func recordImageUsage(command string, cfg runtimeConfig, model string, images int64, latency time.Duration) {
+	record := usageRecord{
+		At:        time.Now().UTC(),
+		Command:   command,
+		Model:     strings.TrimSpace(model),
+		Profile:   cfg.Profile,
+		Calls:     1,
+		Images:    images,
+		LatencyMS: latency.Milliseconds(),
+	}
+	if err := appendUsageRecord(record); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record usage: %v\n", err)
+	}
+}
+
+func appendUsageRecord(record usageRecord) error {
+	path, err := usageLedgerPath()
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600) //nolint:gosec // ledger lives under the mrl data dir
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ledgerCommandName names the command for the ledger, e.g. "agent loop".
+// The bare root command (mrl "prompt") is recorded as "prompt".
+func ledgerCommandName(cmd *cobra.Command) string {
+	if cmd == nil {
+		return "prompt"
+	}
+	path := strings.Fields(cmd.CommandPath())
+	if len(path) <= 1 {
+		return "prompt"
+	}
+	return strings.Join(path[1:], " ")
+}
+
+func readUsageRecords(since time.Time) ([]usageRecord, error) {
+	path, err := usageLedgerPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path) //nolint:gosec // ledger lives under the mrl data dir
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []usageRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record usageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if !since.IsZero() && record.At.Before(since) {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+type usageGroup struct {
+	Key          string  `json:"key"`
+	Records      int     `json:"records"`
+	Calls        int     `json:"calls"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	TotalTokens  int64   `json:"total_tokens"`
+	Images       int64   `json:"images"`
+	LatencyMS    int64   `json:"latency_ms"`
+	CostCents    float64 `json:"cost_cents"`
+	// CostComplete is false when some records have no cost (unpriced models).
+	CostComplete bool `json:"cost_complete"`
+}
+
+func (g *usageGroup) add(record usageRecord) {
+	g.Records++
+	g.Calls += record.Calls
+	g.InputTokens += record.InputTokens
+	g.OutputTokens += record.OutputTokens
+	g.TotalTokens += record.TotalTokens
+	g.Images += record.Images
+	g.LatencyMS += record.LatencyMS
+	if record.CostCents != nil {
+		g.CostCents += *record.CostCents
+	} else {
+		g.CostComplete = false
+	}
+}
+
+// groupUsageRecords aggregates records by model, profile or command, sorted
+// by total tokens descending. The second result is the overall total.
+func groupUsageRecords(records []usageRecord, groupBy string) ([]usageGroup, usageGroup, error) {
+	keyFor, err := usageGroupKey(groupBy)
+	if err != nil {
+		return nil, usageGroup{}, err
+	}
+	total := usageGroup{Key: "total", CostComplete: true}
+	index := map[string]*usageGroup{}
+	var groups []*usageGroup
+	for _, record := range records {
+		key := keyFor(record)
+		if key == "" {
+			key = "-"
+		}
+		group, ok := index[key]
+		if !ok {
+			group = &usageGroup{Key: key, CostComplete: true}
+			index[key] = group
+			groups = append(groups, group)
+		}
+		group.add(record)
+		total.add(record)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].TotalTokens != groups[j].TotalTokens {
+			return groups[i].TotalTokens > groups[j].TotalTokens
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	out := make([]usageGroup, 0, len(groups))
+	for _, group := range groups {
+		out = append(out, *group)
+	}
+	return out, total, nil
+}
+
+func usageGroupKey(groupBy string) (func(usageRecord) string, error) {
+	switch strings.ToLower(strings.TrimSpace(groupBy)) {
+	case "model":
+		return func(r usageRecord) string { return r.Model }, nil
+	case "profile":
+		return func(r usageRecord) string { return r.Profile }, nil
+	case "command":
+		return func(r usageRecord) string { return r.Command }, nil
+	default:
+		return nil, errors.New("group-by must be model, profile, or command")
+	}
+}
+
+// parseUsageSince accepts a lookback such as 30m, 12h, 7d or 2w, or an
+// absolute date (2006-01-02) or RFC 3339 timestamp. Empty means all time.
+func parseUsageSince(raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, time.Local); err == nil {
+		return t, nil
+	}
+	unit := raw[len(raw)-1]
+	switch unit {
+	case 'd', 'w':
+		n, err := strconv.Atoi(raw[:len(raw)-1])
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("invalid --since %q", raw)
+		}
+		days := n
+		if unit == 'w' {
+			days = n * 7
+		}
+		return now.AddDate(0, 0, -days), nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid --since %q (use e.g. 12h, 7d, 2w or 2006-01-02)", raw)
+	}
+	return now.Add(-d), nil
+}
+
+// pricedCents turns a costTracker.record result into the ledger's optional cost.
+func pricedCents(cents float64, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	return &cents
+}
+
+// agentUsageTotals converts accumulated agent usage to the ledger's token counts.
+func agentUsageTotals(usage sdk.AgentUsage) sdk.Usage {
+	return sdk.Usage{
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+		TotalTokens:  usage.TotalTokens,
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	sdk "github.com/modelrelay/modelrelay/sdk/go"
+	"github.com/spf13/cobra"
+)
+
+func TestUsageLedgerRoundTrip(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	cfg := runtimeConfig{Profile: "work"}
+
+	cost := 1.5
+	recordUsage("prompt", cfg, "m1", 1, sdk.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15}, 200*time.Millisecond, &cost)
+	recordUsage("agent loop", cfg, "m2", 3, sdk.Usage{InputTokens: 100, OutputTokens: 50, TotalTokens: 150}, time.Second, nil)
+	recordUsage("do", cfg, "m1", 0, sdk.Usage{}, 0, nil) // no calls: not recorded
+
+	records, err := readUsageRecords(time.Time{})
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if records[0].Command != "prompt" || records[0].Profile != "work" || records[0].LatencyMS != 200 || *records[0].CostCents != 1.5 {
+		t.Fatalf("unexpected first record: %+v", records[0])
+	}
+
+	future, err := readUsageRecords(time.Now().Add(time.Hour))
+	if err != nil || len(future) != 0 {
+		t.Fatalf("expected no records after since filter, got %v, %v", future, err)
+	}
+}
+
+func TestGroupUsageRecords(t *testing.T) {
+	cost := 2.0
+	records := []usageRecord{
+		{Command: "prompt", Model: "a", Profile: "p1", Calls: 1, TotalTokens: 10, LatencyMS: 100, CostCents: &cost},
+		{Command: "do", Model: "b", Profile: "p1", Calls: 4, TotalTokens: 100, LatencyMS: 300},
+		{Command: "prompt", Model: "a", Profile: "p2", Calls: 1, TotalTokens: 20, LatencyMS: 200, CostCents: &cost},
+	}
+	groups, total, err := groupUsageRecords(records, "model")
+	if err != nil {
+		t.Fatalf("group: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Key != "b" || groups[1].Key != "a" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	if groups[1].Records != 2 || groups[1].TotalTokens != 30 || groups[1].CostCents != 4 || !groups[1].CostComplete {
+		t.Fatalf("unexpected group a: %+v", groups[1])
+	}
+	if groups[0].CostComplete {
+		t.Fatal("group without costs must be incomplete")
+	}
+	if total.Records != 3 || total.Calls != 6 || total.TotalTokens != 130 || total.CostComplete {
+		t.Fatalf("unexpected total: %+v", total)
+	}
+	if _, _, err := groupUsageRecords(records, "day"); err == nil {
+		t.Fatal("expected invalid group-by error")
+	}
+}
+
+func TestParseUsageSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"":                     {},
+		"7d":                   now.AddDate(0, 0, -7),
+		"2w":                   now.AddDate(0, 0, -14),
+		"12h":                  now.Add(-12 * time.Hour),
+		"2026-03-01T00:00:00Z": time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for raw, want := range cases {
+		got, err := parseUsageSince(raw, now)
+		if err != nil {
+			t.Fatalf("%q: %v", raw, err)
+		}
+		if !got.Equal(want) {
+			t.Fatalf("%q: got %v, want %v", raw, got, want)
+		}
+	}
+	for _, bad := range []string{"7x", "-3d", "soon"} {
+		if _, err := parseUsageSince(bad, now); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLedgerCommandName(t *testing.T) {
+	root := &cobra.Command{Use: "mrl"}
+	agent := &cobra.Command{Use: "agent"}
+	loop := &cobra.Command{Use: "loop"}
+	root.AddCommand(agent)
+	agent.AddCommand(loop)
+	if got := ledgerCommandName(root); got != "prompt" {
+		t.Fatalf("root: %q", got)
+	}
+	if got := ledgerCommandName(loop); got != "agent loop" {
+		t.Fatalf("loop: %q", got)
+	}
+}